@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
-	"strings"
 
 	"skyell-backend/internal/api"
+	"skyell-backend/internal/api/handlers"
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/config"
 	"skyell-backend/internal/database"
+	"skyell-backend/internal/demo"
+	"skyell-backend/internal/janitor"
+	"skyell-backend/internal/metrics"
+	"skyell-backend/internal/retention"
+	"skyell-backend/internal/tracing"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -31,22 +39,73 @@ func main() {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Tracing (exports over OTLP when OTEL_EXPORTER_OTLP_ENDPOINT is set)
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		log.Fatal("Failed to set up tracing:", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Background pruning of old crawl results (when RESULT_RETENTION_PERIOD is set)
+	retention.StartPruner(context.Background(), db)
+
+	// Background cleanup of orphaned links and drifted link counts
+	janitor.Start(context.Background(), db)
+
+	// Shared read-only demo user and its periodically reset data (when DEMO_MODE=true)
+	if err := demo.Start(context.Background(), db); err != nil {
+		log.Fatal("Failed to seed demo data:", err)
+	}
+
+	// Page sizes of the list endpoints
+	if _, err := config.Pagination(); err != nil {
+		log.Fatal("Invalid pagination configuration:", err)
+	}
+
+	// Users allowed to use the /admin endpoints
+	if _, err := config.AdminUserIDs(); err != nil {
+		log.Fatal("Invalid admin configuration:", err)
+	}
+	if os.Getenv("ADMIN_EMAILS") != "" {
+		log.Printf("WARNING: ADMIN_EMAILS is no longer used; list admin user IDs in ADMIN_USER_IDS instead")
+	}
+
+	// Password hashing cost
+	if cost, err := config.BcryptCost(); err != nil {
+		log.Fatal("Invalid bcrypt configuration:", err)
+	} else if !config.RecommendedBcryptCost(cost) {
+		log.Printf("WARNING: BCRYPT_COST=%d is outside the recommended range of 10-14", cost)
+	}
+
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Reverse proxies whose X-Forwarded-* headers are honoured
+	trustedProxies, err := config.TrustedProxies()
+	if err != nil {
+		log.Fatal("Invalid proxy configuration:", err)
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid proxy configuration:", err)
+	}
+
 	// CORS middleware
-	config := cors.DefaultConfig()
-	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-	if allowedOrigins == "" {
-		allowedOrigins = "http://localhost:3005" // Default for local development
+	corsConfig, err := config.CORSConfig()
+	if err != nil {
+		log.Fatal("Invalid CORS configuration:", err)
 	}
+	r.Use(cors.New(corsConfig))
+
+	// Server span per request
+	r.Use(tracing.Middleware())
+
+	// Request metrics middleware
+	r.Use(metrics.Middleware())
 
-	config.AllowOrigins = strings.Split(allowedOrigins, ",")
-	config.AllowCredentials = true
-	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	r.Use(cors.New(config))
+	// Cap request body size (MAX_REQUEST_BYTES)
+	r.Use(middleware.BodyLimit())
 
-	// Health check endpoint
+	// Health check endpoint (liveness)
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "ok",
@@ -54,6 +113,12 @@ func main() {
 		})
 	})
 
+	// Readiness endpoint - verifies the database is reachable
+	r.GET("/health/ready", handlers.Readiness(db))
+
+	// Prometheus metrics endpoint
+	r.GET("/metrics", metrics.Handler())
+
 	// Initialize API routes
 	api.SetupRoutes(r, db)
 
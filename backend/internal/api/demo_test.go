@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/demo"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// bearer returns an Authorization header value with an access token for the user
+func bearer(t *testing.T, userID uint) string {
+	t.Helper()
+	claims := middleware.JWTClaims{
+		UserID:           userID,
+		TokenType:        middleware.TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+func TestDemoMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("DEMO_RESET_INTERVAL", "0")
+	db := testutil.NewDB(t)
+	router := gin.New()
+	SetupRoutes(router, db)
+
+	do := func(method, target, authorization, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/v1/results", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous read with demo mode off: status = %d, want 401", w.Code)
+	}
+
+	t.Setenv("DEMO_MODE", "true")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		for demo.UserID() != 0 {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	if err := demo.Start(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	demoUserID := demo.UserID()
+
+	// Reads work without signing in, serving the demo user's results
+	w := do(http.MethodGet, "/api/v1/results", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("anonymous GET /results: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var list struct {
+		Data struct {
+			Data []struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data.Data) == 0 {
+		t.Fatal("anonymous GET /results listed no demo results")
+	}
+	resultID := list.Data.Data[0].ID
+	if w := do(http.MethodGet, fmt.Sprintf("/api/v1/results/%d", resultID), "", ""); w.Code != http.StatusOK {
+		t.Errorf("anonymous GET /results/%d: status = %d, want 200: %s", resultID, w.Code, w.Body)
+	}
+
+	// Writes are rejected: anonymously for lack of a user, and as the demo user itself
+	writes := []struct{ method, target, body string }{
+		{http.MethodPost, "/api/v1/urls", `{"url": "https://new.example"}`},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/results/%d", resultID), ""},
+		{http.MethodPost, "/api/v1/tags", `{"name": "mine"}`},
+	}
+	for _, write := range writes {
+		if w := do(write.method, write.target, "", write.body); w.Code != http.StatusUnauthorized {
+			t.Errorf("anonymous %s %s: status = %d, want 401", write.method, write.target, w.Code)
+		}
+		if w := do(write.method, write.target, bearer(t, demoUserID), write.body); w.Code != http.StatusForbidden {
+			t.Errorf("demo user %s %s: status = %d, want 403: %s", write.method, write.target, w.Code, w.Body)
+		}
+	}
+	var results int64
+	if err := db.Model(&models.CrawlResult{}).Where("id = ?", resultID).Count(&results).Error; err != nil {
+		t.Fatal(err)
+	}
+	if results != 1 {
+		t.Error("the demo result was deleted")
+	}
+
+	// Signed-in users still see only their own results
+	user := models.User{Username: "visitor", Email: "visitor@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	w = do(http.MethodGet, "/api/v1/results", bearer(t, user.ID), "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(list.Data.Data) != 0 {
+		t.Errorf("signed-in GET /results: status = %d with %d results, want 200 with none", w.Code, len(list.Data.Data))
+	}
+}
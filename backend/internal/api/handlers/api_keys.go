@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyPrefix marks Skyell API keys so they're recognisable, e.g. in secret scanners
+const apiKeyPrefix = "sk_"
+
+type CreateAPIKeyRequest struct {
+	Label string `json:"label" binding:"max=100"`
+}
+
+// CreateAPIKey issues a new API key. The full key is only returned in this response.
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		respondInternalError(c, "Failed to generate API key", err)
+		return
+	}
+	key := apiKeyPrefix + token
+
+	apiKey := models.APIKey{
+		UserID:  userID,
+		Label:   req.Label,
+		KeyHash: middleware.HashAPIKey(key),
+		Prefix:  key[:len(apiKeyPrefix)+8],
+	}
+	if err := h.db.Create(&apiKey).Error; err != nil {
+		respondInternalError(c, "Failed to create API key", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "API key created; store it now, it won't be shown again",
+		"data": gin.H{
+			"api_key": apiKey,
+			"key":     key,
+		},
+	})
+}
+
+// ListAPIKeys returns the user's API keys, without the keys themselves
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	apiKeys := []models.APIKey{}
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve API keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    apiKeys,
+	})
+}
+
+// RevokeAPIKey permanently disables one of the user's API keys
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid API key ID",
+		})
+		return
+	}
+
+	result := h.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("revoked", true)
+	if result.Error != nil {
+		respondInternalError(c, "Failed to revoke API key", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "API key not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "API key revoked successfully",
+	})
+}
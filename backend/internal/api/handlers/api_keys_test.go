@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "automation")
+	other := createUser(t, db, "other")
+
+	handler := NewAuthHandler(db)
+	keys := testRouter(owner.ID)
+	keys.POST("/api-keys", handler.CreateAPIKey)
+	keys.GET("/api-keys", handler.ListAPIKeys)
+	keys.DELETE("/api-keys/:id", handler.RevokeAPIKey)
+
+	w := doJSON(keys, http.MethodPost, "/api-keys", map[string]string{"label": "CI"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created struct {
+		Data struct {
+			APIKey models.APIKey `json:"api_key"`
+			Key    string        `json:"key"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &created)
+	key := created.Data.Key
+	if !strings.HasPrefix(key, apiKeyPrefix) || !strings.HasPrefix(key, created.Data.APIKey.Prefix) {
+		t.Fatalf("key %q doesn't start with its prefix %q", key, created.Data.APIKey.Prefix)
+	}
+
+	// The key is only shown at creation
+	if w := doJSON(keys, http.MethodGet, "/api-keys", nil); w.Code != http.StatusOK || strings.Contains(w.Body.String(), key) {
+		t.Errorf("list: status = %d, body %s; want 200 without the key", w.Code, w.Body)
+	}
+	var stored models.APIKey
+	if err := db.First(&stored, created.Data.APIKey.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.KeyHash == key || stored.KeyHash != middleware.HashAPIKey(key) {
+		t.Errorf("stored key hash = %q, want the hash of the key", stored.KeyHash)
+	}
+
+	protected := gin.New()
+	protected.Use(middleware.APIKeyAuth(db), middleware.AuthRequired())
+	protected.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, "%d", c.GetUint("user_id"))
+	})
+	whoami := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		if key != "" {
+			req.Header.Set(middleware.APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		protected.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := whoami(key); w.Code != http.StatusOK || w.Body.String() != fmt.Sprint(owner.ID) {
+		t.Fatalf("with the key: status = %d, body %q; want 200 as user %d", w.Code, w.Body, owner.ID)
+	}
+	if err := db.First(&stored, stored.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("last_used_at wasn't set")
+	}
+	for name, key := range map[string]string{"no key": "", "unknown key": key + "x"} {
+		if w := whoami(key); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", name, w.Code)
+		}
+	}
+
+	// Only the owner can revoke the key, after which it's rejected
+	otherKeys := testRouter(other.ID)
+	otherKeys.DELETE("/api-keys/:id", handler.RevokeAPIKey)
+	if w := doJSON(otherKeys, http.MethodDelete, fmt.Sprintf("/api-keys/%d", stored.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoking another user's key: status = %d, want 404", w.Code)
+	}
+	if w := whoami(key); w.Code != http.StatusOK {
+		t.Errorf("after another user's revoke attempt: status = %d, want 200", w.Code)
+	}
+
+	if w := doJSON(keys, http.MethodDelete, fmt.Sprintf("/api-keys/%d", stored.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := whoami(key); w.Code != http.StatusUnauthorized {
+		t.Errorf("with a revoked key: status = %d, want 401", w.Code)
+	}
+}
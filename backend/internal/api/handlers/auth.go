@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"time"
 
 	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/mailer"
 	"skyell-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -15,11 +23,20 @@ import (
 )
 
 type AuthHandler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	mailer     mailer.Mailer
+	bcryptCost int
 }
 
 func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+	// An invalid BCRYPT_COST is rejected at startup; the returned cost is still usable
+	bcryptCost, _ := config.BcryptCost()
+
+	return &AuthHandler{
+		db:         db,
+		mailer:     mailer.NewMailer(),
+		bcryptCost: bcryptCost,
+	}
 }
 
 type RegisterRequest struct {
@@ -37,6 +54,20 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+type UpdateProfileRequest struct {
+	Username string `json:"username" binding:"omitempty,min=3,max=50"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
 type AuthResponse struct {
 	User         *models.User `json:"user"`
 	Token        string       `json:"access_token"`
@@ -47,11 +78,7 @@ type AuthResponse struct {
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -66,12 +93,9 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), h.bcryptCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to hash password",
-		})
+		respondInternalError(c, "Failed to hash password", err)
 		return
 	}
 
@@ -83,21 +107,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to create user",
-			"error":   err.Error(),
-		})
+		respondInternalError(c, "Failed to create user", err)
 		return
 	}
 
 	// Generate tokens
 	token, refreshToken, err := h.generateTokens(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to generate tokens",
-		})
+		respondInternalError(c, "Failed to generate tokens", err)
 		return
 	}
 
@@ -116,10 +133,22 @@ func (h *AuthHandler) Register(c *gin.Context) {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		respondBindError(c, err)
+		return
+	}
+
+	// Refuse locked-out emails before checking credentials, even correct ones
+	lockedUntil, err := h.loginLockedUntil(req.Email)
+	if err != nil {
+		respondInternalError(c, "Failed to check login attempts", err)
+		return
+	}
+	if lockedUntil != nil {
+		retryAfter := int(time.Until(*lockedUntil).Seconds()) + 1
+		c.Header("Retry-After", fmt.Sprintf("%d", retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
 			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
+			"message": "Too many failed login attempts, please try again later",
 		})
 		return
 	}
@@ -127,29 +156,24 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Find user by email
 	var user models.User
 	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "Invalid email or password",
-		})
+		h.failLogin(c, req.Email)
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "Invalid email or password",
-		})
+		h.failLogin(c, req.Email)
 		return
 	}
 
+	if err := h.resetFailedLogins(req.Email); err != nil {
+		log.Printf("Failed to reset login attempts for user %d: %v", user.ID, err)
+	}
+
 	// Generate tokens
 	token, refreshToken, err := h.generateTokens(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to generate tokens",
-		})
+		respondInternalError(c, "Failed to generate tokens", err)
 		return
 	}
 
@@ -164,15 +188,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// failLogin records a failed attempt and responds with the same error whether or not the email exists
+func (h *AuthHandler) failLogin(c *gin.Context, email string) {
+	if err := h.recordFailedLogin(email); err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": "Invalid email or password",
+	})
+}
+
 // RefreshToken generates a new access token using a refresh token
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	var req RefreshTokenRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -199,16 +231,105 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	// Generate new tokens
 	token, refreshToken, err := h.generateTokens(&user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		respondInternalError(c, "Failed to generate tokens", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Token refreshed successfully",
+		"data": AuthResponse{
+			User:         &user,
+			Token:        token,
+			RefreshToken: refreshToken,
+		},
+	})
+}
+
+// UpdateProfile changes the authenticated user's username and/or email
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if req.Username == "" && req.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Failed to generate tokens",
+			"message": "Nothing to update: provide a username or email",
+		})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	// Check the new values aren't taken by another user
+	conflictQuery := h.db.Model(&models.User{}).Where("id != ?", user.ID)
+	switch {
+	case req.Username != "" && req.Email != "":
+		conflictQuery = conflictQuery.Where("email = ? OR username = ?", req.Email, req.Username)
+	case req.Email != "":
+		conflictQuery = conflictQuery.Where("email = ?", req.Email)
+	default:
+		conflictQuery = conflictQuery.Where("username = ?", req.Username)
+	}
+
+	var conflicts int64
+	if err := conflictQuery.Count(&conflicts).Error; err != nil {
+		respondInternalError(c, "Failed to update profile", err)
+		return
+	}
+	if conflicts > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "User with this email or username already exists",
 		})
 		return
 	}
 
+	if req.Username != "" {
+		user.Username = req.Username
+	}
+	if req.Email != "" {
+		user.Email = req.Email
+	}
+
+	// The unique indexes catch a concurrent update that took the values after the check above
+	if err := h.db.Save(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "User with this email or username already exists",
+			})
+			return
+		}
+		respondInternalError(c, "Failed to update profile", err)
+		return
+	}
+
+	// Tokens embed the username and email, so issue fresh ones
+	token, refreshToken, err := h.generateTokens(&user)
+	if err != nil {
+		respondInternalError(c, "Failed to generate tokens", err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Token refreshed successfully",
+		"message": "Profile updated successfully",
 		"data": AuthResponse{
 			User:         &user,
 			Token:        token,
@@ -217,6 +338,150 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	})
 }
 
+// ForgotPassword issues a password reset token and emails it to the user.
+// It always responds with 200 so callers can't tell which emails are registered.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"message": "If an account with that email exists, a password reset link has been sent",
+	}
+
+	var user models.User
+	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		log.Printf("Failed to generate password reset token: %v", err)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL()),
+	}
+
+	if err := h.db.Create(&reset).Error; err != nil {
+		log.Printf("Failed to store password reset token: %v", err)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:3005" // Default for local development
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It expires at %s.\n\n%s/reset-password?token=%s\n\nIf you didn't request this, you can ignore this email.",
+		user.Username, reset.ExpiresAt.Format(time.RFC1123), frontendURL, token)
+
+	// Deliver asynchronously so response time doesn't reveal whether the account exists
+	go func() {
+		if err := h.mailer.Send(user.Email, "Reset your Skyell password", body); err != nil {
+			log.Printf("Failed to send password reset email: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword sets a new password using a valid, unused reset token
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	var reset models.PasswordReset
+	if err := h.db.Where("token_hash = ? AND used_at IS NULL", hashResetToken(req.Token)).First(&reset).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid or expired reset token",
+		})
+		return
+	}
+
+	if time.Now().After(reset.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid or expired reset token",
+		})
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), h.bcryptCost)
+	if err != nil {
+		respondInternalError(c, "Failed to hash password", err)
+		return
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		// Mark the token used first so a concurrent request can't redeem it twice
+		now := time.Now()
+		result := tx.Model(&models.PasswordReset{}).
+			Where("id = ? AND used_at IS NULL", reset.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", reset.UserID).Update("password", string(hashedPassword)).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid or expired reset token",
+			})
+			return
+		}
+		respondInternalError(c, "Failed to reset password", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Password reset successfully",
+	})
+}
+
+// generateResetToken creates a random token to be sent to the user
+func generateResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashResetToken hashes a reset token for storage so leaked rows can't be redeemed
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// passwordResetTTL returns how long reset tokens stay valid
+func passwordResetTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return time.Hour
+}
+
 // generateTokens creates both access and refresh tokens
 func (h *AuthHandler) generateTokens(user *models.User) (string, string, error) {
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -226,9 +491,10 @@ func (h *AuthHandler) generateTokens(user *models.User) (string, string, error)
 
 	// Access token (expires in 24 hours)
 	accessClaims := middleware.JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		TokenType: middleware.TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -244,9 +510,10 @@ func (h *AuthHandler) generateTokens(user *models.User) (string, string, error)
 
 	// Refresh token (expires in 7 days)
 	refreshClaims := middleware.JWTClaims{
-		UserID:   user.ID,
-		Username: user.Username,
-		Email:    user.Email,
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		TokenType: middleware.TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -281,7 +548,11 @@ func (h *AuthHandler) validateRefreshToken(tokenString string) (*middleware.JWTC
 		return nil, err
 	}
 
+	// Access tokens, including those issued without a token type, can't be used to refresh
 	if claims, ok := token.Claims.(*middleware.JWTClaims); ok && token.Valid {
+		if claims.TokenType != middleware.TokenTypeRefresh {
+			return nil, errors.New("not a refresh token")
+		}
 		return claims, nil
 	}
 
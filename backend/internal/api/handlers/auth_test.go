@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func TestUpdateProfile(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "alice")
+	createUser(t, db, "bob")
+
+	router := testRouter(user.ID)
+	router.PATCH("/auth/me", NewAuthHandler(db).UpdateProfile)
+
+	w := doJSON(router, http.MethodPatch, "/auth/me", map[string]string{"username": "alice2", "email": "alice2@example.com"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var saved models.User
+	db.First(&saved, user.ID)
+	if saved.Username != "alice2" || saved.Email != "alice2@example.com" {
+		t.Errorf("saved user = %s <%s>, want the new username and email", saved.Username, saved.Email)
+	}
+
+	for _, req := range []map[string]string{{"username": "bob"}, {"email": "bob@example.com"}} {
+		if w := doJSON(router, http.MethodPatch, "/auth/me", req); w.Code != http.StatusConflict {
+			t.Errorf("taking %v: status = %d, want 409: %s", req, w.Code, w.Body)
+		}
+	}
+}
+
+func TestUpdateProfileConcurrentConflict(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "carol")
+
+	// Another account takes the email between the conflict check and the save
+	if err := db.Callback().Update().Before("gorm:update").Register("test:take_email", func(tx *gorm.DB) {
+		tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Exec(
+			"INSERT INTO users (username, email, password, created_at, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)",
+			"dave", "taken@example.com", "x")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := testRouter(user.ID)
+	router.PATCH("/auth/me", NewAuthHandler(db).UpdateProfile)
+
+	w := doJSON(router, http.MethodPatch, "/auth/me", map[string]string{"email": "taken@example.com"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409: %s", w.Code, w.Body)
+	}
+}
+
+// fakeMailer hands sent emails to the test instead of delivering them
+type fakeMailer struct {
+	sent chan sentMail
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.sent <- sentMail{to, subject, body}
+	return nil
+}
+
+// resetTokenPattern finds the token in the reset link of a password reset email
+var resetTokenPattern = regexp.MustCompile(`reset-password\?token=([0-9a-f]+)`)
+
+// passwordResetRouter serves the forgot and reset password routes, sending email through mail
+func passwordResetRouter(db *gorm.DB, mail *fakeMailer) http.Handler {
+	handler := NewAuthHandler(db)
+	handler.mailer = mail
+	router := testRouter(0)
+	router.POST("/auth/forgot-password", handler.ForgotPassword)
+	router.POST("/auth/reset-password", handler.ResetPassword)
+	return router
+}
+
+// passwordMatches reports whether the user's stored password hash matches password
+func passwordMatches(t *testing.T, db *gorm.DB, userID uint, password string) bool {
+	t.Helper()
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "4")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "forgetful")
+	mail := &fakeMailer{sent: make(chan sentMail, 1)}
+	router := passwordResetRouter(db, mail)
+
+	if w := doJSON(router, http.MethodPost, "/auth/forgot-password", map[string]string{"email": user.Email}); w.Code != http.StatusOK {
+		t.Fatalf("forgot password: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var email sentMail
+	select {
+	case email = <-mail.sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no password reset email was sent")
+	}
+	match := resetTokenPattern.FindStringSubmatch(email.body)
+	if email.to != user.Email || match == nil {
+		t.Fatalf("email to %s = %q, want a reset link sent to %s", email.to, email.body, user.Email)
+	}
+	token := match[1]
+
+	reset := map[string]string{"token": token, "password": "new-password"}
+	if w := doJSON(router, http.MethodPost, "/auth/reset-password", reset); w.Code != http.StatusOK {
+		t.Fatalf("reset password: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if !passwordMatches(t, db, user.ID, "new-password") {
+		t.Error("the new password wasn't saved")
+	}
+
+	// A token can only be redeemed once
+	reuse := map[string]string{"token": token, "password": "another-password"}
+	if w := doJSON(router, http.MethodPost, "/auth/reset-password", reuse); w.Code != http.StatusBadRequest {
+		t.Errorf("reusing the token: status = %d, want 400: %s", w.Code, w.Body)
+	}
+	if !passwordMatches(t, db, user.ID, "new-password") {
+		t.Error("reusing the token changed the password")
+	}
+}
+
+func TestResetPasswordRejectsExpiredToken(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "4")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "late")
+	router := passwordResetRouter(db, &fakeMailer{sent: make(chan sentMail, 1)})
+
+	const token = "0123456789abcdef"
+	expired := models.PasswordReset{UserID: user.ID, TokenHash: hashResetToken(token), ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := db.Create(&expired).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w := doJSON(router, http.MethodPost, "/auth/reset-password", map[string]string{"token": token, "password": "new-password"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expired token: status = %d, want 400: %s", w.Code, w.Body)
+	}
+	if passwordMatches(t, db, user.ID, "new-password") {
+		t.Error("an expired token changed the password")
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	db := testutil.NewDB(t)
+	mail := &fakeMailer{sent: make(chan sentMail, 1)}
+	router := passwordResetRouter(db, mail)
+
+	// The response doesn't reveal whether the account exists
+	if w := doJSON(router, http.MethodPost, "/auth/forgot-password", map[string]string{"email": "nobody@example.com"}); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	select {
+	case email := <-mail.sent:
+		t.Errorf("an email was sent to %s for an unknown account", email.to)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// loginRouter serves the login route
+func loginRouter(db *gorm.DB) http.Handler {
+	router := testRouter(0)
+	router.POST("/auth/login", NewAuthHandler(db).Login)
+	return router
+}
+
+// createLoginUser creates a user who signs in with password
+func createLoginUser(t *testing.T, db *gorm.DB, name, password string) models.User {
+	t.Helper()
+	user := createUser(t, db, name)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&user).Update("password", string(hash)).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestLoginLockout(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "200ms")
+	db := testutil.NewDB(t)
+	user := createLoginUser(t, db, "target", "correct horse")
+	router := loginRouter(db)
+
+	login := func(email, password string) int {
+		return doJSON(router, http.MethodPost, "/auth/login", map[string]string{"email": email, "password": password}).Code
+	}
+
+	// A successful login clears the failures so far
+	for i := 0; i < 2; i++ {
+		if code := login(user.Email, "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d: status = %d, want 401", i+1, code)
+		}
+	}
+	if code := login(user.Email, "correct horse"); code != http.StatusOK {
+		t.Fatalf("login after 2 failures: status = %d, want 200", code)
+	}
+	for i := 0; i < 2; i++ {
+		if code := login(user.Email, "wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d after the reset: status = %d, want 401", i+1, code)
+		}
+	}
+
+	// The third failure in a row locks the email, even for the correct password
+	if code := login(user.Email, "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("third failed login: status = %d, want 401", code)
+	}
+	w := doJSON(router, http.MethodPost, "/auth/login", map[string]string{"email": "TARGET@example.com", "password": "correct horse"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("login while locked: status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("locked login has no Retry-After header")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if code := login(user.Email, "correct horse"); code != http.StatusOK {
+		t.Errorf("login after the lockout: status = %d, want 200", code)
+	}
+}
+
+func TestLoginLockoutUnknownEmail(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "2")
+	db := testutil.NewDB(t)
+	router := loginRouter(db)
+
+	// Unknown emails are locked the same way, so lockout doesn't reveal which emails exist
+	var codes []int
+	for i := 0; i < 3; i++ {
+		codes = append(codes, doJSON(router, http.MethodPost, "/auth/login", map[string]string{"email": "nobody@example.com", "password": "guess"}).Code)
+	}
+	if codes[0] != http.StatusUnauthorized || codes[1] != http.StatusUnauthorized || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("statuses = %v, want 401, 401, 429", codes)
+	}
+}
+
+func TestConcurrentFailedLoginsAreAllCounted(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "5")
+	db := testutil.NewDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	user := createLoginUser(t, db, "target", "correct horse")
+	router := loginRouter(db)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doJSON(router, http.MethodPost, "/auth/login", map[string]string{"email": user.Email, "password": "wrong"})
+		}()
+	}
+	wg.Wait()
+
+	w := doJSON(router, http.MethodPost, "/auth/login", map[string]string{"email": user.Email, "password": "correct horse"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("login after 5 parallel failures: status = %d, want 429", w.Code)
+	}
+}
+
+func TestFailedLoginRetriesWhenTheCounterIsCreatedConcurrently(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "1")
+	db := testutil.NewDB(t)
+	router := loginRouter(db)
+
+	// The first insert loses the race to another failure creating the same counter
+	raced := false
+	if err := db.Callback().Create().Before("gorm:create").Register("test:race_login_attempt", func(tx *gorm.DB) {
+		if tx.Statement.Table == "login_attempts" && !raced {
+			raced = true
+			tx.AddError(gorm.ErrDuplicatedKey)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(password string) int {
+		return doJSON(router, http.MethodPost, "/auth/login", map[string]string{"email": "racer@example.com", "password": password}).Code
+	}
+	if code := login("guess"); code != http.StatusUnauthorized {
+		t.Fatalf("failed login: status = %d, want 401", code)
+	}
+	if code := login("guess"); code != http.StatusTooManyRequests {
+		t.Errorf("login after the raced failure: status = %d, want 429 with the failure counted", code)
+	}
+}
+
+func TestRegisterUsesConfiguredBcryptCost(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "5")
+	db := testutil.NewDB(t)
+	router := testRouter(0)
+	router.POST("/auth/register", NewAuthHandler(db).Register)
+
+	req := map[string]string{"username": "hasher", "email": "hasher@example.com", "password": "secret-password"}
+	if w := doJSON(router, http.MethodPost, "/auth/register", req); w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", w.Code, w.Body)
+	}
+
+	var user models.User
+	if err := db.Where("email = ?", req["email"]).First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cost, err := bcrypt.Cost([]byte(user.Password)); err != nil || cost != 5 {
+		t.Errorf("hash cost = %d (%v), want 5", cost, err)
+	}
+	if !passwordMatches(t, db, user.ID, "secret-password") {
+		t.Error("the stored hash doesn't verify the password")
+	}
+}
+
+func TestRefreshTokenRejectsAccessTokens(t *testing.T) {
+	db := testutil.NewDB(t)
+	handler := NewAuthHandler(db)
+	router := testRouter(0)
+	router.POST("/auth/refresh", handler.RefreshToken)
+
+	user := createUser(t, db, "refresher")
+	access, refresh, err := handler.generateTokens(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := doJSON(router, http.MethodPost, "/auth/refresh", map[string]string{"refresh_token": access}); w.Code != http.StatusUnauthorized {
+		t.Errorf("refreshing with an access token: status = %d, want 401", w.Code)
+	}
+	if w := doJSON(router, http.MethodPost, "/auth/refresh", map[string]string{"refresh_token": refresh}); w.Code != http.StatusOK {
+		t.Errorf("refreshing with a refresh token: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
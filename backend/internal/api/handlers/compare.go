@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CountDiff describes how a single count changed between two crawls
+type CountDiff struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Delta int `json:"delta"`
+}
+
+// TitleDiff describes how the page title changed between two crawls
+type TitleDiff struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Changed bool   `json:"changed"`
+}
+
+// ResultComparison is the diff between two crawl results of the same URL
+type ResultComparison struct {
+	URLID         uint                 `json:"url_id"`
+	FromResultID  uint                 `json:"from_result_id"`
+	ToResultID    uint                 `json:"to_result_id"`
+	Title         TitleDiff            `json:"title"`
+	HeadingCounts map[string]CountDiff `json:"heading_counts"`
+	LinkCounts    map[string]CountDiff `json:"link_counts"`
+	LinksAdded    []string             `json:"links_added"`
+	LinksRemoved  []string             `json:"links_removed"`
+}
+
+func newCountDiff(from, to int) CountDiff {
+	return CountDiff{From: from, To: to, Delta: to - from}
+}
+
+// CompareResults returns what changed between two crawl results of a URL
+func (h *URLHandler) CompareResults(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid URL ID",
+		})
+		return
+	}
+
+	fromID, fromErr := strconv.ParseUint(c.Query("from"), 10, 32)
+	toID, toErr := strconv.ParseUint(c.Query("to"), 10, 32)
+	if fromErr != nil || toErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Both from and to result IDs are required",
+		})
+		return
+	}
+
+	// Verify the user owns the URL
+	url, err := findOwned[models.URL](h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
+		return
+	}
+
+	// Load both results, scoped to the user's URLs
+	var results []models.CrawlResult
+	if err := h.db.Table("crawl_results").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("crawl_results.id IN ? AND urls.user_id = ? AND crawl_results.deleted_at IS NULL", []uint64{fromID, toID}, userID).
+		Select("crawl_results.*").
+		Find(&results).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve results", err)
+		return
+	}
+
+	var from, to *models.CrawlResult
+	for i := range results {
+		if uint64(results[i].ID) == fromID {
+			from = &results[i]
+		}
+		if uint64(results[i].ID) == toID {
+			to = &results[i]
+		}
+	}
+
+	if from == nil || to == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Result not found",
+		})
+		return
+	}
+
+	if from.URLID != url.ID || to.URLID != url.ID {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Both results must belong to this URL",
+		})
+		return
+	}
+
+	// Compute added/removed links by set difference
+	var fromLinks, toLinks []string
+	if err := h.db.Model(&models.Link{}).Where("crawl_result_id = ?", from.ID).Pluck("url", &fromLinks).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve links", err)
+		return
+	}
+	if err := h.db.Model(&models.Link{}).Where("crawl_result_id = ?", to.ID).Pluck("url", &toLinks).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve links", err)
+		return
+	}
+
+	fromHeadings := from.GetHeadingCounts()
+	toHeadings := to.GetHeadingCounts()
+	headingDiffs := make(map[string]CountDiff, len(fromHeadings))
+	for level, count := range fromHeadings {
+		headingDiffs[level] = newCountDiff(count, toHeadings[level])
+	}
+
+	comparison := ResultComparison{
+		URLID:        url.ID,
+		FromResultID: from.ID,
+		ToResultID:   to.ID,
+		Title: TitleDiff{
+			From:    from.Title,
+			To:      to.Title,
+			Changed: from.Title != to.Title,
+		},
+		HeadingCounts: headingDiffs,
+		LinkCounts: map[string]CountDiff{
+			"internal": newCountDiff(from.InternalLinks, to.InternalLinks),
+			"external": newCountDiff(from.ExternalLinks, to.ExternalLinks),
+			"broken":   newCountDiff(from.BrokenLinks, to.BrokenLinks),
+			"total":    newCountDiff(from.GetTotalLinks(), to.GetTotalLinks()),
+		},
+		LinksAdded:   stringSetDifference(toLinks, fromLinks),
+		LinksRemoved: stringSetDifference(fromLinks, toLinks),
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    comparison,
+	})
+}
+
+// stringSetDifference returns the sorted unique values in a that aren't in b
+func stringSetDifference(a, b []string) []string {
+	exclude := make(map[string]bool, len(b))
+	for _, value := range b {
+		exclude[value] = true
+	}
+
+	difference := []string{}
+	for _, value := range a {
+		if !exclude[value] {
+			difference = append(difference, value)
+			exclude[value] = true // avoid duplicates in the output
+		}
+	}
+
+	sort.Strings(difference)
+	return difference
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// compareRouter serves the comparison endpoint as userID
+func compareRouter(db *gorm.DB, userID uint) http.Handler {
+	router := testRouter(userID)
+	router.GET("/urls/:id/compare", NewURLHandler(db).CompareResults)
+	return router
+}
+
+// createLinks saves internal links with the given addresses on a crawl result
+func createLinks(t *testing.T, db *gorm.DB, resultID uint, addresses ...string) {
+	t.Helper()
+	for _, address := range addresses {
+		link := models.Link{CrawlResultID: resultID, URL: address, Type: models.LinkTypeInternal}
+		if err := db.Create(&link).Error; err != nil {
+			t.Fatalf("creating link %s: %v", address, err)
+		}
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "comparer")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+
+	from := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Old", H1Count: 1, H2Count: 4, InternalLinks: 3, BrokenLinks: 1})
+	createLinks(t, db, from.ID, "https://example.com/a", "https://example.com/b", "https://example.com/old")
+	to := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "New", H1Count: 1, H2Count: 2, InternalLinks: 4})
+	createLinks(t, db, to.ID, "https://example.com/a", "https://example.com/b", "https://example.com/new", "https://example.com/newer")
+
+	w := doJSON(compareRouter(db, user.ID), http.MethodGet, fmt.Sprintf("/urls/%d/compare?from=%d&to=%d", urlEntry.ID, from.ID, to.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Data ResultComparison `json:"data"`
+	}
+	decodeBody(t, w, &body)
+	comparison := body.Data
+
+	if comparison.Title != (TitleDiff{From: "Old", To: "New", Changed: true}) {
+		t.Errorf("title diff = %+v", comparison.Title)
+	}
+	if got := comparison.HeadingCounts["h2"]; got != (CountDiff{From: 4, To: 2, Delta: -2}) {
+		t.Errorf("h2 diff = %+v, want 4 to 2", got)
+	}
+	if got := comparison.HeadingCounts["h1"]; got.Delta != 0 {
+		t.Errorf("h1 diff = %+v, want unchanged", got)
+	}
+	if got := comparison.LinkCounts["internal"]; got != (CountDiff{From: 3, To: 4, Delta: 1}) {
+		t.Errorf("internal link diff = %+v, want 3 to 4", got)
+	}
+	if got := comparison.LinkCounts["broken"]; got != (CountDiff{From: 1, To: 0, Delta: -1}) {
+		t.Errorf("broken link diff = %+v, want 1 to 0", got)
+	}
+	if want := []string{"https://example.com/new", "https://example.com/newer"}; !slices.Equal(comparison.LinksAdded, want) {
+		t.Errorf("links added = %v, want %v", comparison.LinksAdded, want)
+	}
+	if want := []string{"https://example.com/old"}; !slices.Equal(comparison.LinksRemoved, want) {
+		t.Errorf("links removed = %v, want %v", comparison.LinksRemoved, want)
+	}
+}
+
+func TestCompareResultsErrors(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "comparer")
+	other := createUser(t, db, "other")
+
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+	result := createResult(t, db, urlEntry.ID, models.CrawlResult{})
+	otherURLResult := createResult(t, db, createURL(t, db, user.ID, "https://other.example.com").ID, models.CrawlResult{})
+	otherUserResult := createResult(t, db, createURL(t, db, other.ID, "https://theirs.example.com").ID, models.CrawlResult{})
+
+	router := compareRouter(db, user.ID)
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"missing to", fmt.Sprintf("from=%d", result.ID), http.StatusBadRequest},
+		{"result of another URL", fmt.Sprintf("from=%d&to=%d", result.ID, otherURLResult.ID), http.StatusBadRequest},
+		{"result of another user", fmt.Sprintf("from=%d&to=%d", result.ID, otherUserResult.ID), http.StatusNotFound},
+		{"unknown result", fmt.Sprintf("from=%d&to=9999", result.ID), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d/compare?%s", urlEntry.ID, tt.query), nil)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+
+	// Someone else's URL isn't found
+	w := doJSON(compareRouter(db, other.ID), http.MethodGet, fmt.Sprintf("/urls/%d/compare?from=%d&to=%d", urlEntry.ID, result.ID, result.ID), nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("another user's URL: status = %d, want 404", w.Code)
+	}
+}
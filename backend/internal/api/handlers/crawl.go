@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
+	"skyell-backend/internal/config"
 	"skyell-backend/internal/crawler"
 	"skyell-backend/internal/models"
 
@@ -24,18 +28,19 @@ func NewCrawlHandler(db *gorm.DB) *CrawlHandler {
 	}
 }
 
+// CrawlerService returns the service the handler's crawls and status polls go through
+func (h *CrawlHandler) CrawlerService() *crawler.CrawlerService {
+	return h.crawlerService
+}
+
 type BulkCrawlRequest struct {
 	IDs []uint `json:"ids" binding:"required"`
 }
 
 // StartCrawl initiates crawling for a specific URL
 func (h *CrawlHandler) StartCrawl(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -48,26 +53,54 @@ func (h *CrawlHandler) StartCrawl(c *gin.Context) {
 		return
 	}
 
-	// Find the URL
-	var url models.URL
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&url).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
+	priority, ok := parsePriority(c, crawler.DefaultPriority)
+	if !ok {
+		return
+	}
+
+	force := false
+	if forceParam := c.Query("force"); forceParam != "" {
+		if force, err = strconv.ParseBool(forceParam); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "URL not found",
+				"message": "Invalid force: must be true or false",
 			})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
+	}
+
+	// Find the URL
+	url, err := findOwned[models.URL](h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
+		return
+	}
+
+	// Re-crawling a completed URL has to be asked for explicitly
+	if url.Status == models.StatusCompleted && !force {
+		c.JSON(http.StatusConflict, gin.H{
 			"success": false,
-			"message": "Failed to find URL",
-			"error":   err.Error(),
+			"message": "URL has already been crawled; pass force=true to crawl it again",
 		})
 		return
 	}
 
-	// Check if URL is already running
-	if url.Status == models.StatusRunning {
+	// Atomically mark the URL as running so concurrent requests can't start duplicate
+	// crawls, counting it toward the daily crawl quota in the same transaction
+	maxCrawls := dailyCrawlLimit()
+	claimed, err := h.crawlerService.StartCrawls(userID, []uint{url.ID}, maxCrawls)
+	if errors.Is(err, crawler.ErrDailyCrawlLimit) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("Daily crawl limit reached: you can run at most %d crawls per day", maxCrawls),
+		})
+		return
+	}
+	if err != nil {
+		respondInternalError(c, "Failed to update URL status", err)
+		return
+	}
+	if len(claimed) == 0 {
 		c.JSON(http.StatusConflict, gin.H{
 			"success": false,
 			"message": "URL crawling is already in progress",
@@ -75,13 +108,8 @@ func (h *CrawlHandler) StartCrawl(c *gin.Context) {
 		return
 	}
 
-	// Start crawling asynchronously
-	go func() {
-		if err := h.crawlerService.CrawlURL(uint(id)); err != nil {
-			// Log error - in production you'd want proper logging here
-			fmt.Printf("Crawl error for URL %d: %v\n", id, err)
-		}
-	}()
+	// Queue the crawl; single crawls default to a higher priority than bulk ones
+	h.crawlerService.Enqueue(url.ID, url.UserID, priority)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -96,12 +124,8 @@ func (h *CrawlHandler) StartCrawl(c *gin.Context) {
 
 // StopCrawl stops crawling for a specific URL
 func (h *CrawlHandler) StopCrawl(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -115,20 +139,9 @@ func (h *CrawlHandler) StopCrawl(c *gin.Context) {
 	}
 
 	// Find the URL
-	var url models.URL
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&url).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "URL not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to find URL",
-			"error":   err.Error(),
-		})
+	url, err := findOwned[models.URL](h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
 		return
 	}
 
@@ -146,18 +159,13 @@ func (h *CrawlHandler) StopCrawl(c *gin.Context) {
 	url.ErrorMessage = "Crawling stopped by user"
 
 	if err := h.db.Save(&url).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to update URL status",
-			"error":   err.Error(),
-		})
+		respondInternalError(c, "Failed to update URL status", err)
 		return
 	}
 
-	// TODO: In a real implementation, this is where we would:
-	// 1. Send a signal to stop the crawling process
-	// 2. Remove from job queue if not yet processed
-	// 3. Gracefully terminate any ongoing crawler operations
+	// Stop the in-flight crawl, if any
+	h.crawlerService.Cancel(url.ID)
+	h.crawlerService.RecordEvent(url.ID, url.UserID, models.EventStopped, url.ErrorMessage)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -172,22 +180,14 @@ func (h *CrawlHandler) StopCrawl(c *gin.Context) {
 
 // BulkStartCrawl starts crawling for multiple URLs
 func (h *CrawlHandler) BulkStartCrawl(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
 	var req BulkCrawlRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -199,14 +199,15 @@ func (h *CrawlHandler) BulkStartCrawl(c *gin.Context) {
 		return
 	}
 
+	priority, ok := parsePriority(c, crawler.DefaultBulkPriority)
+	if !ok {
+		return
+	}
+
 	// Find URLs that belong to the user and are not already running
 	var urls []models.URL
 	if err := h.db.Where("id IN ? AND user_id = ? AND status != ?", req.IDs, userID, models.StatusRunning).Find(&urls).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to find URLs",
-			"error":   err.Error(),
-		})
+		respondInternalError(c, "Failed to find URLs", err)
 		return
 	}
 
@@ -218,24 +219,39 @@ func (h *CrawlHandler) BulkStartCrawl(c *gin.Context) {
 		return
 	}
 
-	// Update status to running for all found URLs
+	// Atomically claim the URLs, skipping those started concurrently. The whole batch
+	// counts toward the daily crawl quota and is refused if it doesn't fit.
+	ids := make([]uint, len(urls))
+	for i, url := range urls {
+		ids[i] = url.ID
+	}
+	maxCrawls := dailyCrawlLimit()
+	claimed, err := h.crawlerService.StartCrawls(userID, ids, maxCrawls)
+	if errors.Is(err, crawler.ErrDailyCrawlLimit) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("Daily crawl limit exceeded: you can run at most %d crawls per day", maxCrawls),
+		})
+		return
+	}
+	if err != nil {
+		respondInternalError(c, "Failed to update URL status", err)
+		return
+	}
+
 	var updatedURLs []gin.H
 	for _, url := range urls {
-		url.Status = models.StatusRunning
-		url.ErrorMessage = ""
-
-		if err := h.db.Save(&url).Error; err != nil {
-			// Log error but continue with other URLs
+		if !slices.Contains(claimed, url.ID) {
 			continue
 		}
 
 		updatedURLs = append(updatedURLs, gin.H{
 			"id":     url.ID,
 			"url":    url.URL,
-			"status": url.Status,
+			"status": models.StatusRunning,
 		})
 
-		// TODO: Add to job queue for actual crawling
+		h.crawlerService.Enqueue(url.ID, url.UserID, priority)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -247,22 +263,14 @@ func (h *CrawlHandler) BulkStartCrawl(c *gin.Context) {
 
 // BulkStopCrawl stops crawling for multiple URLs
 func (h *CrawlHandler) BulkStopCrawl(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
 	var req BulkCrawlRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -277,11 +285,7 @@ func (h *CrawlHandler) BulkStopCrawl(c *gin.Context) {
 	// Find URLs that belong to the user and are currently running
 	var urls []models.URL
 	if err := h.db.Where("id IN ? AND user_id = ? AND status = ?", req.IDs, userID, models.StatusRunning).Find(&urls).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to find URLs",
-			"error":   err.Error(),
-		})
+		respondInternalError(c, "Failed to find URLs", err)
 		return
 	}
 
@@ -310,7 +314,9 @@ func (h *CrawlHandler) BulkStopCrawl(c *gin.Context) {
 			"status": url.Status,
 		})
 
-		// TODO: Send stop signals to running crawlers
+		// Stop the in-flight crawl, if any
+		h.crawlerService.Cancel(url.ID)
+		h.crawlerService.RecordEvent(url.ID, url.UserID, models.EventStopped, url.ErrorMessage)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -320,51 +326,184 @@ func (h *CrawlHandler) BulkStopCrawl(c *gin.Context) {
 	})
 }
 
-// GetCrawlStatus returns the current crawling status for all URLs or specific ones
-func (h *CrawlHandler) GetCrawlStatus(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
+// StopAllCrawls stops every running crawl of the authenticated user
+func (h *CrawlHandler) StopAllCrawls(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var runningIDs []uint
+	if err := h.db.Model(&models.URL{}).
+		Where("user_id = ? AND status = ?", userID, models.StatusRunning).
+		Pluck("id", &runningIDs).Error; err != nil {
+		respondInternalError(c, "Failed to find running URLs", err)
+		return
+	}
+
+	if len(runningIDs) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "No running crawls to stop",
+			"data":    gin.H{"stopped": 0},
 		})
 		return
 	}
 
-	var urls []models.URL
-	query := h.db.Where("user_id = ?", userID).Select("id, url, status, error_message, updated_at")
+	// Reset status to queued (stopped) before cancelling so crawlers don't record a result
+	result := h.db.Model(&models.URL{}).
+		Where("id IN ? AND status = ?", runningIDs, models.StatusRunning).
+		Updates(map[string]interface{}{
+			"status":        models.StatusQueued,
+			"error_message": "Crawling stopped by user",
+		})
+	if result.Error != nil {
+		respondInternalError(c, "Failed to update URL status", result.Error)
+		return
+	}
+
+	for _, id := range runningIDs {
+		h.crawlerService.Cancel(id)
+		h.crawlerService.RecordEvent(id, userID, models.EventStopped, "Crawling stopped by user")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Stopped crawling for %d URL(s)", result.RowsAffected),
+		"data":    gin.H{"stopped": result.RowsAffected},
+	})
+}
+
+// GetCrawlStatus returns the current crawling status for all URLs or specific ones
+func (h *CrawlHandler) GetCrawlStatus(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
 
 	// Check if specific URL IDs are requested
-	if idsParam := c.Query("ids"); idsParam != "" {
-		query = query.Where("id IN (?)", idsParam)
+	idsParam := c.Query("ids")
+	if idsParam == "" {
+		// Polls for all URLs are served from the short-lived status cache
+		snapshot, err := h.crawlerService.StatusSnapshot(userID)
+		if err != nil {
+			respondInternalError(c, "Failed to retrieve crawl status", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"urls":    snapshot.URLs,
+				"summary": snapshot.Summary,
+			},
+		})
+		return
 	}
 
-	if err := query.Find(&urls).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	ids, err := parseIDList(idsParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Failed to retrieve crawl status",
-			"error":   err.Error(),
+			"message": "Invalid ids: must contain at least one comma-separated URL ID",
 		})
 		return
 	}
 
-	// Group URLs by status for summary
-	statusSummary := map[string]int{
-		"queued":    0,
-		"running":   0,
-		"completed": 0,
-		"error":     0,
+	h.respondWithStatuses(c, userID, ids)
+}
+
+// maxStatusIDs is the most URLs a status request reports on, matching StatusBatchRequest
+const maxStatusIDs = 1000
+
+// StatusBatchRequest lists the URLs to report on; a JSON body avoids URL length limits
+type StatusBatchRequest struct {
+	IDs []uint `json:"ids" binding:"required,min=1,max=1000"`
+}
+
+// BatchCrawlStatus returns the crawling status of the URLs listed in the request body
+func (h *CrawlHandler) BatchCrawlStatus(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
 	}
 
-	for _, url := range urls {
-		statusSummary[string(url.Status)]++
+	var req StatusBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	h.respondWithStatuses(c, userID, req.IDs)
+}
+
+// respondWithStatuses responds with the status of the given URLs that belong to the user,
+// along with a count per status. IDs of other users' URLs are silently left out.
+func (h *CrawlHandler) respondWithStatuses(c *gin.Context, userID uint, ids []uint) {
+	var urls []models.URL
+	if err := h.db.Where("user_id = ? AND id IN ?", userID, ids).
+		Select("id, url, status, error_message, updated_at").
+		Find(&urls).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve crawl status", err)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
 			"urls":    urls,
-			"summary": statusSummary,
+			"summary": crawler.SummarizeStatuses(urls),
 		},
 	})
 }
+
+// parseIDList parses a comma-separated list of IDs such as "1,2,3". Entries that aren't
+// valid IDs and repeated IDs are dropped, and only the first maxStatusIDs IDs are kept, so
+// a polling client with a stale or garbled list still gets the statuses it can. It fails
+// only when no valid ID is left.
+func parseIDList(raw string) ([]uint, error) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		if ids = append(ids, uint(id)); len(ids) == maxStatusIDs {
+			break
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no valid IDs given")
+	}
+	return ids, nil
+}
+
+// parsePriority reads the optional priority query param of the start endpoints. It writes
+// a 400 and returns false when the value is out of range.
+func parsePriority(c *gin.Context, fallback int) (int, bool) {
+	raw := c.Query("priority")
+	if raw == "" {
+		return fallback, true
+	}
+
+	priority, err := strconv.Atoi(raw)
+	if err != nil || priority < crawler.MinPriority || priority > crawler.MaxPriority {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("Invalid priority: must be an integer from %d to %d", crawler.MinPriority, crawler.MaxPriority),
+		})
+		return 0, false
+	}
+	return priority, true
+}
+
+// dailyCrawlLimit is how many crawls a user may start per day (MAX_CRAWLS_PER_DAY; 0 means
+// no limit). The quota counts the started events crawler.StartCrawls records as it claims
+// URLs rather than crawl results: every crawl started today counts, whether it's still
+// queued or running, was stopped, failed or had its results deleted, so the quota can't be
+// escaped by any of those.
+func dailyCrawlLimit() int {
+	return max(config.GetEnvInt("MAX_CRAWLS_PER_DAY", 0), 0)
+}
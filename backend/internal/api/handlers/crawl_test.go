@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestStartCrawlWithIdempotencyKeyStartsOnce(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "idem")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", middleware.Idempotency(db), handler.StartCrawl)
+
+	start := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/crawl/start/%d", urlEntry.ID), nil)
+		req.Header.Set(middleware.IdempotencyKeyHeader, "start-once")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first, second := start(), start()
+	if first.Code != http.StatusOK {
+		t.Fatalf("first start: status = %d, want 200: %s", first.Code, first.Body)
+	}
+	if second.Code != http.StatusOK || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("repeated start: status = %d, replayed = %q, want the first response replayed",
+			second.Code, second.Header().Get("Idempotent-Replayed"))
+	}
+
+	var started int64
+	db.Model(&models.CrawlEvent{}).Where("url_id = ? AND type = ?", urlEntry.ID, models.EventStarted).Count(&started)
+	if started != 1 {
+		t.Errorf("%d crawls started, want 1", started)
+	}
+}
+
+func TestConcurrentStartCrawlStartsOnce(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "racer")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+
+	// One connection serializes the statements so SQLite doesn't report the table locked
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	// Hold both requests after they've loaded the queued URL, so both pass the status
+	// check before either marks the URL running
+	var arrivals sync.WaitGroup
+	arrivals.Add(2)
+	var loads atomic.Int32
+	db.Callback().Query().After("gorm:query").Register("test:race", func(tx *gorm.DB) {
+		if tx.Statement.Table != "urls" || loads.Add(1) > 2 {
+			return
+		}
+		arrivals.Done()
+		done := make(chan struct{})
+		go func() { arrivals.Wait(); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", urlEntry.ID), nil).Code
+		}()
+	}
+	wg.Wait()
+
+	ok, conflict := 0, 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			ok++
+		case http.StatusConflict:
+			conflict++
+		}
+	}
+	if ok != 1 || conflict != 1 {
+		t.Errorf("statuses = %v, want one 200 and one 409", codes)
+	}
+
+	var started int64
+	db.Model(&models.CrawlEvent{}).Where("url_id = ? AND type = ?", urlEntry.ID, models.EventStarted).Count(&started)
+	if started != 1 {
+		t.Errorf("%d crawls started, want 1", started)
+	}
+}
+
+func TestCrawlStatusOfSelectedURLs(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+	queued := createURL(t, db, owner.ID, "https://queued.example")
+	completed := createURL(t, db, owner.ID, "https://completed.example")
+	if err := db.Model(&completed).Update("status", models.StatusCompleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	createURL(t, db, owner.ID, "https://unlisted.example")
+	foreign := createURL(t, db, other.ID, "https://foreign.example")
+
+	handler := NewCrawlHandler(db)
+	router := testRouter(owner.ID)
+	router.GET("/status/crawl", handler.GetCrawlStatus)
+	router.POST("/status/batch", handler.BatchCrawlStatus)
+
+	// statuses decodes the URL IDs and summary of a status response
+	statuses := func(w *httptest.ResponseRecorder) ([]uint, map[string]int) {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Data struct {
+				URLs    []models.URL   `json:"urls"`
+				Summary map[string]int `json:"summary"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+
+		ids := make([]uint, 0, len(body.Data.URLs))
+		for _, u := range body.Data.URLs {
+			ids = append(ids, u.ID)
+		}
+		slices.Sort(ids)
+		return ids, body.Data.Summary
+	}
+	want := []uint{queued.ID, completed.ID}
+
+	// Each ID of the list is looked up, not the whole list as one value
+	ids, summary := statuses(doJSON(router, http.MethodGet, fmt.Sprintf("/status/crawl?ids=%d,%d,%d", queued.ID, completed.ID, foreign.ID), nil))
+	if !slices.Equal(ids, want) {
+		t.Errorf("GET ids = %v, want %v", ids, want)
+	}
+	if summary[string(models.StatusQueued)] != 1 || summary[string(models.StatusCompleted)] != 1 {
+		t.Errorf("GET summary = %v, want one queued and one completed", summary)
+	}
+
+	// Spaces, repeats and garbage are tolerated
+	ids, _ = statuses(doJSON(router, http.MethodGet, fmt.Sprintf("/status/crawl?ids=%d,+%d,%d,abc,", completed.ID, queued.ID, completed.ID), nil))
+	if !slices.Equal(ids, want) {
+		t.Errorf("GET with a messy list = %v, want %v", ids, want)
+	}
+
+	ids, _ = statuses(doJSON(router, http.MethodPost, "/status/batch", map[string]any{"ids": []uint{queued.ID, completed.ID, foreign.ID}}))
+	if !slices.Equal(ids, want) {
+		t.Errorf("POST ids = %v, want %v", ids, want)
+	}
+
+	// IDs past the first maxStatusIDs aren't looked up
+	long := make([]string, 0, maxStatusIDs+1)
+	for id := 100000; len(long) < maxStatusIDs; id++ {
+		long = append(long, strconv.Itoa(id))
+	}
+	long = append(long, strconv.FormatUint(uint64(queued.ID), 10))
+	ids, _ = statuses(doJSON(router, http.MethodGet, "/status/crawl?ids="+strings.Join(long, ","), nil))
+	if len(ids) != 0 {
+		t.Errorf("GET with URL %d past the cap = %v, want none", queued.ID, ids)
+	}
+
+	if w := doJSON(router, http.MethodGet, "/status/crawl?ids=abc,", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET without a valid ID: status = %d, want 400", w.Code)
+	}
+	for _, body := range []any{map[string]any{"ids": []uint{}}, map[string]any{}} {
+		if w := doJSON(router, http.MethodPost, "/status/batch", body); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %v: status = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestStartCrawlPriorityParam(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "priority")
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+	router.POST("/crawl/bulk-start", handler.BulkStartCrawl)
+
+	for _, priority := range []string{"-1", "11", "urgent"} {
+		urlEntry := createURL(t, db, user.ID, "https://example.com/"+priority)
+		if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d?priority=%s", urlEntry.ID, priority), nil); w.Code != http.StatusBadRequest {
+			t.Errorf("start with priority %s: status = %d, want 400", priority, w.Code)
+		}
+		if w := doJSON(router, http.MethodPost, "/crawl/bulk-start?priority="+priority, map[string]any{"ids": []uint{urlEntry.ID}}); w.Code != http.StatusBadRequest {
+			t.Errorf("bulk start with priority %s: status = %d, want 400", priority, w.Code)
+		}
+	}
+
+	urgent := createURL(t, db, user.ID, "https://example.com/urgent")
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d?priority=10", urgent.ID), nil); w.Code != http.StatusOK {
+		t.Errorf("start with priority 10: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	bulk := createURL(t, db, user.ID, "https://example.com/bulk")
+	if w := doJSON(router, http.MethodPost, "/crawl/bulk-start?priority=0", map[string]any{"ids": []uint{bulk.ID}}); w.Code != http.StatusOK {
+		t.Errorf("bulk start with priority 0: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if depth := handler.crawlerService.QueueStatus().Depth; depth != 2 {
+		t.Errorf("queue depth = %d, want the 2 accepted crawls", depth)
+	}
+}
+
+func TestStartCrawlOfCompletedURLNeedsForce(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "recrawler")
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+
+	withStatus := func(address string, status models.CrawlStatus) models.URL {
+		t.Helper()
+		urlEntry := createURL(t, db, user.ID, address)
+		if err := db.Model(&urlEntry).Update("status", status).Error; err != nil {
+			t.Fatal(err)
+		}
+		return urlEntry
+	}
+	start := func(id uint, query string) int {
+		t.Helper()
+		return doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d%s", id, query), nil).Code
+	}
+
+	completed := withStatus("https://example.com/done", models.StatusCompleted)
+	if code := start(completed.ID, ""); code != http.StatusConflict {
+		t.Errorf("completed URL without force: status = %d, want 409", code)
+	}
+	if code := start(completed.ID, "?force=false"); code != http.StatusConflict {
+		t.Errorf("completed URL with force=false: status = %d, want 409", code)
+	}
+	if code := start(completed.ID, "?force=maybe"); code != http.StatusBadRequest {
+		t.Errorf("invalid force: status = %d, want 400", code)
+	}
+	var saved models.URL
+	db.First(&saved, completed.ID)
+	if saved.Status != models.StatusCompleted || handler.crawlerService.QueueStatus().Depth != 0 {
+		t.Fatalf("a blocked start changed the URL to %s or queued it", saved.Status)
+	}
+
+	if code := start(completed.ID, "?force=true"); code != http.StatusOK {
+		t.Errorf("completed URL with force=true: status = %d, want 200", code)
+	}
+
+	// Other URLs start without force
+	for _, status := range []models.CrawlStatus{models.StatusQueued, models.StatusError} {
+		urlEntry := withStatus("https://example.com/"+string(status), status)
+		if code := start(urlEntry.ID, ""); code != http.StatusOK {
+			t.Errorf("%s URL: status = %d, want 200", status, code)
+		}
+	}
+	if depth := handler.crawlerService.QueueStatus().Depth; depth != 3 {
+		t.Errorf("queue depth = %d, want the forced crawl and the 2 other starts", depth)
+	}
+}
+
+func TestParseIDList(t *testing.T) {
+	tooMany := make([]string, maxStatusIDs+5)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i + 1)
+	}
+
+	tests := []struct {
+		raw     string
+		want    []uint
+		wantErr bool
+	}{
+		{"1,2,3", []uint{1, 2, 3}, false},
+		{" 4 , x, 5,-6, 0, 4,99999999999, 7,", []uint{4, 5, 7}, false},
+		{"abc,,0,-1", nil, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseIDList(tt.raw)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("parseIDList(%q) = %v, %v; want %v, error: %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	got, err := parseIDList(strings.Join(tooMany, ","))
+	if err != nil || len(got) != maxStatusIDs || got[0] != 1 || got[len(got)-1] != maxStatusIDs {
+		t.Errorf("parsing %d IDs kept %d (%v), want the first %d", len(tooMany), len(got), err, maxStatusIDs)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"skyell-backend/internal/api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondInternalError logs err with a correlation ID and writes a 500. The error
+// details are only included in the response outside of production.
+func respondInternalError(c *gin.Context, message string, err error) {
+	middleware.RespondInternalError(c, message, err)
+}
+
+// NotFound responds to requests for unknown routes
+func NotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"success": false,
+		"message": "Route not found",
+		"code":    "not_found",
+	})
+}
+
+// MethodNotAllowed responds to requests using a method the route doesn't support.
+// Gin has already set the Allow header to the supported methods.
+func MethodNotAllowed(c *gin.Context) {
+	c.JSON(http.StatusMethodNotAllowed, gin.H{
+		"success": false,
+		"message": "Method not allowed",
+		"code":    "method_not_allowed",
+	})
+}
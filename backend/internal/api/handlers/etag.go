@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// computeETag builds a strong ETag from the values that determine a response
+func computeETag(parts ...any) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%v|", part)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+}
+
+// notModified sets the ETag header and, when the client's If-None-Match matches,
+// responds with 304 and returns true so the handler can stop early
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == etag || candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+// getIfNoneMatch sends a GET with the given If-None-Match header, if any
+func getIfNoneMatch(router http.Handler, target, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalGets(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "poller")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+	otherURL := createURL(t, db, user.ID, "https://other.example.com")
+	result := createResult(t, db, otherURL.ID, models.CrawlResult{Title: "Example"})
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls/:id", handler.GetURL)
+	router.GET("/results/:id", handler.GetResultDetail)
+
+	for _, target := range []string{fmt.Sprintf("/urls/%d", urlEntry.ID), fmt.Sprintf("/results/%d", result.ID)} {
+		first := getIfNoneMatch(router, target, "")
+		if first.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", target, first.Code, first.Body)
+		}
+		etag := first.Header().Get("ETag")
+		if etag == "" {
+			t.Fatalf("GET %s: no ETag", target)
+		}
+
+		for _, ifNoneMatch := range []string{etag, "W/" + etag, `"stale", ` + etag} {
+			w := getIfNoneMatch(router, target, ifNoneMatch)
+			if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+				t.Errorf("GET %s with If-None-Match %s: status = %d with %d bytes, want an empty 304",
+					target, ifNoneMatch, w.Code, w.Body.Len())
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("GET %s: 304 has ETag %q, want %q", target, w.Header().Get("ETag"), etag)
+			}
+		}
+		if w := getIfNoneMatch(router, target, `"stale"`); w.Code != http.StatusOK {
+			t.Errorf("GET %s with a stale ETag: status = %d, want 200", target, w.Code)
+		}
+	}
+
+	// Changing the URL changes its ETag
+	first := getIfNoneMatch(router, fmt.Sprintf("/urls/%d", urlEntry.ID), "")
+	if err := db.Model(&urlEntry).Updates(map[string]any{"status": models.StatusRunning, "updated_at": time.Now().Add(time.Second)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	w := getIfNoneMatch(router, fmt.Sprintf("/urls/%d", urlEntry.ID), first.Header().Get("ETag"))
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == first.Header().Get("ETag") {
+		t.Errorf("GET an updated URL: status = %d, ETag = %q, want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
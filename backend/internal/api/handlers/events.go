@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validEventTypes are the accepted values of the event_type filter
+var validEventTypes = map[models.CrawlEventType]bool{
+	models.EventStarted:   true,
+	models.EventCompleted: true,
+	models.EventFailed:    true,
+	models.EventStopped:   true,
+}
+
+// GetURLEvents returns a page of a URL's crawl audit trail, most recent first, optionally
+// filtered by event_type
+func (h *URLHandler) GetURLEvents(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid URL ID",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	if !checkPageDepth(c, h.pagination, page, limit, false) {
+		return
+	}
+
+	eventType := models.CrawlEventType(c.Query("event_type"))
+	if eventType != "" && !validEventTypes[eventType] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid event_type: must be started, completed, failed or stopped",
+		})
+		return
+	}
+
+	url, err := findOwned[models.URL](h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
+		return
+	}
+
+	query := h.db.Model(&models.CrawlEvent{}).Where("url_id = ?", url.ID)
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondInternalError(c, "Failed to count events", err)
+		return
+	}
+
+	events := []models.CrawlEvent{}
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&events).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve events", err)
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+	setPaginationHeaders(c, pagination)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"events":     events,
+			"pagination": pagination,
+		},
+	})
+}
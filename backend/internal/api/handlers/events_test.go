@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestCrawlEventsAreRecorded(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "auditor")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Audited</title></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	crawlHandler := NewCrawlHandler(db)
+	crawlHandler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", crawlHandler.StartCrawl)
+	router.POST("/crawl/stop/:id", crawlHandler.StopCrawl)
+	router.GET("/urls/:id/events", NewURLHandler(db).GetURLEvents)
+
+	// crawl starts a URL through the API, then runs the queued crawl
+	crawl := func(urlID uint) {
+		t.Helper()
+		if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", urlID), nil); w.Code != http.StatusOK {
+			t.Fatalf("start: status = %d, want 200: %s", w.Code, w.Body)
+		}
+		crawlHandler.crawlerService.CrawlURL(urlID)
+	}
+	eventTypes := func(urlID uint, query string) []models.CrawlEventType {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d/events?%s", urlID, query), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("events: status = %d, want 200: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Data struct {
+				Events []models.CrawlEvent `json:"events"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+
+		var types []models.CrawlEventType
+		for _, event := range body.Data.Events {
+			if event.URLID != urlID || event.UserID != user.ID {
+				t.Errorf("event %+v doesn't belong to URL %d of user %d", event, urlID, user.ID)
+			}
+			types = append(types, event.Type)
+		}
+		return types
+	}
+
+	succeeded := createURL(t, db, user.ID, server.URL+"/")
+	crawl(succeeded.ID)
+	if got, want := eventTypes(succeeded.ID, ""), []models.CrawlEventType{models.EventCompleted, models.EventStarted}; !slices.Equal(got, want) {
+		t.Errorf("events of a successful crawl = %v, want %v", got, want)
+	}
+	if got, want := eventTypes(succeeded.ID, "event_type=started"), []models.CrawlEventType{models.EventStarted}; !slices.Equal(got, want) {
+		t.Errorf("started events = %v, want %v", got, want)
+	}
+
+	failed := createURL(t, db, user.ID, server.URL+"/missing")
+	crawl(failed.ID)
+	if got, want := eventTypes(failed.ID, ""), []models.CrawlEventType{models.EventFailed, models.EventStarted}; !slices.Equal(got, want) {
+		t.Errorf("events of a failed crawl = %v, want %v", got, want)
+	}
+
+	stopped := createURL(t, db, user.ID, server.URL+"/")
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", stopped.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("start: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/stop/%d", stopped.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("stop: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if got, want := eventTypes(stopped.ID, ""), []models.CrawlEventType{models.EventStopped, models.EventStarted}; !slices.Equal(got, want) {
+		t.Errorf("events of a stopped crawl = %v, want %v", got, want)
+	}
+
+	if w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d/events?event_type=paused", stopped.ID), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown event type: status = %d, want 400", w.Code)
+	}
+}
+
+func TestGetURLEventsPagingAndFilter(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+	urlEntry := createURL(t, db, owner.ID, "https://example.com")
+	foreign := createURL(t, db, other.ID, "https://example.org")
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var ids []uint // Oldest first
+	for i, eventType := range []models.CrawlEventType{
+		models.EventStarted, models.EventFailed,
+		models.EventStarted, models.EventStopped,
+		models.EventStarted, models.EventCompleted,
+	} {
+		event := models.CrawlEvent{URLID: urlEntry.ID, UserID: owner.ID, Type: eventType, CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := db.Create(&event).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, event.ID)
+	}
+	if err := db.Create(&models.CrawlEvent{URLID: foreign.ID, UserID: other.ID, Type: models.EventStarted}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	router := testRouter(owner.ID)
+	router.GET("/urls/:id/events", NewURLHandler(db).GetURLEvents)
+	list := func(query string) ([]uint, PaginationResponse) {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d/events?%s", urlEntry.ID, query), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("events?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+		}
+		var body struct {
+			Data struct {
+				Events     []models.CrawlEvent `json:"events"`
+				Pagination PaginationResponse  `json:"pagination"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+
+		got := make([]uint, 0, len(body.Data.Events))
+		for _, event := range body.Data.Events {
+			got = append(got, event.ID)
+		}
+		return got, body.Data.Pagination
+	}
+
+	got, pagination := list("")
+	if want := []uint{ids[5], ids[4], ids[3], ids[2], ids[1], ids[0]}; !slices.Equal(got, want) {
+		t.Errorf("events = %v, want newest first %v", got, want)
+	}
+	if pagination.Total != 6 {
+		t.Errorf("total = %d, want 6", pagination.Total)
+	}
+
+	got, pagination = list("limit=2&page=2")
+	if want := []uint{ids[3], ids[2]}; !slices.Equal(got, want) {
+		t.Errorf("page 2 = %v, want %v", got, want)
+	}
+	if pagination.TotalPages != 3 {
+		t.Errorf("total pages = %d, want 3", pagination.TotalPages)
+	}
+
+	tests := []struct {
+		eventType models.CrawlEventType
+		want      []uint
+	}{
+		{models.EventStarted, []uint{ids[4], ids[2], ids[0]}},
+		{models.EventFailed, []uint{ids[1]}},
+		{models.EventStopped, []uint{ids[3]}},
+		{models.EventCompleted, []uint{ids[5]}},
+	}
+	for _, tt := range tests {
+		got, pagination := list("event_type=" + string(tt.eventType))
+		if !slices.Equal(got, tt.want) || pagination.Total != int64(len(tt.want)) {
+			t.Errorf("%s events = %v (total %d), want %v", tt.eventType, got, pagination.Total, tt.want)
+		}
+	}
+
+	if w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d/events", foreign.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("another user's URL: status = %d, want 404", w.Code)
+	}
+}
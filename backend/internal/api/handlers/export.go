@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many links are loaded from the database at a time while exporting
+const exportBatchSize = 500
+
+// ExportLink is the exported representation of a single link
+type ExportLink struct {
+	URL        string          `json:"url"`
+	Type       models.LinkType `json:"type"`
+	AnchorText string          `json:"anchor_text"`
+	StatusCode int             `json:"status_code"`
+	IsBroken   bool            `json:"is_broken"`
+}
+
+// ExportResult returns a downloadable JSON or CSV export of a crawl result and all of its links
+func (h *URLHandler) ExportResult(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid result ID",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid format: must be json or csv",
+		})
+		return
+	}
+
+	// Get crawl result with URL info, verifying ownership
+	var result struct {
+		models.CrawlResult
+		CrawlURL string `json:"crawl_url"`
+	}
+
+	if err := h.db.Table("crawl_results").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("crawl_results.id = ? AND urls.user_id = ? AND crawl_results.deleted_at IS NULL", id, userID).
+		Select("crawl_results.*, urls.url as crawl_url").
+		First(&result).Error; err != nil {
+		respondFindError(c, err, "Result not found", "Failed to retrieve result")
+		return
+	}
+
+	summary := newCrawlResultResponse(result.CrawlResult, result.CrawlURL)
+	filename := fmt.Sprintf("crawl-result-%d.%s", result.ID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var streamErr error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		streamErr = h.writeResultCSV(c, summary)
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		streamErr = h.writeResultJSON(c, summary)
+	}
+
+	if streamErr != nil {
+		// Headers are already sent, so the best we can do is log and abort the stream
+		log.Printf("Failed to export crawl result %d: %v", result.ID, streamErr)
+		c.Abort()
+	}
+}
+
+// streamLinks loads a result's links in batches and passes each batch to fn
+func (h *URLHandler) streamLinks(crawlResultID uint, fn func([]models.Link) error) error {
+	var batch []models.Link
+	return h.db.Where("crawl_result_id = ?", crawlResultID).
+		Order("id asc").
+		FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// writeResultJSON streams the export as a JSON object with a "result" and a "links" array
+func (h *URLHandler) writeResultJSON(c *gin.Context, summary CrawlResultResponse) error {
+	w := c.Writer
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"result":%s,"links":[`, summaryJSON); err != nil {
+		return err
+	}
+
+	first := true
+	err = h.streamLinks(summary.ID, func(links []models.Link) error {
+		for _, link := range links {
+			linkJSON, err := json.Marshal(ExportLink{
+				URL:        link.URL,
+				Type:       link.Type,
+				AnchorText: link.AnchorText,
+				StatusCode: link.StatusCode,
+				IsBroken:   link.IsBroken,
+			})
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := w.WriteString(","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(linkJSON); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.WriteString("]}")
+	return err
+}
+
+// writeResultCSV streams the export as CSV: a summary section followed by one row per link
+func (h *URLHandler) writeResultCSV(c *gin.Context, summary CrawlResultResponse) error {
+	w := csv.NewWriter(c.Writer)
+
+	rows := [][]string{
+		{"result_id", "url", "title", "html_version", "has_login_form", "response_status",
+			"h1_count", "h2_count", "h3_count", "h4_count", "h5_count", "h6_count",
+			"internal_links", "external_links", "broken_links", "crawled_at"},
+		{
+			strconv.FormatUint(uint64(summary.ID), 10),
+			summary.URL,
+			summary.Title,
+			summary.HTMLVersion,
+			strconv.FormatBool(summary.HasLoginForm),
+			strconv.Itoa(summary.ResponseStatus),
+			strconv.Itoa(summary.H1Count),
+			strconv.Itoa(summary.H2Count),
+			strconv.Itoa(summary.H3Count),
+			strconv.Itoa(summary.H4Count),
+			strconv.Itoa(summary.H5Count),
+			strconv.Itoa(summary.H6Count),
+			strconv.Itoa(summary.InternalLinks),
+			strconv.Itoa(summary.ExternalLinks),
+			strconv.Itoa(summary.BrokenLinks),
+			summary.CrawledAt.Format(time.RFC3339),
+		},
+		{},
+		{"link_url", "type", "anchor_text", "status_code", "is_broken"},
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return h.streamLinks(summary.ID, func(links []models.Link) error {
+		for _, link := range links {
+			if err := w.Write([]string{
+				link.URL,
+				string(link.Type),
+				link.AnchorText,
+				strconv.Itoa(link.StatusCode),
+				strconv.FormatBool(link.IsBroken),
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportRecord is one line of the full account export
+type exportRecord struct {
+	Type string      `json:"type"` // "url", "crawl_result" or "link"
+	Data interface{} `json:"data"`
+}
+
+// The exported records hide the relations of the models, which are never loaded here
+type (
+	exportedURL struct {
+		models.URL
+		User *struct{} `json:"user,omitempty"`
+	}
+	exportedResult struct {
+		models.CrawlResult
+		URL *struct{} `json:"url,omitempty"`
+	}
+	exportedLink struct {
+		models.Link
+		CrawlResult *struct{} `json:"crawl_result,omitempty"`
+	}
+)
+
+// ExportAll streams all of the user's URLs, crawl results and links as newline-delimited
+// JSON, one tagged record per line. Rows are read through database cursors, so memory use
+// doesn't grow with the size of the account.
+func (h *URLHandler) ExportAll(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	urls := h.db.Model(&models.URL{}).
+		Where("user_id = ?", userID).
+		Order("id asc")
+	results := h.db.Model(&models.CrawlResult{}).
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("urls.user_id = ? AND urls.deleted_at IS NULL", userID).
+		Select("crawl_results.*").
+		Order("crawl_results.id asc")
+	links := h.db.Model(&models.Link{}).
+		Joins("JOIN crawl_results ON links.crawl_result_id = crawl_results.id").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("urls.user_id = ? AND urls.deleted_at IS NULL AND crawl_results.deleted_at IS NULL", userID).
+		Select("links.*").
+		Order("links.id asc")
+
+	filename := fmt.Sprintf("skyell-export-%s.ndjson", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	err := streamRecords(c, h.db, urls, "url", func(url models.URL) interface{} {
+		return exportedURL{URL: url}
+	})
+	if err == nil {
+		err = streamRecords(c, h.db, results, "crawl_result", func(result models.CrawlResult) interface{} {
+			return exportedResult{CrawlResult: result}
+		})
+	}
+	if err == nil {
+		err = streamRecords(c, h.db, links, "link", func(link models.Link) interface{} {
+			return exportedLink{Link: link}
+		})
+	}
+
+	if err != nil {
+		// Headers are already sent, so the best we can do is log and abort the stream
+		log.Printf("Failed to export data of user %v: %v", userID, err)
+		c.Abort()
+	}
+}
+
+// streamRecords writes every row of query as an export line of the given type, reading the
+// rows through a cursor and flushing every exportBatchSize lines
+func streamRecords[T any](c *gin.Context, db *gorm.DB, query *gorm.DB, recordType string, data func(T) interface{}) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	for rows.Next() {
+		var record T
+		if err := db.ScanRows(rows, &record); err != nil {
+			return err
+		}
+		if err := encoder.Encode(exportRecord{Type: recordType, Data: data(record)}); err != nil {
+			return err
+		}
+
+		if written++; written%exportBatchSize == 0 {
+			c.Writer.Flush()
+		}
+	}
+	c.Writer.Flush()
+
+	return rows.Err()
+}
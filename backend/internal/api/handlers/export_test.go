@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// seedExportResult seeds a crawl result with more links than one export batch holds. The
+// first link is broken.
+func seedExportResult(t *testing.T, db *gorm.DB, userID uint) (models.CrawlResult, []models.Link) {
+	t.Helper()
+	urlEntry := createURL(t, db, userID, "https://example.com")
+	result := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Example", ResponseStatus: 200, InternalLinks: exportBatchSize + 2})
+
+	links := make([]models.Link, exportBatchSize+2)
+	for i := range links {
+		links[i] = models.Link{
+			CrawlResultID: result.ID,
+			URL:           fmt.Sprintf("https://example.com/page-%d", i),
+			AnchorText:    fmt.Sprintf("Page %d", i),
+			Type:          models.LinkTypeInternal,
+			StatusCode:    200,
+		}
+	}
+	links[0].StatusCode, links[0].IsBroken = 404, true
+	if err := db.CreateInBatches(links, 100).Error; err != nil {
+		t.Fatalf("creating links: %v", err)
+	}
+	return result, links
+}
+
+// exportRouter serves the single result export as userID
+func exportRouter(db *gorm.DB, userID uint) http.Handler {
+	router := testRouter(userID)
+	router.GET("/results/:id/export", NewURLHandler(db).ExportResult)
+	return router
+}
+
+func TestExportResultJSON(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "exporter")
+	result, links := seedExportResult(t, db, user.ID)
+
+	w := doJSON(exportRouter(db, user.ID), http.MethodGet, fmt.Sprintf("/results/%d/export", result.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, fmt.Sprintf("crawl-result-%d.json", result.ID)) {
+		t.Errorf("Content-Disposition = %q, want an attachment named after the result", disposition)
+	}
+
+	var body struct {
+		Result struct {
+			ID             uint   `json:"id"`
+			Title          string `json:"title"`
+			ResponseStatus int    `json:"response_status"`
+		} `json:"result"`
+		Links []ExportLink `json:"links"`
+	}
+	decodeBody(t, w, &body)
+
+	if body.Result.ID != result.ID || body.Result.Title != "Example" || body.Result.ResponseStatus != 200 {
+		t.Errorf("exported result = %+v, want result %d", body.Result, result.ID)
+	}
+	if len(body.Links) != len(links) {
+		t.Fatalf("exported %d links, want all %d", len(body.Links), len(links))
+	}
+	want := ExportLink{URL: links[0].URL, Type: models.LinkTypeInternal, AnchorText: "Page 0", StatusCode: 404, IsBroken: true}
+	if body.Links[0] != want {
+		t.Errorf("first link = %+v, want %+v", body.Links[0], want)
+	}
+	if last := body.Links[len(body.Links)-1]; last.URL != links[len(links)-1].URL || last.IsBroken {
+		t.Errorf("last link = %+v, want %s from the second batch", last, links[len(links)-1].URL)
+	}
+}
+
+func TestExportResultCSV(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "csvexporter")
+	result, links := seedExportResult(t, db, user.ID)
+
+	w := doJSON(exportRouter(db, user.ID), http.MethodGet, fmt.Sprintf("/results/%d/export?format=csv", result.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", contentType)
+	}
+
+	reader := csv.NewReader(w.Body)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("parsing the CSV: %v", err)
+	}
+
+	// Summary header and row, then the link header and one row per link
+	if len(rows) != 3+len(links) {
+		t.Fatalf("got %d rows, want %d", len(rows), 3+len(links))
+	}
+	if rows[0][0] != "result_id" || rows[1][0] != fmt.Sprint(result.ID) || rows[1][2] != "Example" {
+		t.Errorf("summary = %v / %v, want result %d", rows[0], rows[1], result.ID)
+	}
+	if strings.Join(rows[2], ",") != "link_url,type,anchor_text,status_code,is_broken" {
+		t.Errorf("link header = %v", rows[2])
+	}
+	if got := strings.Join(rows[3], ","); got != links[0].URL+",internal,Page 0,404,true" {
+		t.Errorf("first link row = %q", got)
+	}
+}
+
+func TestExportResultErrors(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+	result := createResult(t, db, createURL(t, db, owner.ID, "https://example.com").ID, models.CrawlResult{})
+
+	if w := doJSON(exportRouter(db, owner.ID), http.MethodGet, fmt.Sprintf("/results/%d/export?format=xml", result.ID), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status = %d, want 400", w.Code)
+	}
+	if w := doJSON(exportRouter(db, other.ID), http.MethodGet, fmt.Sprintf("/results/%d/export", result.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("another user's result: status = %d, want 404", w.Code)
+	}
+}
+
+func TestExportAll(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "exporter")
+	other := createUser(t, db, "bystander")
+
+	mine := createURL(t, db, user.ID, "https://mine.example")
+	myResult := createResult(t, db, mine.ID, models.CrawlResult{Title: "Mine"})
+	createLinks(t, db, myResult.ID, "https://mine.example/a", "https://mine.example/b")
+
+	trashed := createURL(t, db, user.ID, "https://trashed.example")
+	createLinks(t, db, createResult(t, db, trashed.ID, models.CrawlResult{}).ID, "https://trashed.example/a")
+	if err := db.Delete(&trashed).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	theirs := createURL(t, db, other.ID, "https://theirs.example")
+	createLinks(t, db, createResult(t, db, theirs.ID, models.CrawlResult{Title: "Theirs"}).ID, "https://theirs.example/a")
+
+	router := testRouter(user.ID)
+	router.GET("/export/all", NewURLHandler(db).ExportAll)
+	w := doJSON(router, http.MethodGet, "/export/all", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="skyell-export-`) || !strings.HasSuffix(cd, `.ndjson"`) {
+		t.Errorf("Content-Disposition = %q, want an .ndjson attachment", cd)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(w.Body.String()), "\n") {
+		var record struct {
+			Type string `json:"type"`
+			Data struct {
+				ID  uint   `json:"id"`
+				URL string `json:"url"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("line %q isn't JSON: %v", line, err)
+		}
+		got = append(got, fmt.Sprintf("%s %d %s", record.Type, record.Data.ID, record.Data.URL))
+	}
+
+	var links []models.Link
+	db.Where("crawl_result_id = ?", myResult.ID).Order("id").Find(&links)
+	want := []string{
+		fmt.Sprintf("url %d %s", mine.ID, mine.URL),
+		fmt.Sprintf("crawl_result %d ", myResult.ID),
+		fmt.Sprintf("link %d %s", links[0].ID, links[0].URL),
+		fmt.Sprintf("link %d %s", links[1].ID, links[1].URL),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("export =\n%s\nwant only the user's live records:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
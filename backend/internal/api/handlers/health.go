@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Readiness reports whether the database is reachable, with the ping latency. It answers
+// 503 when the connection is unavailable or the ping fails or takes longer than 2s.
+func Readiness(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "unavailable",
+				"message": "Database connection unavailable",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		err = sqlDB.PingContext(ctx)
+		latency := time.Since(start)
+
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":        "unavailable",
+				"message":       "Database ping failed",
+				"error":         err.Error(),
+				"db_latency_ms": latency.Milliseconds(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":        "ok",
+			"message":       "Skyell Backend API is ready",
+			"db_latency_ms": latency.Milliseconds(),
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"skyell-backend/internal/testutil"
+)
+
+func TestReadiness(t *testing.T) {
+	db := testutil.NewDB(t)
+	router := testRouter(0)
+	router.GET("/health/ready", Readiness(db))
+
+	w := doJSON(router, http.MethodGet, "/health/ready", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	decodeBody(t, w, &body)
+	if body.Status != "ok" {
+		t.Errorf("status field = %q, want ok", body.Status)
+	}
+}
+
+func TestReadinessWithClosedDatabase(t *testing.T) {
+	db := testutil.NewDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	router := testRouter(0)
+	router.GET("/health/ready", Readiness(db))
+
+	w := doJSON(router, http.MethodGet, "/health/ready", nil)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	decodeBody(t, w, &body)
+	if body.Status != "unavailable" {
+		t.Errorf("status field = %q, want unavailable", body.Status)
+	}
+}
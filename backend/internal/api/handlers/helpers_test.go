@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testRouter returns a router whose requests are authenticated as userID, or anonymous when it's 0
+func testRouter(userID uint) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+		c.Next()
+	})
+	return router
+}
+
+// doJSON sends a request with body encoded as JSON (none when body is nil) and records the response
+func doJSON(handler http.Handler, method, target string, body any) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// decodeBody decodes a JSON response into v, failing the test if it isn't JSON
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+}
+
+// createUser saves a user with a unique username and email
+func createUser(t *testing.T, db *gorm.DB, name string) models.User {
+	t.Helper()
+	user := models.User{Username: name, Email: fmt.Sprintf("%s@example.com", name), Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	return user
+}
+
+// createURL saves a URL owned by userID
+func createURL(t *testing.T, db *gorm.DB, userID uint, address string) models.URL {
+	t.Helper()
+	urlEntry := models.URL{URL: address, UserID: userID, Status: models.StatusQueued}
+	if err := db.Create(&urlEntry).Error; err != nil {
+		t.Fatalf("creating URL %s: %v", address, err)
+	}
+	return urlEntry
+}
+
+// listURLIDs calls GET /urls with the given query string and returns the IDs listed
+func listURLIDs(t *testing.T, router http.Handler, query string) []uint {
+	t.Helper()
+	w := doJSON(router, http.MethodGet, "/urls?"+query, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /urls?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+	}
+
+	var body struct {
+		Data struct {
+			Data []struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &body)
+
+	ids := make([]uint, 0, len(body.Data.Data))
+	for _, u := range body.Data.Data {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+// createResult saves result as a crawl result of urlID
+func createResult(t *testing.T, db *gorm.DB, urlID uint, result models.CrawlResult) models.CrawlResult {
+	t.Helper()
+	result.URLID = urlID
+	if err := db.Create(&result).Error; err != nil {
+		t.Fatalf("creating crawl result of URL %d: %v", urlID, err)
+	}
+	return result
+}
+
+// listResults calls GET /results with the given query string and returns the result IDs
+// listed and the next cursor, if any
+func listResults(t *testing.T, router http.Handler, query string) ([]uint, string) {
+	t.Helper()
+	w := doJSON(router, http.MethodGet, "/results?"+query, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /results?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+	}
+
+	var body struct {
+		Data struct {
+			Data []struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+			NextCursor string `json:"next_cursor"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &body)
+
+	ids := make([]uint, 0, len(body.Data.Data))
+	for _, r := range body.Data.Data {
+		ids = append(ids, r.ID)
+	}
+	return ids, body.Data.NextCursor
+}
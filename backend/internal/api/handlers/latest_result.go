@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LatestResultSummary is the most recent crawl result of a URL, as included in the URL list
+type LatestResultSummary struct {
+	ID            uint      `json:"id"`
+	URLID         uint      `json:"-"`
+	Title         string    `json:"title"`
+	InternalLinks int       `json:"internal_links"`
+	ExternalLinks int       `json:"external_links"`
+	BrokenLinks   int       `json:"broken_links"`
+	CrawledAt     time.Time `json:"crawled_at" gorm:"column:created_at"`
+}
+
+// latestResults loads the most recent crawl result of each of the given URLs in a single
+// query on db, keyed by URL ID. URLs that were never crawled are absent from the map.
+func (h *URLHandler) latestResults(db *gorm.DB, urlIDs []uint) (map[uint]*LatestResultSummary, error) {
+	latest := make(map[uint]*LatestResultSummary, len(urlIDs))
+	if len(urlIDs) == 0 {
+		return latest, nil
+	}
+
+	// IDs increase with creation time, so the highest ID per URL is its latest result.
+	// Pages reached through crawl depth are children of that result, not results of their own.
+	newest := db.Table("crawl_results").
+		Select("url_id, MAX(id) AS id").
+		Where("url_id IN ? AND parent_id IS NULL AND deleted_at IS NULL", urlIDs).
+		Group("url_id")
+
+	var summaries []LatestResultSummary
+	if err := db.Table("crawl_results").
+		Joins("JOIN (?) AS newest ON newest.id = crawl_results.id", newest).
+		Select("crawl_results.id, crawl_results.url_id, crawl_results.title, crawl_results.internal_links, " +
+			"crawl_results.external_links, crawl_results.broken_links, crawl_results.created_at").
+		Scan(&summaries).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range summaries {
+		summaries[i].CrawledAt = summaries[i].CrawledAt.UTC()
+		latest[summaries[i].URLID] = &summaries[i]
+	}
+	return latest, nil
+}
+
+// lastCrawledAt loads when each of the given URLs was last crawled in a single query on db,
+// keyed by URL ID. URLs that were never crawled are absent from the map.
+func lastCrawledAt(db *gorm.DB, urlIDs []uint) (map[uint]*time.Time, error) {
+	lastCrawled := make(map[uint]*time.Time, len(urlIDs))
+	if len(urlIDs) == 0 {
+		return lastCrawled, nil
+	}
+
+	// The time is read from the row holding the newest one rather than from MAX() itself,
+	// which some drivers (SQLite) return as text that can't be scanned into a time
+	newest := db.Table("crawl_results").
+		Select("url_id, MAX(created_at) AS created_at").
+		Where("url_id IN ? AND parent_id IS NULL AND deleted_at IS NULL", urlIDs).
+		Group("url_id")
+
+	var rows []struct {
+		URLID     uint
+		CrawledAt time.Time
+	}
+	if err := db.Table("crawl_results").
+		Joins("JOIN (?) AS newest ON newest.url_id = crawl_results.url_id AND newest.created_at = crawl_results.created_at", newest).
+		Select("crawl_results.url_id, crawl_results.created_at AS crawled_at").
+		Where("crawl_results.parent_id IS NULL AND crawl_results.deleted_at IS NULL").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		lastCrawled[rows[i].URLID] = &rows[i].CrawledAt
+	}
+	return lastCrawled, nil
+}
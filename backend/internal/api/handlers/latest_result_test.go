@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestGetURLsIncludesLatestResult(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "latest")
+	crawled := createURL(t, db, user.ID, "https://crawled.example")
+	neverCrawled := createURL(t, db, user.ID, "https://never.example")
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	createResult(t, db, crawled.ID, models.CrawlResult{Title: "Old", InternalLinks: 1, CreatedAt: base})
+	latest := createResult(t, db, crawled.ID, models.CrawlResult{Title: "New", InternalLinks: 4, ExternalLinks: 2, BrokenLinks: 1, CreatedAt: base.Add(time.Hour)})
+	// Neither a page reached through crawl depth nor a deleted result is the latest one
+	createResult(t, db, crawled.ID, models.CrawlResult{Title: "Child", ParentID: &latest.ID, CreatedAt: base.Add(2 * time.Hour)})
+	deleted := createResult(t, db, crawled.ID, models.CrawlResult{Title: "Deleted", CreatedAt: base.Add(3 * time.Hour)})
+	if err := db.Delete(&deleted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	router := testRouter(user.ID)
+	router.GET("/urls", NewURLHandler(db).GetURLs)
+	list := func(query string) map[uint]URLResponse {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, "/urls?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /urls?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+		}
+		var body struct {
+			Data URLListResponse `json:"data"`
+		}
+		decodeBody(t, w, &body)
+
+		byID := make(map[uint]URLResponse, len(body.Data.Data))
+		for _, u := range body.Data.Data {
+			byID[u.ID] = u
+		}
+		return byID
+	}
+
+	urls := list("include=latest_result")
+	got := urls[crawled.ID].LatestResult
+	want := LatestResultSummary{ID: latest.ID, Title: "New", InternalLinks: 4, ExternalLinks: 2, BrokenLinks: 1, CrawledAt: base.Add(time.Hour)}
+	if got == nil {
+		t.Fatal("latest result missing")
+	}
+	if got.ID != want.ID || got.Title != want.Title || got.InternalLinks != want.InternalLinks ||
+		got.ExternalLinks != want.ExternalLinks || got.BrokenLinks != want.BrokenLinks || !got.CrawledAt.Equal(want.CrawledAt) {
+		t.Errorf("latest result = %+v, want %+v", *got, want)
+	}
+	if last := urls[crawled.ID].LastCrawled; last == nil || !last.Equal(base.Add(time.Hour)) {
+		t.Errorf("last crawled = %v, want %v", last, base.Add(time.Hour))
+	}
+	if never := urls[neverCrawled.ID]; never.LatestResult != nil || never.LastCrawled != nil {
+		t.Errorf("never crawled URL has latest result %+v, last crawled %v; want neither", never.LatestResult, never.LastCrawled)
+	}
+
+	// Without the param the list is unchanged
+	if got := list("")[crawled.ID].LatestResult; got != nil {
+		t.Errorf("latest result without include = %+v, want none", *got)
+	}
+}
+
+func TestGetURLLastCrawledAt(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "detail")
+	crawled := createURL(t, db, user.ID, "https://crawled.example")
+	neverCrawled := createURL(t, db, user.ID, "https://never.example")
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	latest := createResult(t, db, crawled.ID, models.CrawlResult{CreatedAt: base.Add(time.Hour)})
+	createResult(t, db, crawled.ID, models.CrawlResult{CreatedAt: base})
+	createResult(t, db, crawled.ID, models.CrawlResult{ParentID: &latest.ID, CreatedAt: base.Add(2 * time.Hour)})
+
+	router := testRouter(user.ID)
+	router.GET("/urls/:id", NewURLHandler(db).GetURL)
+	get := func(id uint) map[string]json.RawMessage {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d", id), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /urls/%d: status = %d, want 200: %s", id, w.Code, w.Body)
+		}
+		var body struct {
+			Data map[string]json.RawMessage `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		return body.Data
+	}
+
+	var last time.Time
+	if err := json.Unmarshal(get(crawled.ID)["last_crawled_at"], &last); err != nil || !last.Equal(latest.CreatedAt) {
+		t.Errorf("last crawled = %v (%v), want the latest result's %v", last, err, latest.CreatedAt)
+	}
+	if raw, ok := get(neverCrawled.ID)["last_crawled_at"]; !ok || string(raw) != "null" {
+		t.Errorf("last crawled of a URL never crawled = %s, want null", raw)
+	}
+}
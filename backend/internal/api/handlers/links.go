@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkWithPage is a link along with the URL of the page it was found on
+type LinkWithPage struct {
+	models.Link
+	PageURL string `json:"page_url"`
+}
+
+// GetAllLinks returns a paginated, filterable view of links across all of the user's results
+func (h *URLHandler) GetAllLinks(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	// Parse query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	linkType := c.Query("type") // "internal" or "external"
+	search := c.Query("search")
+
+	if page < 1 {
+		page = 1
+	}
+	limit = h.pagination.Limit(limit, h.pagination.DefaultLinksPageSize)
+	if !checkPageDepth(c, h.pagination, page, limit, false) {
+		return
+	}
+
+	offset := (page - 1) * limit
+
+	// Only links of the user's own, non-deleted results and URLs
+	query := h.db.Model(&models.Link{}).
+		Joins("JOIN crawl_results ON links.crawl_result_id = crawl_results.id").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("urls.user_id = ? AND urls.deleted_at IS NULL AND crawl_results.deleted_at IS NULL", userID)
+
+	// Apply filters
+	switch linkType {
+	case "":
+	case "internal":
+		query = query.Where("links.type = ?", models.LinkTypeInternal)
+	case "external":
+		query = query.Where("links.type = ?", models.LinkTypeExternal)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid type: must be internal or external",
+		})
+		return
+	}
+
+	if isBrokenParam := c.Query("is_broken"); isBrokenParam != "" {
+		isBroken, err := strconv.ParseBool(isBrokenParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid is_broken: must be true or false",
+			})
+			return
+		}
+		query = query.Where("links.is_broken = ?", isBroken)
+	}
+
+	if search != "" {
+		query = query.Where("links.url LIKE ?", "%"+search+"%")
+	}
+
+	// Get total count
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondInternalError(c, "Failed to count links", err)
+		return
+	}
+
+	links := []LinkWithPage{}
+	if err := query.Select("links.*, urls.url AS page_url").
+		Order("links.id DESC").
+		Offset(offset).Limit(limit).
+		Scan(&links).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve links", err)
+		return
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+	setPaginationHeaders(c, pagination)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"links":      links,
+			"pagination": pagination,
+		},
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// linksRouter serves the links of a result as userID
+func linksRouter(db *gorm.DB, userID uint) http.Handler {
+	router := testRouter(userID)
+	router.GET("/results/:id/links", NewURLHandler(db).GetLinks)
+	return router
+}
+
+// listLinkURLs calls GET /results/:id/links with the given query string and returns the
+// addresses of the links listed
+func listLinkURLs(t *testing.T, router http.Handler, resultID uint, query string) []string {
+	t.Helper()
+	w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d/links?%s", resultID, query), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET links?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+	}
+
+	var body struct {
+		Data struct {
+			Links []models.Link `json:"links"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &body)
+
+	addresses := make([]string, 0, len(body.Data.Links))
+	for _, link := range body.Data.Links {
+		addresses = append(addresses, link.URL)
+	}
+	return addresses
+}
+
+func TestGetLinksRelFilter(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "rels")
+	result := createResult(t, db, createURL(t, db, user.ID, "https://example.com").ID, models.CrawlResult{})
+
+	for _, link := range []models.Link{
+		{URL: "https://example.com/plain"},
+		{URL: "https://ads.example/", Nofollow: true, Sponsored: true},
+		{URL: "https://forum.example/", UGC: true},
+		{URL: "https://old.example/", Nofollow: true},
+	} {
+		link.CrawlResultID, link.Type = result.ID, models.LinkTypeExternal
+		if err := db.Create(&link).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	router := linksRouter(db, user.ID)
+
+	tests := []struct {
+		rel  string
+		want []string
+	}{
+		{"nofollow", []string{"https://ads.example/", "https://old.example/"}},
+		{"sponsored", []string{"https://ads.example/"}},
+		{"ugc", []string{"https://forum.example/"}},
+		{"follow", []string{"https://example.com/plain"}},
+	}
+	for _, tt := range tests {
+		if got := listLinkURLs(t, router, result.ID, "rel="+tt.rel); !slices.Equal(got, tt.want) {
+			t.Errorf("rel=%s: links = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+
+	if w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d/links?rel=noopener", result.ID), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown rel: status = %d, want 400", w.Code)
+	}
+}
+
+func TestGetAllLinksIsScopedToTheUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+
+	// createLink saves a link on a new result of a new URL of userID
+	createLink := func(userID uint, page string, link models.Link) models.Link {
+		t.Helper()
+		result := createResult(t, db, createURL(t, db, userID, page).ID, models.CrawlResult{})
+		link.CrawlResultID = result.ID
+		if err := db.Create(&link).Error; err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+	docs := createLink(owner.ID, "https://example.com", models.Link{URL: "https://docs.example.org/guide", Type: models.LinkTypeExternal})
+	broken := createLink(owner.ID, "https://example.com/blog", models.Link{URL: "https://example.com/gone", Type: models.LinkTypeInternal, IsBroken: true, StatusCode: 404})
+	about := createLink(owner.ID, "https://example.net", models.Link{URL: "https://example.net/about", Type: models.LinkTypeInternal})
+	createLink(other.ID, "https://example.com", models.Link{URL: "https://docs.example.org/private", Type: models.LinkTypeExternal})
+
+	// Links of a trashed URL aren't listed
+	trashed := createLink(owner.ID, "https://trashed.example", models.Link{URL: "https://docs.example.org/trashed", Type: models.LinkTypeExternal})
+	var trashedResult models.CrawlResult
+	if err := db.First(&trashedResult, trashed.CrawlResultID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&models.URL{}, trashedResult.URLID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	router := testRouter(owner.ID)
+	router.GET("/links", NewURLHandler(db).GetAllLinks)
+	list := func(query string) []LinkWithPage {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, "/links?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /links?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+		}
+		var body struct {
+			Data struct {
+				Links      []LinkWithPage     `json:"links"`
+				Pagination PaginationResponse `json:"pagination"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		if body.Data.Pagination.Total < int64(len(body.Data.Links)) {
+			t.Errorf("GET /links?%s: total %d is less than the %d links listed", query, body.Data.Pagination.Total, len(body.Data.Links))
+		}
+		return body.Data.Links
+	}
+	urls := func(links []LinkWithPage) []string {
+		addresses := make([]string, 0, len(links))
+		for _, link := range links {
+			addresses = append(addresses, link.URL)
+		}
+		return addresses
+	}
+
+	all := list("")
+	if got, want := urls(all), []string{about.URL, broken.URL, docs.URL}; !slices.Equal(got, want) {
+		t.Fatalf("links = %v, want only the owner's, newest first: %v", got, want)
+	}
+	if all[2].PageURL != "https://example.com" {
+		t.Errorf("page URL = %q, want the page the link was found on", all[2].PageURL)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"search=docs.example.org", []string{docs.URL}},
+		{"type=internal", []string{about.URL, broken.URL}},
+		{"type=external", []string{docs.URL}},
+		{"is_broken=true", []string{broken.URL}},
+		{"is_broken=false&type=internal", []string{about.URL}},
+		{"limit=1&page=2", []string{broken.URL}},
+	}
+	for _, tt := range tests {
+		if got := urls(list(tt.query)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: links = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"type=nofollow", "is_broken=maybe"} {
+		if w := doJSON(router, http.MethodGet, "/links?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// loginLockoutPolicy is read from LOGIN_MAX_ATTEMPTS, LOGIN_ATTEMPT_WINDOW and LOGIN_LOCKOUT_DURATION.
+// Setting LOGIN_MAX_ATTEMPTS to 0 disables lockout.
+type loginLockoutPolicy struct {
+	maxAttempts int
+	window      time.Duration
+	lockout     time.Duration
+}
+
+func currentLoginLockoutPolicy() loginLockoutPolicy {
+	return loginLockoutPolicy{
+		maxAttempts: config.GetEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		window:      config.GetEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		lockout:     config.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+	}
+}
+
+// normalizeLoginEmail keys attempts case-insensitively so "A@x.com" and "a@x.com" share a counter
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// loginLockedUntil returns when the lockout for an email ends, or nil if it isn't locked
+func (h *AuthHandler) loginLockedUntil(email string) (*time.Time, error) {
+	if currentLoginLockoutPolicy().maxAttempts <= 0 {
+		return nil, nil
+	}
+
+	var attempt models.LoginAttempt
+	err := h.db.Where("email = ?", normalizeLoginEmail(email)).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(time.Now()) {
+		return attempt.LockedUntil, nil
+	}
+	return nil, nil
+}
+
+// failedLoginRetries is how often recordFailedLogin retries when concurrent failures of a
+// new email race to create its counter
+const failedLoginRetries = 3
+
+// recordFailedLogin counts a failed attempt against an email and locks it once the limit
+// is reached within the window. Unknown emails are counted the same as known ones so the
+// lockout doesn't reveal which addresses have accounts.
+func (h *AuthHandler) recordFailedLogin(email string) error {
+	policy := currentLoginLockoutPolicy()
+	if policy.maxAttempts <= 0 {
+		return nil
+	}
+
+	email = normalizeLoginEmail(email)
+	for retry := 0; ; retry++ {
+		err := h.db.Transaction(func(tx *gorm.DB) error {
+			return countFailedLogin(tx, email, policy, time.Now())
+		})
+		// Another failure created the counter first; the retry finds and locks it
+		if errors.Is(err, gorm.ErrDuplicatedKey) && retry < failedLoginRetries {
+			continue
+		}
+		return err
+	}
+}
+
+// countFailedLogin adds a failure to the email's counter. The counter row is locked while
+// it's updated, so concurrent failures are each counted instead of overwriting each other.
+func countFailedLogin(tx *gorm.DB, email string, policy loginLockoutPolicy, now time.Time) error {
+	var attempt models.LoginAttempt
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("email = ?", email).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		attempt = models.LoginAttempt{Email: email, WindowStart: now}
+		err = tx.Create(&attempt).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	// Start a fresh window once the previous one (or lockout) has passed
+	if now.Sub(attempt.WindowStart) > policy.window {
+		attempt.Failures = 0
+		attempt.WindowStart = now
+		attempt.LockedUntil = nil
+	}
+
+	attempt.Failures++
+	if attempt.Failures >= policy.maxAttempts {
+		lockedUntil := now.Add(policy.lockout)
+		attempt.LockedUntil = &lockedUntil
+		attempt.Failures = 0
+		attempt.WindowStart = lockedUntil
+	}
+
+	return tx.Save(&attempt).Error
+}
+
+// resetFailedLogins clears the counter after a successful login
+func (h *AuthHandler) resetFailedLogins(email string) error {
+	return h.db.Where("email = ?", normalizeLoginEmail(email)).Delete(&models.LoginAttempt{}).Error
+}
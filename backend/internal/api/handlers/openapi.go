@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"skyell-backend/internal/crawler"
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiOperation describes one endpoint for the OpenAPI document. request and response are
+// zero values of the JSON body and of the response's "data" field; nil when there is none
+// or the handler responds with an ad-hoc object.
+type apiOperation struct {
+	method   string
+	path     string // Gin path under /api/v1, e.g. /urls/:id
+	tag      string
+	summary  string
+	public   bool
+	request  any
+	response any
+}
+
+// bulkDeleteRequest mirrors the body of BulkDeleteURLs
+type bulkDeleteRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// apiOperations lists the documented endpoints; keep it in sync with SetupRoutes
+var apiOperations = []apiOperation{
+	{http.MethodPost, "/auth/register", "auth", "Register a new account", true, RegisterRequest{}, AuthResponse{}},
+	{http.MethodPost, "/auth/login", "auth", "Log in with email and password", true, LoginRequest{}, AuthResponse{}},
+	{http.MethodPost, "/auth/refresh", "auth", "Exchange a refresh token for new tokens", true, RefreshTokenRequest{}, AuthResponse{}},
+	{http.MethodPost, "/auth/forgot-password", "auth", "Email a password reset link", true, ForgotPasswordRequest{}, nil},
+	{http.MethodPost, "/auth/reset-password", "auth", "Reset the password with a reset token", true, ResetPasswordRequest{}, nil},
+	{http.MethodPatch, "/auth/me", "auth", "Update the current user's profile", false, UpdateProfileRequest{}, AuthResponse{}},
+	{http.MethodPut, "/auth/webhook", "auth", "Set the crawl notification webhook", false, SetWebhookRequest{}, nil},
+	{http.MethodDelete, "/auth/webhook", "auth", "Remove the crawl notification webhook", false, nil, nil},
+	{http.MethodPost, "/auth/api-keys", "auth", "Create an API key", false, CreateAPIKeyRequest{}, nil},
+	{http.MethodGet, "/auth/api-keys", "auth", "List API keys", false, nil, []models.APIKey{}},
+	{http.MethodDelete, "/auth/api-keys/:id", "auth", "Revoke an API key", false, nil, nil},
+
+	{http.MethodGet, "/urls", "urls", "List URLs", false, nil, URLListResponse{}},
+	{http.MethodPost, "/urls", "urls", "Add a URL", false, CreateURLRequest{}, URLResponse{}},
+	{http.MethodDelete, "/urls", "urls", "Delete several URLs", false, bulkDeleteRequest{}, nil},
+	{http.MethodPost, "/urls/validate", "urls", "Check that a URL is reachable without saving it", false, ValidateURLRequest{}, nil},
+	{http.MethodGet, "/urls/trash", "urls", "List deleted URLs", false, nil, []URLResponse{}},
+	{http.MethodGet, "/urls/:id", "urls", "Get a URL with its crawl results", false, nil, URLResponse{}},
+	{http.MethodPut, "/urls/:id", "urls", "Update a URL", false, UpdateURLRequest{}, URLResponse{}},
+	{http.MethodDelete, "/urls/:id", "urls", "Delete a URL", false, nil, nil},
+	{http.MethodPost, "/urls/:id/restore", "urls", "Restore a deleted URL", false, nil, URLResponse{}},
+	{http.MethodGet, "/urls/:id/compare", "urls", "Compare two crawl results of a URL", false, nil, ResultComparison{}},
+	{http.MethodGet, "/urls/:id/events", "urls", "List the crawl events of a URL", false, nil, nil},
+	{http.MethodGet, "/urls/:id/trend", "urls", "Get the link counts of a URL's crawls over time", false, nil, []TrendPoint{}},
+	{http.MethodPost, "/urls/:id/tags", "urls", "Attach a tag to a URL", false, AttachTagRequest{}, nil},
+	{http.MethodDelete, "/urls/:id/tags/:tagId", "urls", "Detach a tag from a URL", false, nil, nil},
+
+	{http.MethodGet, "/tags", "tags", "List tags", false, nil, []models.Tag{}},
+	{http.MethodPost, "/tags", "tags", "Create a tag", false, CreateTagRequest{}, models.Tag{}},
+
+	{http.MethodPost, "/crawl/start/:id", "crawl", "Start crawling a URL", false, nil, nil},
+	{http.MethodPost, "/crawl/stop/:id", "crawl", "Stop crawling a URL", false, nil, nil},
+	{http.MethodPost, "/crawl/bulk-start", "crawl", "Start crawling several URLs", false, BulkCrawlRequest{}, []models.URL{}},
+	{http.MethodPost, "/crawl/bulk-stop", "crawl", "Stop crawling several URLs", false, BulkCrawlRequest{}, []models.URL{}},
+	{http.MethodPost, "/crawl/stop-all", "crawl", "Stop all running crawls", false, nil, nil},
+
+	{http.MethodGet, "/results", "results", "List crawl results", false, nil, CrawlResultsListResponse{}},
+	{http.MethodDelete, "/results/prune", "results", "Delete old crawl results", false, nil, nil},
+	{http.MethodGet, "/results/recent", "results", "List the results you recently viewed", false, nil, []RecentResultResponse{}},
+	{http.MethodGet, "/results/stream", "results", "Stream crawl status changes as server-sent events", false, nil, nil},
+	{http.MethodGet, "/results/:id", "results", "Get a crawl result in detail", false, nil, CrawlResultResponse{}},
+	{http.MethodDelete, "/results/:id", "results", "Delete a crawl result", false, nil, nil},
+	{http.MethodGet, "/results/:id/links", "results", "List the links of a crawl result", false, nil, nil},
+	{http.MethodGet, "/results/:id/export", "results", "Export a crawl result as JSON or CSV", false, nil, nil},
+	{http.MethodGet, "/results/:id/snapshot", "results", "Get the HTML the crawler analyzed for a crawl result", false, nil, nil},
+	{http.MethodGet, "/links", "results", "Search links across all crawl results", false, nil, nil},
+	{http.MethodGet, "/export/all", "results", "Export all URLs, crawl results and links as NDJSON", false, nil, nil},
+	{http.MethodGet, "/stats", "results", "Get dashboard statistics", false, nil, DashboardStats{}},
+
+	{http.MethodGet, "/status/urls", "status", "Get the status of all URLs", false, nil, nil},
+	{http.MethodGet, "/status/url/:id", "status", "Get the status of a URL", false, nil, models.URL{}},
+	{http.MethodGet, "/status/crawl", "status", "Get URL statuses with a per-status summary", false, nil, nil},
+	{http.MethodPost, "/status/batch", "status", "Get the statuses of the given URLs", false, StatusBatchRequest{}, nil},
+
+	{http.MethodGet, "/admin/queue", "admin", "Get the crawl queue depth and worker states", false, nil, crawler.QueueStatus{}},
+	{http.MethodPost, "/admin/queue/pause", "admin", "Pause starting queued crawls", false, nil, crawler.QueueStatus{}},
+	{http.MethodPost, "/admin/queue/resume", "admin", "Resume starting queued crawls", false, nil, crawler.QueueStatus{}},
+}
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument map[string]any
+)
+
+// OpenAPI serves the OpenAPI 3 document of the API, built once from apiOperations and
+// the request and response types
+func OpenAPI(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIDocument = buildOpenAPIDocument(apiOperations)
+	})
+	c.JSON(http.StatusOK, openAPIDocument)
+}
+
+// pathParam matches Gin path parameters such as :id
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// buildOpenAPIDocument describes the operations, deriving schemas from their Go types
+func buildOpenAPIDocument(operations []apiOperation) map[string]any {
+	schemas := &schemaBuilder{components: map[string]any{}}
+	paths := map[string]map[string]any{}
+
+	for _, op := range operations {
+		path := pathParam.ReplaceAllString(op.path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+
+		data := map[string]any{}
+		if op.response != nil {
+			data = schemas.schema(reflect.TypeOf(op.response))
+		}
+		operation := map[string]any{
+			"tags":    []string{op.tag},
+			"summary": op.summary,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "Success",
+					"content": map[string]any{"application/json": map[string]any{"schema": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"success": map[string]any{"type": "boolean"},
+							"message": map[string]any{"type": "string"},
+							"data":    data,
+						},
+					}}},
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content": map[string]any{"application/json": map[string]any{
+						"schema": map[string]any{"$ref": "#/components/schemas/ErrorResponse"},
+					}},
+				},
+			},
+		}
+
+		var parameters []map[string]any
+		for _, match := range pathParam.FindAllStringSubmatch(op.path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "integer", "minimum": 0},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if op.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{"application/json": map[string]any{
+					"schema": schemas.schema(reflect.TypeOf(op.request)),
+				}},
+			}
+		}
+		if op.tag == "admin" {
+			operation["description"] = "Only for users whose IDs are listed in ADMIN_USER_IDS; anyone else gets 403."
+		}
+		if !op.public {
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}}
+		}
+
+		paths[path][strings.ToLower(op.method)] = operation
+	}
+
+	schemas.components["ErrorResponse"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"success": map[string]any{"type": "boolean"},
+			"message": map[string]any{"type": "string"},
+			"code":    map[string]any{"type": "string"},
+			"error":   map[string]any{"type": "string"},
+		},
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Skyell API",
+			"version": "1.0.0",
+		},
+		"servers": []map[string]any{{"url": "/api/v1"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// schemaBuilder derives JSON schemas from Go types, following the json and binding tags.
+// Named structs become shared components so recursive types terminate.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if t == timeType || t == deletedAtType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			b.components[t.Name()] = map[string]any{} // Placeholder while the fields are described
+			b.components[t.Name()] = b.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// object describes a struct's JSON fields, inlining embedded structs like encoding/json does
+func (b *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	b.addFields(t, properties, &required)
+
+	object := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getUserID returns the ID of the authenticated user, responding 401 when there is none
+func getUserID(c *gin.Context) (uint, bool) {
+	if value, exists := c.Get("user_id"); exists {
+		if userID, ok := value.(uint); ok {
+			return userID, true
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": "User not authenticated",
+	})
+	return 0, false
+}
+
+// findOwned loads the record of type T with the given ID if it belongs to the user; T's
+// table must have a user_id column. Another user's record is reported as not found.
+func findOwned[T any](db *gorm.DB, id, userID uint) (T, error) {
+	var record T
+	err := db.Where("id = ? AND user_id = ?", id, userID).First(&record).Error
+	return record, err
+}
+
+// findOwnedResult loads a crawl result if it belongs to one of the user's URLs
+func findOwnedResult(db *gorm.DB, id, userID uint) (models.CrawlResult, error) {
+	var result models.CrawlResult
+	err := db.Table("crawl_results").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("crawl_results.id = ? AND urls.user_id = ? AND crawl_results.deleted_at IS NULL", id, userID).
+		Select("crawl_results.*").
+		First(&result).Error
+	return result, err
+}
+
+// respondFindError responds 404 with notFound when a lookup found nothing, and otherwise
+// like respondQueryError with failed
+func respondFindError(c *gin.Context, err error, notFound, failed string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": notFound,
+		})
+		return
+	}
+	respondQueryError(c, failed, err)
+}
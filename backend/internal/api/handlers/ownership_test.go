@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlersRequireAUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	urls := NewURLHandler(db)
+	crawls := NewCrawlHandler(db)
+	crawls.crawlerService.PauseQueue()
+	tags := NewTagHandler(db)
+	auth := NewAuthHandler(db)
+
+	// Valid-looking requests, so nothing but the missing user can be wrong with them
+	routes := []struct {
+		method, path, target string
+		handler              gin.HandlerFunc
+		body                 any
+	}{
+		{http.MethodGet, "/urls", "/urls", urls.GetURLs, nil},
+		{http.MethodPost, "/urls", "/urls", urls.CreateURL, map[string]string{"url": "https://example.com"}},
+		{http.MethodDelete, "/urls", "/urls", urls.BulkDeleteURLs, map[string][]uint{"ids": {1}}},
+		{http.MethodPost, "/urls/validate", "/urls/validate", crawls.ValidateURL, map[string]string{"url": "https://example.com"}},
+		{http.MethodGet, "/urls/trash", "/urls/trash", urls.GetTrashedURLs, nil},
+		{http.MethodGet, "/urls/:id", "/urls/1", urls.GetURL, nil},
+		{http.MethodPut, "/urls/:id", "/urls/1", urls.UpdateURL, map[string]string{"url": "https://example.com"}},
+		{http.MethodDelete, "/urls/:id", "/urls/1", urls.DeleteURL, nil},
+		{http.MethodPost, "/urls/:id/restore", "/urls/1/restore", urls.RestoreURL, nil},
+		{http.MethodGet, "/urls/:id/compare", "/urls/1/compare?from=1&to=2", urls.CompareResults, nil},
+		{http.MethodGet, "/urls/:id/events", "/urls/1/events", urls.GetURLEvents, nil},
+		{http.MethodGet, "/urls/:id/trend", "/urls/1/trend", urls.GetURLTrend, nil},
+		{http.MethodPost, "/urls/:id/tags", "/urls/1/tags", tags.AttachTag, map[string]uint{"tag_id": 1}},
+		{http.MethodDelete, "/urls/:id/tags/:tagId", "/urls/1/tags/1", tags.DetachTag, nil},
+		{http.MethodGet, "/tags", "/tags", tags.GetTags, nil},
+		{http.MethodPost, "/tags", "/tags", tags.CreateTag, map[string]string{"name": "news"}},
+		{http.MethodPost, "/crawl/start/:id", "/crawl/start/1", crawls.StartCrawl, nil},
+		{http.MethodPost, "/crawl/stop/:id", "/crawl/stop/1", crawls.StopCrawl, nil},
+		{http.MethodPost, "/crawl/bulk-start", "/crawl/bulk-start", crawls.BulkStartCrawl, map[string][]uint{"ids": {1}}},
+		{http.MethodPost, "/crawl/bulk-stop", "/crawl/bulk-stop", crawls.BulkStopCrawl, map[string][]uint{"ids": {1}}},
+		{http.MethodPost, "/crawl/stop-all", "/crawl/stop-all", crawls.StopAllCrawls, nil},
+		{http.MethodGet, "/results", "/results", urls.GetResults, nil},
+		{http.MethodGet, "/results/stream", "/results/stream", crawls.StreamStatus, nil},
+		{http.MethodGet, "/results/recent", "/results/recent", urls.GetRecentResults, nil},
+		{http.MethodDelete, "/results/prune", "/results/prune?older_than=30d", urls.PruneResults, nil},
+		{http.MethodGet, "/results/:id", "/results/1", urls.GetResultDetail, nil},
+		{http.MethodDelete, "/results/:id", "/results/1", urls.DeleteResult, nil},
+		{http.MethodGet, "/results/:id/links", "/results/1/links", urls.GetLinks, nil},
+		{http.MethodGet, "/results/:id/export", "/results/1/export", urls.ExportResult, nil},
+		{http.MethodGet, "/results/:id/snapshot", "/results/1/snapshot", urls.GetSnapshot, nil},
+		{http.MethodGet, "/links", "/links", urls.GetAllLinks, nil},
+		{http.MethodGet, "/export/all", "/export/all", urls.ExportAll, nil},
+		{http.MethodGet, "/stats", "/stats", urls.GetStats, nil},
+		{http.MethodGet, "/status/urls", "/status/urls", urls.GetURLsStatus, nil},
+		{http.MethodGet, "/status/url/:id", "/status/url/1", urls.GetURLStatus, nil},
+		{http.MethodGet, "/status/crawl", "/status/crawl", crawls.GetCrawlStatus, nil},
+		{http.MethodPost, "/status/batch", "/status/batch", crawls.BatchCrawlStatus, map[string][]uint{"ids": {1}}},
+		{http.MethodPatch, "/auth/me", "/auth/me", auth.UpdateProfile, map[string]string{"username": "someone"}},
+		{http.MethodPut, "/auth/webhook", "/auth/webhook", auth.SetWebhook, map[string]string{"url": "https://example.com/hook"}},
+		{http.MethodDelete, "/auth/webhook", "/auth/webhook", auth.DeleteWebhook, nil},
+		{http.MethodPost, "/auth/api-keys", "/auth/api-keys", auth.CreateAPIKey, map[string]string{"name": "ci"}},
+		{http.MethodGet, "/auth/api-keys", "/auth/api-keys", auth.ListAPIKeys, nil},
+		{http.MethodDelete, "/auth/api-keys/:id", "/auth/api-keys/1", auth.RevokeAPIKey, nil},
+	}
+
+	router := testRouter(0)
+	for _, route := range routes {
+		router.Handle(route.method, route.path, route.handler)
+	}
+	for _, route := range routes {
+		w := doJSON(router, route.method, route.target, route.body)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "User not authenticated") {
+			t.Errorf("%s %s without a user: status = %d, body %s; want 401", route.method, route.target, w.Code, w.Body)
+		}
+	}
+}
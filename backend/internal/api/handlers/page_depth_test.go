@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestDeepPagesAreRejected(t *testing.T) {
+	t.Setenv("MAX_OFFSET", "100")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "digger")
+	urlEntry := createURL(t, db, user.ID, "https://deep.example")
+	result := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Deep"})
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/urls/:id/events", handler.GetURLEvents)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id/links", handler.GetLinks)
+	router.GET("/links", handler.GetAllLinks)
+
+	for _, list := range []string{
+		"/urls",
+		fmt.Sprintf("/urls/%d/events", urlEntry.ID),
+		"/results",
+		fmt.Sprintf("/results/%d/links", result.ID),
+		"/links",
+	} {
+		// Page 11 of 10 starts 100 rows in, page 12 past MAX_OFFSET
+		if w := doJSON(router, http.MethodGet, list+"?limit=10&page=11", nil); w.Code != http.StatusOK {
+			t.Errorf("GET %s page 11: status = %d, want 200: %s", list, w.Code, w.Body)
+		}
+		w := doJSON(router, http.MethodGet, list+"?limit=10&page=12", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s page 12: status = %d, want 400", list, w.Code)
+			continue
+		}
+		if wantCursor := list == "/results"; strings.Contains(w.Body.String(), "cursor") != wantCursor {
+			t.Errorf("GET %s page 12: message %s, suggesting cursor pagination: %v", list, w.Body, wantCursor)
+		}
+	}
+
+	// Cursor pagination is never too deep
+	if w := doJSON(router, http.MethodGet, "/results?limit=10&page=12&cursor=", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /results with a cursor: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkPageDepth rejects pages starting more than MAX_OFFSET rows in with 400, as the
+// database would have to scan and skip all of those rows. hasCursor is set for lists that
+// also support cursor pagination, which the error then points to.
+func checkPageDepth(c *gin.Context, p config.PaginationConfig, page, limit int, hasCursor bool) bool {
+	if !p.TooDeep(page, limit) {
+		return true
+	}
+
+	alternative := "narrow the list with filters instead"
+	if hasCursor {
+		alternative = "use cursor pagination (the cursor parameter) instead"
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"message": fmt.Sprintf("Page too deep: pages may start at most %d rows in; %s", p.MaxOffset, alternative),
+	})
+	return false
+}
+
+// setPaginationHeaders mirrors the JSON pagination object as response headers
+// and adds RFC 5988 Link headers pointing at the neighbouring pages
+func setPaginationHeaders(c *gin.Context, p PaginationResponse) {
+	c.Header("X-Total-Count", strconv.FormatInt(p.Total, 10))
+	c.Header("X-Page", strconv.Itoa(p.Page))
+	c.Header("X-Per-Page", strconv.Itoa(p.Limit))
+	c.Header("X-Total-Pages", strconv.Itoa(p.TotalPages))
+
+	var links []string
+	if p.Page < p.TotalPages {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(c, p.Page+1)))
+	}
+	if p.Page > 1 && p.Page <= p.TotalPages {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(c, p.Page-1)))
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageURL returns the current request URL with the page query parameter replaced
+func pageURL(c *gin.Context, page int) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// PaginationLinks are absolute URLs for navigating a paginated list. Next and prev are
+// omitted on the last and first pages.
+type PaginationLinks struct {
+	Self  string `json:"self"`
+	First string `json:"first"`
+	Last  string `json:"last"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// paginationLinks builds the _links object for a page, keeping the request's other query params
+func paginationLinks(c *gin.Context, p PaginationResponse) *PaginationLinks {
+	lastPage := p.TotalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := &PaginationLinks{
+		Self:  absolutePageURL(c, p.Page),
+		First: absolutePageURL(c, 1),
+		Last:  absolutePageURL(c, lastPage),
+	}
+	if p.Page < p.TotalPages {
+		links.Next = absolutePageURL(c, p.Page+1)
+	}
+	if p.Page > 1 && p.Page <= p.TotalPages {
+		links.Prev = absolutePageURL(c, p.Page-1)
+	}
+	return links
+}
+
+// absolutePageURL is pageURL prefixed with the scheme and host the client used.
+// A Host that isn't a plain host[:port] gets the relative pageURL instead.
+func absolutePageURL(c *gin.Context, page int) string {
+	host := c.Request.Host
+	if u, err := url.Parse("//" + host); err != nil || host == "" || u.Host != host || u.User != nil {
+		return pageURL(c, page)
+	}
+	return requestScheme(c) + "://" + host + pageURL(c, page)
+}
+
+// requestScheme is the scheme the client used. X-Forwarded-Proto is only honoured from a
+// proxy in TRUSTED_PROXIES, and only as http or https; a proxy that appends to the header
+// instead of replacing it is taken at its first, client-facing value.
+func requestScheme(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if !config.TrustedProxy(c.RemoteIP()) {
+		return scheme
+	}
+
+	proto, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Proto"), ",")
+	switch proto = strings.ToLower(strings.TrimSpace(proto)); proto {
+	case "http", "https":
+		return proto
+	}
+	return scheme
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestPaginationHeadersMatchBody(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "pager")
+
+	// Five of everything: URLs, their results, and links of the first result
+	var first models.CrawlResult
+	for i := 0; i < 5; i++ {
+		result := createResult(t, db, createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i)).ID, models.CrawlResult{})
+		if i == 0 {
+			first = result
+		}
+	}
+	createLinks(t, db, first.ID, "https://example.com/a", "https://example.com/b", "https://example.com/c",
+		"https://example.com/d", "https://example.com/e")
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id/links", handler.GetLinks)
+	router.GET("/urls", handler.GetURLs)
+
+	for _, path := range []string{"/urls", "/results", fmt.Sprintf("/results/%d/links", first.ID)} {
+		for page, wantLinks := range map[int][]string{
+			1: {`rel="next"`},
+			2: {`rel="next"`, `rel="prev"`},
+			3: {`rel="prev"`},
+		} {
+			target := fmt.Sprintf("%s?limit=2&page=%d", path, page)
+			w := doJSON(router, http.MethodGet, target, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s: status = %d, want 200: %s", target, w.Code, w.Body)
+			}
+
+			var body struct {
+				Data struct {
+					Pagination PaginationResponse `json:"pagination"`
+				} `json:"data"`
+			}
+			decodeBody(t, w, &body)
+			p := body.Data.Pagination
+			if p != (PaginationResponse{Page: page, Limit: 2, Total: 5, TotalPages: 3}) {
+				t.Errorf("GET %s: pagination = %+v, want page %d of 3 with 5 rows", target, p, page)
+			}
+
+			headers := map[string]string{
+				"X-Total-Count": strconv.FormatInt(p.Total, 10),
+				"X-Page":        strconv.Itoa(p.Page),
+				"X-Per-Page":    strconv.Itoa(p.Limit),
+				"X-Total-Pages": strconv.Itoa(p.TotalPages),
+			}
+			for header, want := range headers {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("GET %s: %s = %q, want %q", target, header, got, want)
+				}
+			}
+
+			link := w.Header().Get("Link")
+			if got := strings.Count(link, "rel="); got != len(wantLinks) {
+				t.Errorf("GET %s: Link = %q, want %v", target, link, wantLinks)
+			}
+			for _, rel := range wantLinks {
+				if !strings.Contains(link, rel) {
+					t.Errorf("GET %s: Link = %q, want %s", target, link, rel)
+				}
+			}
+			if page < 3 && !strings.Contains(link, fmt.Sprintf("page=%d", page+1)) {
+				t.Errorf("GET %s: Link = %q, want the next page", target, link)
+			}
+		}
+	}
+}
+
+func TestPaginationLinksKeepFilters(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "navigator")
+	for i := 0; i < 3; i++ {
+		createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i))
+	}
+	done := createURL(t, db, user.ID, "https://example.com/done")
+	if err := db.Model(&done).Update("status", models.StatusCompleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	router := testRouter(user.ID)
+	router.GET("/urls", NewURLHandler(db).GetURLs)
+
+	// links fetches a page of queued example.com URLs and returns its _links
+	links := func(page int, headers map[string]string) PaginationLinks {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/urls?status=queued&search=example.com&limit=1&page=%d", page), nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d: status = %d, want 200: %s", page, w.Code, w.Body)
+		}
+		var body struct {
+			Data URLListResponse `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		if body.Data.Links == nil {
+			t.Fatalf("page %d: _links missing", page)
+		}
+		return *body.Data.Links
+	}
+	// checkLink asserts link is an absolute URL of the wanted page with the filters kept
+	checkLink := func(name, link, scheme string, page int) {
+		t.Helper()
+		u, err := url.Parse(link)
+		if err != nil || u.Scheme != scheme || u.Host != "example.com" || u.Path != "/urls" {
+			t.Errorf("%s = %q, want an absolute %s URL of /urls", name, link, scheme)
+			return
+		}
+		query := u.Query()
+		if query.Get("status") != "queued" || query.Get("search") != "example.com" || query.Get("limit") != "1" {
+			t.Errorf("%s = %q, want the status, search and limit params kept", name, link)
+		}
+		if got := query.Get("page"); got != strconv.Itoa(page) {
+			t.Errorf("%s points at page %s, want %d", name, got, page)
+		}
+	}
+
+	middle := links(2, nil)
+	checkLink("self", middle.Self, "http", 2)
+	checkLink("first", middle.First, "http", 1)
+	checkLink("last", middle.Last, "http", 3)
+	checkLink("next", middle.Next, "http", 3)
+	checkLink("prev", middle.Prev, "http", 1)
+
+	if first := links(1, nil); first.Prev != "" || first.Next == "" {
+		t.Errorf("first page links = %+v, want next but no prev", first)
+	}
+	if last := links(3, nil); last.Next != "" || last.Prev == "" {
+		t.Errorf("last page links = %+v, want prev but no next", last)
+	}
+
+	// X-Forwarded-Proto is honoured from trusted proxies only (httptest requests come
+	// from 192.0.2.1), and only as http or https
+	proxied := map[string]string{"X-Forwarded-Proto": "https"}
+	checkLink("self from an untrusted client", links(1, proxied).Self, "http", 1)
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.0/24")
+	checkLink("self behind a proxy", links(1, proxied).Self, "https", 1)
+	checkLink("self behind chained proxies", links(1, map[string]string{"X-Forwarded-Proto": "HTTPS, http"}).Self, "https", 1)
+	for _, proto := range []string{"javascript", "ftp", ""} {
+		checkLink("self with X-Forwarded-Proto "+proto, links(1, map[string]string{"X-Forwarded-Proto": proto}).Self, "http", 1)
+	}
+}
+
+func TestPaginationLinksIgnoreInvalidHost(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "host")
+	for i := 0; i < 2; i++ {
+		createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i))
+	}
+	router := testRouter(user.ID)
+	router.GET("/urls", NewURLHandler(db).GetURLs)
+
+	for _, host := range []string{"evil.example/phish?", "user@evil.example"} {
+		req := httptest.NewRequest(http.MethodGet, "/urls?limit=1", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body struct {
+			Data URLListResponse `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		if body.Data.Links == nil || !strings.HasPrefix(body.Data.Links.Next, "/urls?") {
+			t.Errorf("Host %q: _links = %+v, want relative links", host, body.Data.Links)
+		}
+	}
+}
+
+func TestPageSizeIsConfigurable(t *testing.T) {
+	t.Setenv("DEFAULT_PAGE_SIZE", "2")
+	t.Setenv("DEFAULT_LINKS_PAGE_SIZE", "3")
+	t.Setenv("MAX_PAGE_SIZE", "4")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "sizer")
+
+	var result models.CrawlResult
+	for i := 0; i < 6; i++ {
+		result = createResult(t, db, createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i)).ID, models.CrawlResult{})
+	}
+	createLinks(t, db, result.ID, "https://example.com/a", "https://example.com/b", "https://example.com/c",
+		"https://example.com/d", "https://example.com/e", "https://example.com/f")
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id/links", handler.GetLinks)
+
+	linksPath := fmt.Sprintf("/results/%d/links", result.ID)
+	tests := []struct {
+		target    string
+		wantLimit int
+	}{
+		{"/urls", 2},
+		{"/urls?limit=50", 4},
+		{"/results?page=1", 2},
+		{"/results?page=1&limit=50", 4},
+		{linksPath, 3},
+		{linksPath + "?limit=50", 4},
+		{linksPath + "?limit=1", 1},
+	}
+	for _, tt := range tests {
+		w := doJSON(router, http.MethodGet, tt.target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", tt.target, w.Code, w.Body)
+		}
+		if got := w.Header().Get("X-Per-Page"); got != strconv.Itoa(tt.wantLimit) {
+			t.Errorf("GET %s: page size = %s, want %d", tt.target, got, tt.wantLimit)
+		}
+	}
+}
+
+func TestPagesAreStableWhenSortValuesTie(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "tied")
+
+	// Seven URLs, results and links that all share a creation time, so only the ID
+	// tiebreaker keeps their order stable from page to page
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var urlIDs, resultIDs []uint
+	var first models.CrawlResult
+	for i := 0; i < 7; i++ {
+		urlEntry := createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i))
+		urlIDs = append(urlIDs, urlEntry.ID)
+		result := createResult(t, db, urlEntry.ID, models.CrawlResult{CreatedAt: created})
+		resultIDs = append(resultIDs, result.ID)
+		if i == 0 {
+			first = result
+		}
+	}
+	if err := db.Model(&models.URL{}).Where("user_id = ?", user.ID).Update("created_at", created).Error; err != nil {
+		t.Fatalf("backdating URLs: %v", err)
+	}
+	var linkIDs []uint
+	for i := 0; i < 7; i++ {
+		link := models.Link{CrawlResultID: first.ID, URL: fmt.Sprintf("https://example.com/link/%d", i), Type: models.LinkTypeInternal}
+		if err := db.Create(&link).Error; err != nil {
+			t.Fatalf("creating link: %v", err)
+		}
+		linkIDs = append(linkIDs, link.ID)
+	}
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id/links", handler.GetLinks)
+
+	lists := []struct {
+		path    string
+		field   string
+		queries []string
+		want    []uint
+	}{
+		{"/urls", "data", []string{"sort_by=created_at&sort_order=asc", "sort_by=created_at&sort_order=desc"}, urlIDs},
+		{"/results", "data", []string{"sort_by=crawled_at&sort_order=asc", "sort_by=crawled_at&sort_order=desc"}, resultIDs},
+		{fmt.Sprintf("/results/%d/links", first.ID), "links", []string{""}, linkIDs},
+	}
+	for _, list := range lists {
+		for _, query := range list.queries {
+			var got []uint
+			for page := 1; page <= 3; page++ {
+				target := fmt.Sprintf("%s?limit=3&page=%d&%s", list.path, page, query)
+				w := doJSON(router, http.MethodGet, target, nil)
+				if w.Code != http.StatusOK {
+					t.Fatalf("GET %s: status = %d, want 200: %s", target, w.Code, w.Body)
+				}
+
+				var body struct {
+					Data map[string]json.RawMessage `json:"data"`
+				}
+				decodeBody(t, w, &body)
+				var rows []struct {
+					ID uint `json:"id"`
+				}
+				if err := json.Unmarshal(body.Data[list.field], &rows); err != nil {
+					t.Fatalf("GET %s: decoding %s: %v", target, list.field, err)
+				}
+				for _, row := range rows {
+					got = append(got, row.ID)
+				}
+			}
+
+			// Tied rows come back by ID whichever way the sort column is ordered
+			if !slices.Equal(got, list.want) {
+				t.Errorf("paging %s?%s: got IDs %v, want each of %v once, in order", list.path, query, got, list.want)
+			}
+		}
+	}
+}
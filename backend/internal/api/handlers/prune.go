@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skyell-backend/internal/retention"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PruneResults deletes the user's crawl results older than older_than, always keeping
+// the keep_latest most recent results of each URL
+func (h *URLHandler) PruneResults(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	olderThan, err := retention.ParseDuration(c.Query("older_than"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid older_than: use a duration like 720h or 30d",
+		})
+		return
+	}
+
+	keepLatest, err := strconv.Atoi(c.DefaultQuery("keep_latest", "1"))
+	if err != nil || keepLatest < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid keep_latest: must be a non-negative integer",
+		})
+		return
+	}
+
+	removed, err := retention.PruneResults(h.db, &userID, retention.Policy{
+		OlderThan:  olderThan,
+		KeepLatest: keepLatest,
+	})
+	if err != nil {
+		respondInternalError(c, "Failed to prune results", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Results pruned successfully",
+		"data": gin.H{
+			"removed": removed,
+		},
+	})
+}
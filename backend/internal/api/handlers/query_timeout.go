@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// queryDB returns db bound to a context derived from the request, so queries are cancelled
+// when the client goes away or after DB_QUERY_TIMEOUT (default 10s, 0 disables the timeout).
+// The returned cancel function must be called once the handler is done with the database.
+func queryDB(c *gin.Context, db *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	timeout := config.GetEnvDuration("DB_QUERY_TIMEOUT", 10*time.Second)
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		return db.WithContext(ctx), cancel
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	return db.WithContext(ctx), cancel
+}
+
+// respondQueryError writes a 504 when a query ran past DB_QUERY_TIMEOUT, and a 500 otherwise
+func respondQueryError(c *gin.Context, message string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"success": false,
+			"message": "Database query timed out",
+		})
+		return
+	}
+	respondInternalError(c, message, err)
+}
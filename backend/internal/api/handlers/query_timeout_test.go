@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestListQueriesTimeOut(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "slow")
+	result := createResult(t, db, createURL(t, db, user.ID, "https://example.com").ID, models.CrawlResult{})
+
+	// While slow is set, queries stall until their context is done, as a locked table would
+	var slow atomic.Bool
+	if err := db.Callback().Query().Before("gorm:query").Register("test:slow_query", func(tx *gorm.DB) {
+		if !slow.Load() {
+			return
+		}
+		select {
+		case <-tx.Statement.Context.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id/links", handler.GetLinks)
+	targets := []string{"/urls", "/results", fmt.Sprintf("/results/%d/links", result.ID)}
+
+	t.Setenv("DB_QUERY_TIMEOUT", "50ms")
+	slow.Store(true)
+	for _, target := range targets {
+		start := time.Now()
+		w := doJSON(router, http.MethodGet, target, nil)
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("GET %s: status = %d, want 504: %s", target, w.Code, w.Body)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("GET %s took %v, want it cut off by the timeout", target, elapsed)
+		}
+	}
+
+	// Fast queries are unaffected
+	slow.Store(false)
+	for _, target := range targets {
+		if w := doJSON(router, http.MethodGet, target, nil); w.Code != http.StatusOK {
+			t.Errorf("GET %s without a slow query: status = %d, want 200: %s", target, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQueueStatus reports whether the crawl queue is paused, its depth and what each
+// worker is doing
+func (h *CrawlHandler) GetQueueStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.crawlerService.QueueStatus(),
+	})
+}
+
+// PauseQueue stops workers from starting queued crawls. Running crawls finish and queued
+// ones wait until the queue is resumed.
+func (h *CrawlHandler) PauseQueue(c *gin.Context) {
+	h.crawlerService.PauseQueue()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Crawl queue paused",
+		"data":    h.crawlerService.QueueStatus(),
+	})
+}
+
+// ResumeQueue lets workers start queued crawls again
+func (h *CrawlHandler) ResumeQueue(c *gin.Context) {
+	h.crawlerService.ResumeQueue()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Crawl queue resumed",
+		"data":    h.crawlerService.QueueStatus(),
+	})
+}
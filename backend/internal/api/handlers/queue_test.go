@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/crawler"
+	"skyell-backend/internal/testutil"
+)
+
+func TestQueueAdminEndpoints(t *testing.T) {
+	t.Setenv("CRAWLER_MAX_CONCURRENT", "2")
+	db := testutil.NewDB(t)
+	admin := createUser(t, db, "boss")
+	user := createUser(t, db, "worker")
+	t.Setenv("ADMIN_USER_IDS", strconv.FormatUint(uint64(admin.ID), 10))
+	// Admin access isn't granted by email, which users choose themselves
+	t.Setenv("ADMIN_EMAILS", user.Email)
+	crawls := NewCrawlHandler(db)
+
+	queue := func(userID uint, method, target string) (crawler.QueueStatus, int) {
+		t.Helper()
+		router := testRouter(userID)
+		group := router.Group("/admin", middleware.AdminOnly(db))
+		group.GET("/queue", crawls.GetQueueStatus)
+		group.POST("/queue/pause", crawls.PauseQueue)
+		group.POST("/queue/resume", crawls.ResumeQueue)
+
+		w := doJSON(router, method, target, nil)
+		var body struct {
+			Data crawler.QueueStatus `json:"data"`
+		}
+		if w.Code == http.StatusOK {
+			decodeBody(t, w, &body)
+		}
+		return body.Data, w.Code
+	}
+
+	for _, target := range []string{"/admin/queue", "/admin/queue/pause", "/admin/queue/resume"} {
+		method := http.MethodPost
+		if target == "/admin/queue" {
+			method = http.MethodGet
+		}
+		if _, code := queue(user.ID, method, target); code != http.StatusForbidden {
+			t.Errorf("%s %s as a regular user: status = %d, want 403", method, target, code)
+		}
+	}
+
+	if status, code := queue(admin.ID, http.MethodPost, "/admin/queue/pause"); code != http.StatusOK || !status.Paused {
+		t.Fatalf("pause: status %d, %+v, want 200 and paused", code, status)
+	}
+	// The URL isn't claimed, so once a worker takes the job it ends without crawling
+	crawls.crawlerService.Enqueue(createURL(t, db, user.ID, "https://waiting.example").ID, user.ID, crawler.DefaultPriority)
+	status, _ := queue(admin.ID, http.MethodGet, "/admin/queue")
+	if !status.Paused || status.Depth != 1 || len(status.Workers) != 2 {
+		t.Errorf("queue = %+v, want paused with 1 queued job and 2 workers", status)
+	}
+	for _, worker := range status.Workers {
+		if worker.State != "idle" {
+			t.Errorf("worker %d is %s while paused, want idle", worker.ID, worker.State)
+		}
+	}
+
+	if resumed, code := queue(admin.ID, http.MethodPost, "/admin/queue/resume"); code != http.StatusOK || resumed.Paused {
+		t.Fatalf("resume: status %d, %+v, want 200 and not paused", code, resumed)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for crawls.crawlerService.QueueStatus().Depth != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the queued job wasn't taken after resuming")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A token outliving the admin's account grants nothing
+	if err := db.Unscoped().Delete(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, code := queue(admin.ID, http.MethodGet, "/admin/queue"); code != http.StatusForbidden {
+		t.Errorf("GET /admin/queue as a deleted admin: status = %d, want 403", code)
+	}
+}
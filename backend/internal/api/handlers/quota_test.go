@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestCreateURLEnforcesURLQuota(t *testing.T) {
+	t.Setenv("MAX_URLS_PER_USER", "2")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "quota")
+
+	router := testRouter(user.ID)
+	router.POST("/urls", NewURLHandler(db).CreateURL)
+
+	for i := 1; i <= 2; i++ {
+		w := doJSON(router, http.MethodPost, "/urls", map[string]string{"url": fmt.Sprintf("https://example.com/%d", i)})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("URL %d: status = %d, want 201: %s", i, w.Code, w.Body)
+		}
+	}
+
+	w := doJSON(router, http.MethodPost, "/urls", map[string]string{"url": "https://example.com/3"})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("URL beyond the limit: status = %d, want 403: %s", w.Code, w.Body)
+	}
+
+	// Another user's quota is separate
+	other := createUser(t, db, "other")
+	otherRouter := testRouter(other.ID)
+	otherRouter.POST("/urls", NewURLHandler(db).CreateURL)
+	if w := doJSON(otherRouter, http.MethodPost, "/urls", map[string]string{"url": "https://example.com/3"}); w.Code != http.StatusCreated {
+		t.Errorf("another user's URL: status = %d, want 201: %s", w.Code, w.Body)
+	}
+}
+
+func TestStartCrawlEnforcesDailyQuota(t *testing.T) {
+	t.Setenv("MAX_CRAWLS_PER_DAY", "2")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "crawler")
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue() // Keep started crawls queued
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+	router.POST("/crawl/stop/:id", handler.StopCrawl)
+
+	first := createURL(t, db, user.ID, "https://example.com/1")
+	second := createURL(t, db, user.ID, "https://example.com/2")
+	third := createURL(t, db, user.ID, "https://example.com/3")
+
+	for _, id := range []uint{first.ID, second.ID} {
+		if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", id), nil); w.Code != http.StatusOK {
+			t.Fatalf("starting URL %d: status = %d, want 200: %s", id, w.Code, w.Body)
+		}
+	}
+
+	// Stopping a queued crawl doesn't give it back
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/stop/%d", first.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("stopping URL %d: status = %d, want 200: %s", first.ID, w.Code, w.Body)
+	}
+
+	for _, id := range []uint{third.ID, first.ID} {
+		if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", id), nil); w.Code != http.StatusTooManyRequests {
+			t.Errorf("starting URL %d beyond the limit: status = %d, want 429: %s", id, w.Code, w.Body)
+		}
+	}
+}
+
+func TestBulkStartCrawlEnforcesDailyQuota(t *testing.T) {
+	t.Setenv("MAX_CRAWLS_PER_DAY", "3")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "bulk")
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/bulk-start", handler.BulkStartCrawl)
+
+	var ids []uint
+	for i := 1; i <= 4; i++ {
+		ids = append(ids, createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i)).ID)
+	}
+
+	if w := doJSON(router, http.MethodPost, "/crawl/bulk-start", map[string][]uint{"ids": ids[:2]}); w.Code != http.StatusOK {
+		t.Fatalf("bulk start within the limit: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := doJSON(router, http.MethodPost, "/crawl/bulk-start", map[string][]uint{"ids": ids[2:]}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("bulk start past the limit: status = %d, want 429: %s", w.Code, w.Body)
+	}
+	if w := doJSON(router, http.MethodPost, "/crawl/bulk-start", map[string][]uint{"ids": ids[2:3]}); w.Code != http.StatusOK {
+		t.Errorf("bulk start up to the limit: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestConcurrentStartsStayWithinDailyQuota(t *testing.T) {
+	t.Setenv("MAX_CRAWLS_PER_DAY", "3")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "rusher")
+
+	// One connection serializes the statements so SQLite doesn't report the table locked
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	const starts = 6
+	var ids []uint
+	for i := 1; i <= starts; i++ {
+		ids = append(ids, createURL(t, db, user.ID, fmt.Sprintf("https://example.com/%d", i)).ID)
+	}
+
+	// Hold every request after it has loaded its URL, so all of them are past their
+	// checks before any crawl is claimed
+	var arrivals sync.WaitGroup
+	arrivals.Add(starts)
+	var loads atomic.Int32
+	db.Callback().Query().After("gorm:query").Register("test:rush", func(tx *gorm.DB) {
+		if tx.Statement.Table != "urls" || loads.Add(1) > starts {
+			return
+		}
+		arrivals.Done()
+		done := make(chan struct{})
+		go func() { arrivals.Wait(); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+
+	codes := make([]int, starts)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", id), nil).Code
+		}()
+	}
+	wg.Wait()
+
+	ok, limited := 0, 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+			limited++
+		}
+	}
+	if ok != 3 || limited != 3 {
+		t.Errorf("statuses = %v, want three 200 and three 429", codes)
+	}
+
+	var running int64
+	db.Model(&models.URL{}).Where("user_id = ? AND status = ?", user.ID, models.StatusRunning).Count(&running)
+	if running != 3 {
+		t.Errorf("%d URLs running, want the quota of 3", running)
+	}
+}
+
+func TestStartCrawlFailsWhenTheStartIsNotRecorded(t *testing.T) {
+	t.Setenv("MAX_CRAWLS_PER_DAY", "1")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "unrecorded")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+
+	fail := true
+	db.Callback().Create().Before("gorm:create").Register("test:fail_events", func(tx *gorm.DB) {
+		if fail && tx.Statement.Table == "crawl_events" {
+			tx.AddError(errors.New("disk full"))
+		}
+	})
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+	target := fmt.Sprintf("/crawl/start/%d", urlEntry.ID)
+
+	// A start that can't be counted doesn't happen, so it can't be a free crawl
+	if w := doJSON(router, http.MethodPost, target, nil); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %s", w.Code, w.Body)
+	}
+	var stored models.URL
+	if err := db.First(&stored, urlEntry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.StatusQueued {
+		t.Errorf("URL status = %s, want still queued", stored.Status)
+	}
+
+	fail = false
+	if w := doJSON(router, http.MethodPost, target, nil); w.Code != http.StatusOK {
+		t.Errorf("start once events record again: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecentResultResponse is a recently viewed result along with when it was viewed
+type RecentResultResponse struct {
+	CrawlResultResponse
+	ViewedAt time.Time `json:"viewed_at"`
+}
+
+// recentResultsLimit is how many viewed results are kept per user (RECENT_RESULTS_LIMIT)
+func recentResultsLimit() int {
+	return max(config.GetEnvInt("RECENT_RESULTS_LIMIT", 50), 1)
+}
+
+// recordResultView marks the result as viewed by the user now and drops the user's views
+// beyond RECENT_RESULTS_LIMIT. Failures are only logged so viewing never fails because of it.
+func recordResultView(db *gorm.DB, userID, resultID uint) {
+	view := models.ResultView{UserID: userID, CrawlResultID: resultID, ViewedAt: time.Now()}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "crawl_result_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"viewed_at"}),
+		}).Create(&view).Error; err != nil {
+			return err
+		}
+
+		var staleIDs []uint
+		if err := tx.Model(&models.ResultView{}).
+			Where("user_id = ?", userID).
+			Order("viewed_at DESC, id DESC").
+			Offset(recentResultsLimit()).
+			Limit(100).
+			Pluck("id", &staleIDs).Error; err != nil {
+			return err
+		}
+		if len(staleIDs) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", staleIDs).Delete(&models.ResultView{}).Error
+	})
+	if err != nil {
+		log.Printf("Failed to record view of result %d by user %d: %v", resultID, userID, err)
+	}
+}
+
+// GetRecentResults returns the results the user most recently opened, newest first
+func (h *URLHandler) GetRecentResults(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var views []struct {
+		models.CrawlResult
+		CrawlURL string    `json:"crawl_url"`
+		ViewedAt time.Time `json:"viewed_at"`
+	}
+	if err := h.db.Table("result_views").
+		Joins("JOIN crawl_results ON result_views.crawl_result_id = crawl_results.id").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("result_views.user_id = ? AND urls.user_id = ? AND urls.deleted_at IS NULL AND crawl_results.deleted_at IS NULL", userID, userID).
+		Select("crawl_results.*, urls.url as crawl_url, result_views.viewed_at").
+		Order("result_views.viewed_at DESC, result_views.id DESC").
+		Limit(recentResultsLimit()).
+		Scan(&views).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve recent results", err)
+		return
+	}
+
+	data := make([]RecentResultResponse, len(views))
+	for i, view := range views {
+		data[i] = RecentResultResponse{
+			CrawlResultResponse: newCrawlResultResponse(view.CrawlResult, view.CrawlURL),
+			ViewedAt:            view.ViewedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestGetRecentResults(t *testing.T) {
+	t.Setenv("RECENT_RESULTS_LIMIT", "2")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "viewer")
+	urlEntry := createURL(t, db, user.ID, "https://recent.example")
+	first := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "First"})
+	second := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Second"})
+	third := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Third"})
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/results/recent", handler.GetRecentResults)
+	router.GET("/results/:id", handler.GetResultDetail)
+
+	view := func(id uint) {
+		t.Helper()
+		if w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d", id), nil); w.Code != http.StatusOK {
+			t.Fatalf("viewing result %d: status = %d, want 200: %s", id, w.Code, w.Body)
+		}
+	}
+	recent := func() []uint {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, "/results/recent", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /results/recent: status = %d, want 200: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Data []RecentResultResponse `json:"data"`
+		}
+		decodeBody(t, w, &body)
+
+		ids := make([]uint, len(body.Data))
+		for i, result := range body.Data {
+			ids[i] = result.ID
+		}
+		return ids
+	}
+
+	view(first.ID)
+	view(second.ID)
+	if got, want := recent(), []uint{second.ID, first.ID}; !slices.Equal(got, want) {
+		t.Errorf("recent = %v, want the latest view first: %v", got, want)
+	}
+
+	// Viewing a result again moves it to the front instead of adding another entry
+	view(first.ID)
+	if got, want := recent(), []uint{first.ID, second.ID}; !slices.Equal(got, want) {
+		t.Errorf("recent after viewing %d again = %v, want %v", first.ID, got, want)
+	}
+
+	// Past the limit the oldest view is dropped
+	view(third.ID)
+	if got, want := recent(), []uint{third.ID, first.ID}; !slices.Equal(got, want) {
+		t.Errorf("recent = %v, want %v", got, want)
+	}
+	var stored int64
+	if err := db.Model(&models.ResultView{}).Where("user_id = ?", user.ID).Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored != 2 {
+		t.Errorf("stored views = %d, want the limit of 2", stored)
+	}
+
+	// Another user's views are their own
+	other := createUser(t, db, "bystander")
+	otherRouter := testRouter(other.ID)
+	otherRouter.GET("/results/recent", handler.GetRecentResults)
+	var body struct {
+		Data []RecentResultResponse `json:"data"`
+	}
+	decodeBody(t, doJSON(otherRouter, http.MethodGet, "/results/recent", nil), &body)
+	if len(body.Data) != 0 {
+		t.Errorf("another user's recent results = %d, want none", len(body.Data))
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DeleteResult soft-deletes a single crawl result along with its links
+func (h *URLHandler) DeleteResult(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid result ID",
+		})
+		return
+	}
+
+	// Verify user owns this crawl result
+	crawlResult, err := findOwnedResult(h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "Result not found", "Failed to find result")
+		return
+	}
+
+	// Pages reached through crawl depth are deleted along with their parent
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		resultIDs := []uint{crawlResult.ID}
+		var childIDs []uint
+		if err := tx.Model(&models.CrawlResult{}).Where("parent_id = ?", crawlResult.ID).Pluck("id", &childIDs).Error; err != nil {
+			return err
+		}
+		resultIDs = append(resultIDs, childIDs...)
+
+		if err := tx.Where("crawl_result_id IN ?", resultIDs).Delete(&models.Link{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", resultIDs).Delete(&models.CrawlResult{}).Error
+	})
+	if err != nil {
+		respondInternalError(c, "Failed to delete result", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Result deleted successfully",
+	})
+}
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// resultsRouter serves GET /results as userID
+func resultsRouter(db *gorm.DB, userID uint) http.Handler {
+	router := testRouter(userID)
+	router.GET("/results", NewURLHandler(db).GetResults)
+	return router
+}
+
+func TestGetResultsCursorPagingIsStableAcrossInserts(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "pager")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+	router := resultsRouter(db, user.ID)
+
+	// Two results share a timestamp, so the id breaks the tie
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var want []uint
+	for _, minutes := range []int{5, 4, 3, 3, 1} {
+		result := createResult(t, db, urlEntry.ID, models.CrawlResult{CreatedAt: base.Add(time.Duration(minutes) * time.Minute)})
+		want = append(want, result.ID)
+	}
+	// Newest first; the tie goes to the higher id
+	want[2], want[3] = want[3], want[2]
+
+	var got []uint
+	ids, cursor := listResults(t, router, "cursor=&limit=2")
+	got = append(got, ids...)
+
+	// Results crawled while paging don't shift the pages still to come
+	createResult(t, db, urlEntry.ID, models.CrawlResult{CreatedAt: base.Add(time.Hour)})
+
+	for pages := 1; cursor != ""; pages++ {
+		if pages > 5 {
+			t.Fatal("paging didn't end")
+		}
+		ids, cursor = listResults(t, router, "limit=2&cursor="+url.QueryEscape(cursor))
+		got = append(got, ids...)
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("paged results = %v, want %v", got, want)
+	}
+}
+
+func TestGetResultsCursorErrors(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "badcursor")
+	router := resultsRouter(db, user.ID)
+
+	for _, query := range []string{"cursor=not-a-cursor", "cursor=&sort_order=asc", "cursor=&sort_by=title"} {
+		if w := doJSON(router, http.MethodGet, "/results?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", query, w.Code, w.Body)
+		}
+	}
+}
+
+func TestGetResultsLinkFilters(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "filters")
+	router := resultsRouter(db, user.ID)
+
+	blog := createResult(t, db, createURL(t, db, user.ID, "https://blog.example.com").ID,
+		models.CrawlResult{Title: "Blog", BrokenLinks: 0, InternalLinks: 40, ExternalLinks: 2})
+	shop := createResult(t, db, createURL(t, db, user.ID, "https://shop.example.com").ID,
+		models.CrawlResult{Title: "Shop", BrokenLinks: 3, InternalLinks: 10, ExternalLinks: 8, HasLoginForm: true})
+	shopOld := createResult(t, db, createURL(t, db, user.ID, "https://old-shop.example.com").ID,
+		models.CrawlResult{Title: "Old shop", BrokenLinks: 12, InternalLinks: 5, ExternalLinks: 0})
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"min_broken_links=3", []uint{shop.ID, shopOld.ID}},
+		{"max_broken_links=3", []uint{blog.ID, shop.ID}},
+		{"min_internal_links=10", []uint{blog.ID, shop.ID}},
+		{"max_internal_links=10", []uint{shop.ID, shopOld.ID}},
+		{"min_external_links=2", []uint{blog.ID, shop.ID}},
+		{"max_external_links=2", []uint{blog.ID, shopOld.ID}},
+		{"has_login_form=true", []uint{shop.ID}},
+		{"has_login_form=false", []uint{blog.ID, shopOld.ID}},
+		{"min_broken_links=1&max_broken_links=5", []uint{shop.ID}},
+		{"search=shop&min_broken_links=10", []uint{shopOld.ID}},
+		{"search=shop&has_login_form=false&max_internal_links=20", []uint{shopOld.ID}},
+	}
+	for _, tt := range tests {
+		ids, _ := listResults(t, router, tt.query+"&sort_order=asc")
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%s: results = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	for _, query := range []string{"min_broken_links=many", "max_internal_links=1.5", "has_login_form=maybe"} {
+		if w := doJSON(router, http.MethodGet, "/results?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestGetResultsHTMLVersionFilter(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "migrator")
+	router := resultsRouter(db, user.ID)
+
+	modern := createResult(t, db, createURL(t, db, user.ID, "https://modern.example").ID,
+		models.CrawlResult{Title: "Modern", HTMLVersion: "HTML5"})
+	xhtml := createResult(t, db, createURL(t, db, user.ID, "https://xhtml.example").ID,
+		models.CrawlResult{Title: "XHTML", HTMLVersion: "XHTML 1.0", BrokenLinks: 2})
+	legacy := createResult(t, db, createURL(t, db, user.ID, "https://legacy.example").ID,
+		models.CrawlResult{Title: "Legacy", HTMLVersion: "HTML 4.01"})
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"html_version=XHTML+1.0", []uint{xhtml.ID}},
+		{"html_version=HTML5", []uint{modern.ID}},
+		{"html_version=non_html5", []uint{xhtml.ID, legacy.ID}},
+		{"html_version=HTML+3.2", nil},
+		{"html_version=non_html5&min_broken_links=1", []uint{xhtml.ID}},
+		{"", []uint{modern.ID, xhtml.ID, legacy.ID}},
+	}
+	for _, tt := range tests {
+		ids, _ := listResults(t, router, tt.query+"&sort_order=asc")
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%q: results = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
+
+func TestGetResultsThinContent(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "editor")
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id", handler.GetResultDetail)
+
+	thin := createResult(t, db, createURL(t, db, user.ID, "https://example.com/stub").ID,
+		models.CrawlResult{WordCount: 40, IsThinContent: true})
+	full := createResult(t, db, createURL(t, db, user.ID, "https://example.com/guide").ID,
+		models.CrawlResult{WordCount: 1200})
+
+	for query, want := range map[string][]uint{
+		"is_thin_content=true":  {thin.ID},
+		"is_thin_content=false": {full.ID},
+		"":                      {thin.ID, full.ID},
+	} {
+		ids, _ := listResults(t, router, query+"&sort_order=asc")
+		if !slices.Equal(ids, want) {
+			t.Errorf("%q: results = %v, want %v", query, ids, want)
+		}
+	}
+	if w := doJSON(router, http.MethodGet, "/results?is_thin_content=maybe", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("is_thin_content=maybe: status = %d, want 400", w.Code)
+	}
+
+	w := doJSON(router, http.MethodGet, "/results?is_thin_content=true", nil)
+	var list struct {
+		Data struct {
+			Data []CrawlResultResponse `json:"data"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &list)
+	if len(list.Data.Data) != 1 || list.Data.Data[0].WordCount != 40 || !list.Data.Data[0].IsThinContent {
+		t.Errorf("list = %+v, want the thin result with its word count", list.Data.Data)
+	}
+
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d", full.ID), nil)
+	var detail struct {
+		Data CrawlResultResponse `json:"data"`
+	}
+	decodeBody(t, w, &detail)
+	if detail.Data.WordCount != 1200 || detail.Data.IsThinContent {
+		t.Errorf("detail word count %d, thin content %v; want 1200, false", detail.Data.WordCount, detail.Data.IsThinContent)
+	}
+}
+
+func TestResultDetailResourceCounts(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "auditor")
+	result := createResult(t, db, createURL(t, db, user.ID, "https://example.com").ID,
+		models.CrawlResult{ScriptCount: 4, StylesheetCount: 2, InlineScriptCount: 1})
+
+	router := testRouter(user.ID)
+	router.GET("/results/:id", NewURLHandler(db).GetResultDetail)
+	w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d", result.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Data CrawlResultResponse `json:"data"`
+	}
+	decodeBody(t, w, &body)
+	d := body.Data
+	if d.ScriptCount == nil || *d.ScriptCount != 4 || d.StylesheetCount == nil || *d.StylesheetCount != 2 ||
+		d.InlineScriptCount == nil || *d.InlineScriptCount != 1 {
+		t.Errorf("resource counts = %v, %v, %v; want 4 scripts, 2 stylesheets and 1 inline script", d.ScriptCount, d.StylesheetCount, d.InlineScriptCount)
+	}
+}
+
+func TestGetResultsSearchModes(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "searcher")
+	router := resultsRouter(db, user.ID)
+
+	blog := createResult(t, db, createURL(t, db, user.ID, "https://blog.example.com").ID, models.CrawlResult{Title: "Go blog"})
+	places := createResult(t, db, createURL(t, db, user.ID, "https://places.example.com").ID, models.CrawlResult{Title: "Going places"})
+	language := createResult(t, db, createURL(t, db, user.ID, "https://lang.example.com").ID, models.CrawlResult{Title: "The Go language"})
+	createResult(t, db, createURL(t, db, user.ID, "https://other.example.com").ID, models.CrawlResult{Title: "Unrelated"})
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"search=Go&search_mode=prefix", []uint{blog.ID, places.ID}},
+		{"search=https://lang&search_mode=prefix", []uint{language.ID}},
+		{"search=language&search_mode=prefix", []uint{}},
+		{"search=Go&search_mode=contains", []uint{blog.ID, places.ID, language.ID}},
+		{"search=Go", []uint{blog.ID, places.ID, language.ID}},
+		{"search=places.example&search_mode=contains", []uint{places.ID}},
+		// SQLite has no full-text index, so fulltext falls back to contains
+		{"search=language&search_mode=fulltext", []uint{language.ID}},
+	}
+	for _, tt := range tests {
+		ids, _ := listResults(t, router, tt.query+"&sort_order=asc")
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("%s: results = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+
+	if w := doJSON(router, http.MethodGet, "/results?search=Go&search_mode=fuzzy", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown search mode: status = %d, want 400", w.Code)
+	}
+}
+
+func TestDeleteResult(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+	urlEntry := createURL(t, db, owner.ID, "https://example.com")
+
+	kept := createResult(t, db, urlEntry.ID, models.CrawlResult{})
+	createLinks(t, db, kept.ID, "https://example.com/kept")
+	deleted := createResult(t, db, urlEntry.ID, models.CrawlResult{})
+	createLinks(t, db, deleted.ID, "https://example.com/a", "https://example.com/b")
+	// A page reached through crawl depth goes with its parent
+	child := createResult(t, db, urlEntry.ID, models.CrawlResult{ParentID: &deleted.ID})
+	createLinks(t, db, child.ID, "https://example.com/child")
+	foreign := createResult(t, db, createURL(t, db, other.ID, "https://example.org").ID, models.CrawlResult{})
+
+	handler := NewURLHandler(db)
+	router := testRouter(owner.ID)
+	router.GET("/results", handler.GetResults)
+	router.DELETE("/results/:id", handler.DeleteResult)
+
+	if w := doJSON(router, http.MethodDelete, fmt.Sprintf("/results/%d", deleted.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ids, _ := listResults(t, router, ""); !slices.Equal(ids, []uint{kept.ID}) {
+		t.Errorf("results after delete = %v, want only [%d]", ids, kept.ID)
+	}
+
+	var links []models.Link
+	if err := db.Order("id").Find(&links).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].CrawlResultID != kept.ID {
+		t.Errorf("links left = %+v, want only the kept result's", links)
+	}
+	// The links are soft-deleted, so their rows are still there
+	var softDeleted int64
+	db.Unscoped().Model(&models.Link{}).Where("deleted_at IS NOT NULL").Count(&softDeleted)
+	if softDeleted != 3 {
+		t.Errorf("%d soft-deleted links, want 3", softDeleted)
+	}
+	if err := db.Unscoped().First(&models.CrawlResult{}, child.ID).Error; err != nil {
+		t.Errorf("child result row is gone, want it soft-deleted: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{"already deleted", fmt.Sprint(deleted.ID), http.StatusNotFound},
+		{"another user's result", fmt.Sprint(foreign.ID), http.StatusNotFound},
+		{"invalid id", "abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := doJSON(router, http.MethodDelete, "/results/"+tt.id, nil); w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+		}
+	}
+	if err := db.First(&models.CrawlResult{}, foreign.ID).Error; err != nil {
+		t.Errorf("another user's result was deleted: %v", err)
+	}
+}
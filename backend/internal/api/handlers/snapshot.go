@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSnapshot returns the HTML the crawler analyzed for a crawl result
+func (h *URLHandler) GetSnapshot(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if !config.GetEnvBool("STORE_SNAPSHOTS", false) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "HTML snapshots are disabled on this server",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid result ID",
+		})
+		return
+	}
+
+	// Verify user owns this crawl result
+	var snapshot models.CrawlSnapshot
+	if err := h.db.Table("crawl_snapshots").
+		Joins("JOIN crawl_results ON crawl_snapshots.crawl_result_id = crawl_results.id").
+		Joins("JOIN urls ON crawl_results.url_id = urls.id").
+		Where("crawl_results.id = ? AND urls.user_id = ? AND crawl_results.deleted_at IS NULL", id, userID).
+		Select("crawl_snapshots.*").
+		First(&snapshot).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "No snapshot found for this result",
+			})
+			return
+		}
+		respondInternalError(c, "Failed to find snapshot", err)
+		return
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(snapshot.HTML))
+	if err != nil {
+		respondInternalError(c, "Failed to read snapshot", err)
+		return
+	}
+	defer reader.Close()
+
+	html, err := io.ReadAll(reader)
+	if err != nil {
+		respondInternalError(c, "Failed to read snapshot", err)
+		return
+	}
+
+	if snapshot.Truncated {
+		c.Header("X-Snapshot-Truncated", "true")
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", html)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/crawler"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	t.Setenv("STORE_SNAPSHOTS", "true")
+	page := `<!DOCTYPE html><html><head><title>Snapshot</title></head><body><p>` + strings.Repeat("Lorem ipsum. ", 50) + `</p></body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "debugger")
+	other := createUser(t, db, "snoop")
+	cs := crawler.NewCrawlerService(db)
+	crawl := func(address string) models.CrawlResult {
+		t.Helper()
+		urlEntry := createURL(t, db, user.ID, address)
+		if claimed, err := cs.ClaimURL(urlEntry.ID); err != nil || !claimed {
+			t.Fatalf("claiming URL %d: %v, %v", urlEntry.ID, claimed, err)
+		}
+		if err := cs.CrawlURL(urlEntry.ID); err != nil {
+			t.Fatalf("CrawlURL: %v", err)
+		}
+		var result models.CrawlResult
+		if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	snapshotRouter := func(userID uint) http.Handler {
+		router := testRouter(userID)
+		router.GET("/results/:id/snapshot", NewURLHandler(db).GetSnapshot)
+		return router
+	}
+	router := snapshotRouter(user.ID)
+
+	result := crawl(server.URL + "/")
+	var stored models.CrawlSnapshot
+	if err := db.Where("crawl_result_id = ?", result.ID).First(&stored).Error; err != nil {
+		t.Fatalf("no snapshot was stored: %v", err)
+	}
+	if len(stored.HTML) >= len(page) || stored.Size != len(page) {
+		t.Errorf("stored %d bytes of size %d, want the %d byte page compressed", len(stored.HTML), stored.Size, len(page))
+	}
+
+	target := fmt.Sprintf("/results/%d/snapshot", result.ID)
+	w := doJSON(router, http.MethodGet, target, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w.Body.String() != page || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("snapshot = %q (%s), want the page as crawled", w.Body, w.Header().Get("Content-Type"))
+	}
+	if w.Header().Get("X-Snapshot-Truncated") != "" {
+		t.Error("a complete snapshot is marked truncated")
+	}
+
+	if w := doJSON(snapshotRouter(other.ID), http.MethodGet, target, nil); w.Code != http.StatusNotFound {
+		t.Errorf("another user's snapshot: status = %d, want 404", w.Code)
+	}
+
+	// Oversized pages are cut at SNAPSHOT_MAX_BYTES
+	t.Setenv("SNAPSHOT_MAX_BYTES", "100")
+	truncated := crawl(server.URL + "/?truncated")
+	w = doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d/snapshot", truncated.ID), nil)
+	if w.Code != http.StatusOK || w.Body.String() != page[:100] || w.Header().Get("X-Snapshot-Truncated") != "true" {
+		t.Errorf("truncated snapshot: status %d, body %q, truncated header %q; want the first 100 bytes marked truncated",
+			w.Code, w.Body, w.Header().Get("X-Snapshot-Truncated"))
+	}
+
+	t.Setenv("STORE_SNAPSHOTS", "false")
+	w = doJSON(router, http.MethodGet, target, nil)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "disabled") {
+		t.Errorf("snapshots disabled: status = %d, body %s; want 404 saying they're disabled", w.Code, w.Body)
+	}
+}
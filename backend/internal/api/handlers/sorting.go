@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sortable columns of the URL and result lists
+var (
+	urlSortColumns    = []string{"created_at", "updated_at", "url", "status"}
+	resultSortColumns = []string{"crawled_at", "url", "title", "links", "relevance"}
+)
+
+// parseSort reads sort_by and sort_order, responding 400 when sort_by isn't one of
+// columns or sort_order isn't asc or desc (in any case). sort_order defaults to desc.
+func parseSort(c *gin.Context, columns []string, defaultColumn string) (sortBy, sortOrder string, ok bool) {
+	sortBy = c.DefaultQuery("sort_by", defaultColumn)
+	if !slices.Contains(columns, sortBy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("Invalid sort_by: must be one of %s", strings.Join(columns, ", ")),
+		})
+		return "", "", false
+	}
+
+	sortOrder = strings.ToLower(c.DefaultQuery("sort_order", "desc"))
+	if sortOrder != "asc" && sortOrder != "desc" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid sort_order: must be asc or desc",
+		})
+		return "", "", false
+	}
+
+	return sortBy, sortOrder, true
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestListSortValidation(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "sorter")
+	urlEntry := createURL(t, db, user.ID, "https://sort.example")
+	createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "B"})
+	createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "A"})
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/results", handler.GetResults)
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/results?sort_by=title&sort_order=ASC", http.StatusOK},
+		{"/results?sort_order=Desc", http.StatusOK},
+		{"/results?sort_order=drop", http.StatusBadRequest},
+		{"/results?sort_order=asc%3BDROP%20TABLE%20users", http.StatusBadRequest},
+		{"/results?sort_by=titel", http.StatusBadRequest},
+		{"/results?sort_by=created_at", http.StatusBadRequest}, // The URL list's column
+		{"/urls?sort_by=url&sort_order=asc", http.StatusOK},
+		{"/urls?sort_order=sideways", http.StatusBadRequest},
+		{"/urls?sort_by=password", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := doJSON(router, http.MethodGet, tt.target, nil); w.Code != tt.want {
+			t.Errorf("GET %s: status = %d, want %d: %s", tt.target, w.Code, tt.want, w.Body)
+		}
+	}
+
+	// Valid values still sort, whatever their case
+	results := resultsRouter(db, user.ID)
+	asc, _ := listResults(t, results, "sort_by=title&sort_order=ASC")
+	desc, _ := listResults(t, results, "sort_by=title&sort_order=desc")
+	slices.Reverse(desc)
+	if len(asc) != 2 || !slices.Equal(asc, desc) {
+		t.Errorf("title ascending = %v, want the reverse of descending", asc)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DashboardStats holds the top-line numbers shown on the dashboard
+type DashboardStats struct {
+	URLsByStatus     map[string]int64 `json:"urls_by_status"`
+	TotalURLs        int64            `json:"total_urls"`
+	TotalResults     int64            `json:"total_results"`
+	TotalBrokenLinks int64            `json:"total_broken_links"`
+	AvgLinksPerPage  float64          `json:"avg_links_per_page"`
+	LastCrawledAt    *time.Time       `json:"last_crawled_at"`
+}
+
+// GetStats returns aggregate URL and crawl result stats for the current user
+func (h *URLHandler) GetStats(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	stats := DashboardStats{
+		URLsByStatus: map[string]int64{
+			string(models.StatusQueued):    0,
+			string(models.StatusRunning):   0,
+			string(models.StatusCompleted): 0,
+			string(models.StatusError):     0,
+		},
+	}
+
+	var statusCounts []struct {
+		Status string
+		Count  int64
+	}
+	if err := h.db.Model(&models.URL{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Group("status").
+		Scan(&statusCounts).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve stats", err)
+		return
+	}
+	for _, sc := range statusCounts {
+		stats.URLsByStatus[sc.Status] = sc.Count
+		stats.TotalURLs += sc.Count
+	}
+
+	// Pages reached by following links are part of their root's crawl, not results of their own
+	userResults := func() *gorm.DB {
+		return h.db.Model(&models.CrawlResult{}).
+			Joins("JOIN urls ON crawl_results.url_id = urls.id").
+			Where("urls.user_id = ? AND urls.deleted_at IS NULL AND crawl_results.parent_id IS NULL", userID)
+	}
+
+	var resultAggregates struct {
+		TotalResults     int64
+		TotalBrokenLinks int64
+		AvgLinksPerPage  float64
+	}
+	if err := userResults().
+		Select(`COUNT(*) AS total_results,
+			COALESCE(SUM(crawl_results.broken_links), 0) AS total_broken_links,
+			COALESCE(AVG(crawl_results.internal_links + crawl_results.external_links), 0) AS avg_links_per_page`).
+		Scan(&resultAggregates).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve stats", err)
+		return
+	}
+
+	stats.TotalResults = resultAggregates.TotalResults
+	stats.TotalBrokenLinks = resultAggregates.TotalBrokenLinks
+	stats.AvgLinksPerPage = resultAggregates.AvgLinksPerPage
+
+	// The latest crawl time is read from the column itself: SQLite returns MAX() of a
+	// timestamp as untyped text, which can't be scanned into a time
+	var latest []time.Time
+	if err := userResults().
+		Order("crawl_results.created_at desc").
+		Limit(1).
+		Pluck("crawl_results.created_at", &latest).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve stats", err)
+		return
+	}
+	if len(latest) > 0 {
+		stats.LastCrawledAt = &latest[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
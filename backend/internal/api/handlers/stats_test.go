@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// getStats calls GET /stats as userID
+func getStats(t *testing.T, db *gorm.DB, userID uint) DashboardStats {
+	t.Helper()
+	router := testRouter(userID)
+	router.GET("/stats", NewURLHandler(db).GetStats)
+
+	w := doJSON(router, http.MethodGet, "/stats", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Data DashboardStats `json:"data"`
+	}
+	decodeBody(t, w, &body)
+	return body.Data
+}
+
+func TestGetStats(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "dashboard")
+	other := createUser(t, db, "other")
+
+	crawled := createURL(t, db, user.ID, "https://crawled.example.com")
+	db.Model(&crawled).Update("status", models.StatusCompleted)
+	failed := createURL(t, db, user.ID, "https://failed.example.com")
+	db.Model(&failed).Update("status", models.StatusError)
+	createURL(t, db, user.ID, "https://queued.example.com")
+
+	latest := time.Date(2024, 6, 2, 9, 30, 0, 0, time.UTC)
+	createResult(t, db, crawled.ID, models.CrawlResult{InternalLinks: 8, ExternalLinks: 2, BrokenLinks: 1, CreatedAt: latest.Add(-24 * time.Hour)})
+	root := createResult(t, db, crawled.ID, models.CrawlResult{InternalLinks: 4, ExternalLinks: 6, BrokenLinks: 3, CreatedAt: latest})
+	createResult(t, db, failed.ID, models.CrawlResult{ResponseStatus: 500, CreatedAt: latest.Add(-time.Hour)})
+
+	// Linked pages and other users' results aren't counted
+	createResult(t, db, crawled.ID, models.CrawlResult{ParentID: &root.ID, BrokenLinks: 50, CreatedAt: latest.Add(time.Hour)})
+	createResult(t, db, createURL(t, db, other.ID, "https://theirs.example.com").ID, models.CrawlResult{BrokenLinks: 7})
+
+	stats := getStats(t, db, user.ID)
+	wantStatuses := map[string]int64{"queued": 1, "running": 0, "completed": 1, "error": 1}
+	for status, want := range wantStatuses {
+		if stats.URLsByStatus[status] != want {
+			t.Errorf("%s URLs = %d, want %d", status, stats.URLsByStatus[status], want)
+		}
+	}
+	if stats.TotalURLs != 3 || stats.TotalResults != 3 || stats.TotalBrokenLinks != 4 {
+		t.Errorf("URLs, results, broken links = %d, %d, %d, want 3, 3, 4",
+			stats.TotalURLs, stats.TotalResults, stats.TotalBrokenLinks)
+	}
+	// (10 + 10 + 0) links over 3 pages
+	if stats.AvgLinksPerPage < 6.66 || stats.AvgLinksPerPage > 6.67 {
+		t.Errorf("average links per page = %v, want 6.67", stats.AvgLinksPerPage)
+	}
+	if stats.LastCrawledAt == nil || !stats.LastCrawledAt.Equal(latest) {
+		t.Errorf("last crawled at = %v, want %v", stats.LastCrawledAt, latest)
+	}
+}
+
+func TestGetStatsWithoutData(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "newcomer")
+
+	stats := getStats(t, db, user.ID)
+	if stats.TotalURLs != 0 || stats.TotalResults != 0 || stats.TotalBrokenLinks != 0 || stats.AvgLinksPerPage != 0 {
+		t.Errorf("stats = %+v, want zeros", stats)
+	}
+	if stats.LastCrawledAt != nil {
+		t.Errorf("last crawled at = %v, want none", stats.LastCrawledAt)
+	}
+	if len(stats.URLsByStatus) != 4 || stats.URLsByStatus["queued"] != 0 {
+		t.Errorf("URLs by status = %v, want every status at 0", stats.URLsByStatus)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamStatus pushes the user's crawl status changes as server-sent events, with a
+// keep-alive comment every SSE_KEEPALIVE_INTERVAL so proxies don't close the connection
+func (h *CrawlHandler) StreamStatus(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	updates, unsubscribe := h.crawlerService.SubscribeStatus(userID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable response buffering in nginx
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(config.GetEnvDuration("SSE_KEEPALIVE_INTERVAL", 15*time.Second))
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return // Client disconnected
+		case update := <-updates:
+			c.SSEvent("status", update)
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/crawler"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestStreamStatus(t *testing.T) {
+	t.Setenv("SSE_KEEPALIVE_INTERVAL", "20ms")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "watcher")
+	other := createUser(t, db, "neighbour")
+
+	handler := NewCrawlHandler(db)
+	handler.crawlerService.PauseQueue()
+	router := testRouter(user.ID)
+	router.GET("/results/stream", handler.StreamStatus)
+	router.POST("/crawl/start/:id", handler.StartCrawl)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/results/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	next := func(want func(string) bool, what string) string {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream ended before %s", what)
+				}
+				if want(line) {
+					return line
+				}
+			case <-timeout:
+				t.Fatalf("no %s within 5s", what)
+			}
+		}
+	}
+
+	next(func(line string) bool { return line == ": keep-alive" }, "keep-alive comment")
+
+	// Another user's crawl isn't streamed
+	handler.crawlerService.RecordEvent(createURL(t, db, other.ID, "https://theirs.example").ID, other.ID, models.EventStarted, "")
+
+	mine := createURL(t, db, user.ID, "https://mine.example")
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/crawl/start/%d", mine.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("start: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	next(func(line string) bool { return line == "event:status" }, "status event")
+	data := next(func(line string) bool { return strings.HasPrefix(line, "data:") }, "event data")
+	var update crawler.StatusUpdate
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(data, "data:")), &update); err != nil {
+		t.Fatalf("event data %q isn't JSON: %v", data, err)
+	}
+	if update.URLID != mine.ID || update.Event != models.EventStarted || update.Status != models.StatusRunning {
+		t.Errorf("update = %+v, want URL %d started and running", update, mine.ID)
+	}
+}
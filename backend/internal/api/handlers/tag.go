@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TagHandler struct {
+	db *gorm.DB
+}
+
+func NewTagHandler(db *gorm.DB) *TagHandler {
+	return &TagHandler{db: db}
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type AttachTagRequest struct {
+	TagID uint `json:"tag_id" binding:"required"`
+}
+
+// GetTags returns all tags for the authenticated user
+func (h *TagHandler) GetTags(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var tags []models.Tag
+	if err := h.db.Where("user_id = ?", userID).Order("name asc").Find(&tags).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve tags", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tags,
+	})
+}
+
+// CreateTag creates a new tag for the authenticated user
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Tag name cannot be empty",
+		})
+		return
+	}
+
+	// Tag names are unique per user
+	var existingTag models.Tag
+	if err := h.db.Where("user_id = ? AND name = ?", userID, name).First(&existingTag).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Tag already exists",
+		})
+		return
+	}
+
+	tag := models.Tag{
+		UserID: userID,
+		Name:   name,
+	}
+
+	// idx_tags_user_name catches a concurrent create of the same name after the check above
+	if err := h.db.Create(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "Tag already exists",
+			})
+			return
+		}
+		respondInternalError(c, "Failed to create tag", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Tag created successfully",
+		"data":    tag,
+	})
+}
+
+// AttachTag attaches one of the user's tags to one of their URLs
+func (h *TagHandler) AttachTag(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid URL ID",
+		})
+		return
+	}
+
+	var req AttachTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	url, tag, ok := h.findOwnedURLAndTag(c, id, uint64(req.TagID), userID)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(&url).Association("Tags").Append(&tag); err != nil {
+		respondInternalError(c, "Failed to attach tag", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Tag attached successfully",
+	})
+}
+
+// DetachTag removes a tag from a URL
+func (h *TagHandler) DetachTag(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid URL ID",
+		})
+		return
+	}
+
+	tagID, err := strconv.ParseUint(c.Param("tagId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid tag ID",
+		})
+		return
+	}
+
+	url, tag, ok := h.findOwnedURLAndTag(c, id, tagID, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(&url).Association("Tags").Delete(&tag); err != nil {
+		respondInternalError(c, "Failed to detach tag", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Tag detached successfully",
+	})
+}
+
+// findOwnedURLAndTag loads a URL and a tag that both belong to the user, writing an error response if either is missing
+func (h *TagHandler) findOwnedURLAndTag(c *gin.Context, urlID, tagID uint64, userID uint) (models.URL, models.Tag, bool) {
+	url, err := findOwned[models.URL](h.db, uint(urlID), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
+		return url, models.Tag{}, false
+	}
+
+	tag, err := findOwned[models.Tag](h.db, uint(tagID), userID)
+	if err != nil {
+		respondFindError(c, err, "Tag not found", "Failed to find tag")
+		return url, tag, false
+	}
+
+	return url, tag, true
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// tagRouter serves the tag and URL list routes as userID
+func tagRouter(db *gorm.DB, userID uint) http.Handler {
+	tagHandler := NewTagHandler(db)
+	router := testRouter(userID)
+	router.GET("/urls", NewURLHandler(db).GetURLs)
+	router.POST("/tags", tagHandler.CreateTag)
+	router.POST("/urls/:id/tags", tagHandler.AttachTag)
+	router.DELETE("/urls/:id/tags/:tagId", tagHandler.DetachTag)
+	return router
+}
+
+// createTag creates a tag through the API and returns its ID
+func createTag(t *testing.T, router http.Handler, name string) uint {
+	t.Helper()
+	w := doJSON(router, http.MethodPost, "/tags", map[string]string{"name": name})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("creating tag %q: status = %d, want 201: %s", name, w.Code, w.Body)
+	}
+	var body struct {
+		Data models.Tag `json:"data"`
+	}
+	decodeBody(t, w, &body)
+	return body.Data.ID
+}
+
+func TestCreateTagRejectsDuplicates(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "tagger")
+	router := tagRouter(db, user.ID)
+
+	createTag(t, router, "blog")
+	if w := doJSON(router, http.MethodPost, "/tags", map[string]string{"name": " blog "}); w.Code != http.StatusConflict {
+		t.Errorf("duplicate tag: status = %d, want 409: %s", w.Code, w.Body)
+	}
+
+	// Names are unique per user only
+	other := createUser(t, db, "other")
+	createTag(t, tagRouter(db, other.ID), "blog")
+}
+
+func TestCreateTagConcurrentDuplicate(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "racer")
+
+	// Another request creates the same tag between the existence check and the insert
+	if err := db.Callback().Create().Before("gorm:create").Register("test:create_tag", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Dest.(*models.Tag); ok {
+			tx.Session(&gorm.Session{NewDB: true}).Exec(
+				"INSERT INTO tags (user_id, name, created_at, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)",
+				user.ID, "news")
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := doJSON(tagRouter(db, user.ID), http.MethodPost, "/tags", map[string]string{"name": "news"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409: %s", w.Code, w.Body)
+	}
+}
+
+func TestAttachTagAndFilterURLs(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "filter")
+	router := tagRouter(db, user.ID)
+
+	tagged := createURL(t, db, user.ID, "https://example.com/tagged")
+	untagged := createURL(t, db, user.ID, "https://example.com/untagged")
+	tagID := createTag(t, router, "docs")
+
+	attach := fmt.Sprintf("/urls/%d/tags", tagged.ID)
+	if w := doJSON(router, http.MethodPost, attach, map[string]uint{"tag_id": tagID}); w.Code != http.StatusOK {
+		t.Fatalf("attaching tag: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	// Attaching twice is harmless
+	if w := doJSON(router, http.MethodPost, attach, map[string]uint{"tag_id": tagID}); w.Code != http.StatusOK {
+		t.Fatalf("attaching tag again: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	if ids := listURLIDs(t, router, "tag=docs"); len(ids) != 1 || ids[0] != tagged.ID {
+		t.Errorf("URLs tagged docs = %v, want [%d]", ids, tagged.ID)
+	}
+	if ids := listURLIDs(t, router, ""); len(ids) != 2 {
+		t.Errorf("all URLs = %v, want both %d and %d", ids, tagged.ID, untagged.ID)
+	}
+
+	if w := doJSON(router, http.MethodDelete, fmt.Sprintf("/urls/%d/tags/%d", tagged.ID, tagID), nil); w.Code != http.StatusOK {
+		t.Fatalf("detaching tag: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ids := listURLIDs(t, router, "tag=docs"); len(ids) != 0 {
+		t.Errorf("URLs tagged docs after detaching = %v, want none", ids)
+	}
+}
+
+func TestAttachTagOfAnotherUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "intruder")
+
+	urlEntry := createURL(t, db, owner.ID, "https://example.com")
+	othersTag := createTag(t, tagRouter(db, other.ID), "mine")
+	router := tagRouter(db, owner.ID)
+
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/urls/%d/tags", urlEntry.ID), map[string]uint{"tag_id": othersTag})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("attaching another user's tag: status = %d, want 404: %s", w.Code, w.Body)
+	}
+
+	// Another user's tag of the same name doesn't match the filter
+	if ids := listURLIDs(t, router, "tag=mine"); len(ids) != 0 {
+		t.Errorf("URLs tagged mine = %v, want none", ids)
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+var (
+	crawledAtField = regexp.MustCompile(`"crawled_at":"([^"]*)"`)
+	timestampField = regexp.MustCompile(`"([a-z_]+_at)":"([^"]*)"`)
+)
+
+func TestCrawledAtIsRFC3339InUTC(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "clock")
+	urlEntry := createURL(t, db, user.ID, "https://clock.example")
+	// Stored with a non-UTC offset; every response reports the same instant in UTC
+	crawledAt := time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	result := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Clock", ResponseStatus: 200, CreatedAt: crawledAt})
+	const want = "2024-05-01T12:30:00Z"
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/urls/:id/trend", handler.GetURLTrend)
+	router.GET("/results", handler.GetResults)
+	router.GET("/results/:id", handler.GetResultDetail)
+	router.GET("/results/:id/export", handler.ExportResult)
+
+	for _, target := range []string{
+		"/results",
+		fmt.Sprintf("/results/%d", result.ID),
+		fmt.Sprintf("/results/%d/export", result.ID),
+		"/urls?include=latest_result",
+		fmt.Sprintf("/urls/%d/trend", urlEntry.ID),
+	} {
+		w := doJSON(router, http.MethodGet, target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", target, w.Code, w.Body)
+		}
+		matches := crawledAtField.FindAllStringSubmatch(w.Body.String(), -1)
+		if len(matches) == 0 {
+			t.Errorf("GET %s: no crawled_at in %s", target, w.Body)
+		}
+		for _, match := range matches {
+			if match[1] != want {
+				t.Errorf("GET %s: crawled_at = %q, want %q", target, match[1], want)
+			}
+		}
+	}
+
+	w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d/export?format=csv", result.ID), nil)
+	if summary := strings.Split(w.Body.String(), "\n")[1]; !strings.HasSuffix(strings.TrimSpace(summary), ","+want) {
+		t.Errorf("CSV summary row = %q, want crawled_at %s", summary, want)
+	}
+}
+
+func TestModelTimestampsAreUTC(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "zone")
+	urlEntry := createURL(t, db, user.ID, "https://zone.example")
+	result := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Zone", ResponseStatus: 200})
+
+	// Rows written with a non-UTC offset, as a server outside UTC would
+	cest := time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	const want = "2024-05-01T12:30:00Z"
+	seed := func(model any, id uint, columns map[string]any) {
+		t.Helper()
+		if err := db.Model(model).Where("id = ?", id).UpdateColumns(columns).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed(&models.URL{}, urlEntry.ID, map[string]any{"created_at": cest, "updated_at": cest})
+	link := models.Link{CrawlResultID: result.ID, URL: "https://zone.example/a", Type: models.LinkTypeInternal}
+	event := models.CrawlEvent{URLID: urlEntry.ID, UserID: user.ID, Type: models.EventStarted}
+	key := models.APIKey{UserID: user.ID, Label: "zone", KeyHash: "hash", Prefix: "sk_zone"}
+	for _, row := range []any{&link, &event, &key} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed(&models.Link{}, link.ID, map[string]any{"created_at": cest, "updated_at": cest, "last_checked_at": cest})
+	seed(&models.CrawlEvent{}, event.ID, map[string]any{"created_at": cest})
+	seed(&models.APIKey{}, key.ID, map[string]any{"created_at": cest, "updated_at": cest, "last_used_at": cest})
+
+	urls := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/urls/:id", urls.GetURL)
+	router.GET("/urls/:id/events", urls.GetURLEvents)
+	router.GET("/results/:id/links", urls.GetLinks)
+	router.GET("/api-keys", NewAuthHandler(db).ListAPIKeys)
+
+	tests := []struct {
+		target string
+		fields []string
+	}{
+		{fmt.Sprintf("/urls/%d", urlEntry.ID), []string{"created_at", "updated_at"}},
+		{fmt.Sprintf("/urls/%d/events", urlEntry.ID), []string{"created_at"}},
+		{fmt.Sprintf("/results/%d/links", result.ID), []string{"created_at", "updated_at", "last_checked_at"}},
+		{"/api-keys", []string{"created_at", "updated_at", "last_used_at"}},
+	}
+	for _, tt := range tests {
+		w := doJSON(router, http.MethodGet, tt.target, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", tt.target, w.Code, w.Body)
+		}
+
+		seen := make(map[string]bool)
+		for _, match := range timestampField.FindAllStringSubmatch(w.Body.String(), -1) {
+			field, value := match[1], match[2]
+			if !strings.HasSuffix(value, "Z") {
+				t.Errorf("GET %s: %s = %q, want UTC", tt.target, field, value)
+			}
+			if value == want {
+				seen[field] = true
+			}
+		}
+		for _, field := range tt.fields {
+			if !seen[field] {
+				t.Errorf("GET %s: no %s of %s in %s", tt.target, field, want, w.Body)
+			}
+		}
+	}
+
+	// Timestamps GORM sets itself are UTC too
+	var created models.URL
+	if err := db.First(&created, createURL(t, db, user.ID, "https://new.example").ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if created.CreatedAt.Location() != time.UTC || created.UpdatedAt.Location() != time.UTC {
+		t.Errorf("created_at %v, updated_at %v; want both in UTC", created.CreatedAt, created.UpdatedAt)
+	}
+}
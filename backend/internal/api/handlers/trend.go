@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrendPoint is the link counts of one crawl of a URL
+type TrendPoint struct {
+	CrawledAt     time.Time `json:"crawled_at"`
+	InternalLinks int       `json:"internal_links"`
+	ExternalLinks int       `json:"external_links"`
+	BrokenLinks   int       `json:"broken_links"`
+}
+
+// GetURLTrend returns the link counts of a URL's successful crawls, oldest first. With a
+// limit only the most recent points are returned.
+func (h *URLHandler) GetURLTrend(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid URL ID",
+		})
+		return
+	}
+
+	limit := 0
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid limit: must be a positive integer",
+			})
+			return
+		}
+	}
+
+	url, err := findOwned[models.URL](h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
+		return
+	}
+
+	// Pages reached through crawl depth and failed crawls have no comparable link counts
+	query := h.db.Model(&models.CrawlResult{}).
+		Select("created_at AS crawled_at, internal_links, external_links, broken_links").
+		Where("url_id = ? AND parent_id IS NULL AND response_status < ?", url.ID, 400).
+		Order("created_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	points := []TrendPoint{}
+	if err := query.Scan(&points).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve trend", err)
+		return
+	}
+	slices.Reverse(points)
+	for i := range points {
+		points[i].CrawledAt = points[i].CrawledAt.UTC()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    points,
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestGetURLTrend(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "trender")
+	urlEntry := createURL(t, db, user.ID, "https://trend.example")
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// Seeded out of order, so the series has to be sorted by crawl time
+	third := createResult(t, db, urlEntry.ID, models.CrawlResult{ResponseStatus: 200, InternalLinks: 7, ExternalLinks: 3, BrokenLinks: 0, CreatedAt: base.Add(2 * time.Hour)})
+	createResult(t, db, urlEntry.ID, models.CrawlResult{ResponseStatus: 200, InternalLinks: 5, ExternalLinks: 2, BrokenLinks: 1, CreatedAt: base})
+	createResult(t, db, urlEntry.ID, models.CrawlResult{ResponseStatus: 200, InternalLinks: 6, ExternalLinks: 2, BrokenLinks: 4, CreatedAt: base.Add(time.Hour)})
+	// Neither a page reached through crawl depth nor a failed crawl is a point
+	createResult(t, db, urlEntry.ID, models.CrawlResult{ResponseStatus: 200, InternalLinks: 50, ParentID: &third.ID, CreatedAt: base.Add(3 * time.Hour)})
+	createResult(t, db, urlEntry.ID, models.CrawlResult{ResponseStatus: 500, CreatedAt: base.Add(4 * time.Hour)})
+
+	trend := func(userID uint, query string) ([]TrendPoint, int) {
+		t.Helper()
+		router := testRouter(userID)
+		router.GET("/urls/:id/trend", NewURLHandler(db).GetURLTrend)
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d/trend%s", urlEntry.ID, query), nil)
+		if w.Code != http.StatusOK {
+			return nil, w.Code
+		}
+		var body struct {
+			Data []TrendPoint `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		return body.Data, w.Code
+	}
+
+	want := []TrendPoint{
+		{CrawledAt: base, InternalLinks: 5, ExternalLinks: 2, BrokenLinks: 1},
+		{CrawledAt: base.Add(time.Hour), InternalLinks: 6, ExternalLinks: 2, BrokenLinks: 4},
+		{CrawledAt: base.Add(2 * time.Hour), InternalLinks: 7, ExternalLinks: 3, BrokenLinks: 0},
+	}
+	equal := func(a, b TrendPoint) bool {
+		return a.CrawledAt.Equal(b.CrawledAt) && a.InternalLinks == b.InternalLinks &&
+			a.ExternalLinks == b.ExternalLinks && a.BrokenLinks == b.BrokenLinks
+	}
+
+	if got, _ := trend(user.ID, ""); !slices.EqualFunc(got, want, equal) {
+		t.Errorf("trend = %+v, want %+v", got, want)
+	}
+	if got, _ := trend(user.ID, "?limit=2"); !slices.EqualFunc(got, want[1:], equal) {
+		t.Errorf("trend with limit=2 = %+v, want the 2 most recent points %+v", got, want[1:])
+	}
+	if _, code := trend(user.ID, "?limit=0"); code != http.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", code)
+	}
+
+	other := createUser(t, db, "stranger")
+	if _, code := trend(other.ID, ""); code != http.StatusNotFound {
+		t.Errorf("another user's URL: status = %d, want 404", code)
+	}
+}
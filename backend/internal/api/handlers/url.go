@@ -1,36 +1,94 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/database"
 	"skyell-backend/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type URLHandler struct {
-	db *gorm.DB
+	db             *gorm.DB
+	fullTextSearch bool
+	pagination     config.PaginationConfig
+
+	// statuses is told when the user's URLs change, so polls don't serve a stale snapshot
+	statuses StatusInvalidator
+}
+
+// StatusInvalidator drops a user's cached URL statuses; *crawler.CrawlerService is one
+type StatusInvalidator interface {
+	InvalidateStatus(userID uint)
 }
 
 func NewURLHandler(db *gorm.DB) *URLHandler {
-	return &URLHandler{db: db}
+	// An invalid configuration is rejected at startup; the returned values are still usable
+	pagination, _ := config.Pagination()
+
+	return &URLHandler{
+		db:             db,
+		fullTextSearch: database.HasFullTextSearch(db),
+		pagination:     pagination,
+	}
 }
 
 type CreateURLRequest struct {
-	URL string `json:"url" binding:"required"`
+	URL            string          `json:"url" binding:"required"`
+	Auth           *URLAuthRequest `json:"auth"`
+	AcceptLanguage string          `json:"accept_language" binding:"max=100"`
+	CrawlConfigRequest
 }
 
 type UpdateURLRequest struct {
-	URL string `json:"url" binding:"required"`
+	URL            string          `json:"url" binding:"required"`
+	Auth           *URLAuthRequest `json:"auth"`                                        // Omit to keep existing credentials
+	AcceptLanguage *string         `json:"accept_language" binding:"omitempty,max=100"` // Omit to keep, "" to clear
+
+	// Omitted crawl overrides are kept
+	CrawlConfigRequest
+}
+
+// CrawlConfigRequest holds the per-URL crawl overrides; omitted fields fall back to the
+// global configuration
+type CrawlConfigRequest struct {
+	MaxLinksToCheck     *int  `json:"max_links_to_check" binding:"omitempty,min=0,max=1000"`
+	FollowRedirects     *bool `json:"follow_redirects"`
+	CrawlTimeoutSeconds *int  `json:"crawl_timeout_seconds" binding:"omitempty,min=1,max=300"`
+	CrawlDepth          *int  `json:"crawl_depth" binding:"omitempty,min=0,max=5"`
+}
+
+// apply copies the provided overrides onto the URL
+func (r CrawlConfigRequest) apply(url *models.URL) {
+	if r.MaxLinksToCheck != nil {
+		url.MaxLinksToCheck = r.MaxLinksToCheck
+	}
+	if r.FollowRedirects != nil {
+		url.FollowRedirects = r.FollowRedirects
+	}
+	if r.CrawlTimeoutSeconds != nil {
+		url.CrawlTimeoutSeconds = r.CrawlTimeoutSeconds
+	}
+	if r.CrawlDepth != nil {
+		url.CrawlDepth = r.CrawlDepth
+	}
 }
 
 type URLResponse struct {
 	*models.URL
 	CrawlResults []models.CrawlResult `json:"crawl_results,omitempty"`
+	LatestResult *LatestResultSummary `json:"latest_result,omitempty"` // Only with include=latest_result
+	LastCrawled  *time.Time           `json:"last_crawled_at"`         // Null when never crawled
 }
 
 type PaginationResponse struct {
@@ -43,38 +101,41 @@ type PaginationResponse struct {
 type URLListResponse struct {
 	Data       []URLResponse      `json:"data"`
 	Pagination PaginationResponse `json:"pagination"`
+	Links      *PaginationLinks   `json:"_links,omitempty"`
 }
 
 // GetURLs returns a paginated list of URLs for the authenticated user
 func (h *URLHandler) GetURLs(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
 	search := c.Query("search")
 	status := c.Query("status")
-	sortBy := c.DefaultQuery("sort_by", "created_at")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
+	sortBy, sortOrder, ok := parseSort(c, urlSortColumns, "created_at")
+	if !ok {
+		return
+	}
 
 	if page < 1 {
 		page = 1
 	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	limit = h.pagination.Limit(limit, h.pagination.DefaultPageSize)
+	if !checkPageDepth(c, h.pagination, page, limit, false) {
+		return
 	}
 
 	offset := (page - 1) * limit
 
+	db, cancel := queryDB(c, h.db)
+	defer cancel()
+
 	// Build query
-	query := h.db.Where("user_id = ?", userID)
+	query := db.Where("user_id = ?", userID)
 
 	// Apply filters
 	if search != "" {
@@ -83,73 +144,117 @@ func (h *URLHandler) GetURLs(c *gin.Context) {
 	if status != "" && status != "all" {
 		query = query.Where("status = ?", status)
 	}
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("id IN (?)", db.Table("url_tags").
+			Select("url_tags.url_id").
+			Joins("JOIN tags ON tags.id = url_tags.tag_id").
+			Where("tags.name = ? AND tags.user_id = ?", tag, userID))
+	}
+	if hasResults := c.Query("has_results"); hasResults != "" {
+		value, err := strconv.ParseBool(hasResults)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid has_results: must be true or false",
+			})
+			return
+		}
+
+		// Only successful crawls count; error pages are recorded with their status code
+		completed := db.Table("crawl_results").
+			Select("1").
+			Where("crawl_results.url_id = urls.id AND crawl_results.response_status < ? AND crawl_results.deleted_at IS NULL", 400)
+		if value {
+			query = query.Where("EXISTS (?)", completed)
+		} else {
+			query = query.Where("NOT EXISTS (?)", completed)
+		}
+	}
 
-	// Apply sorting
+	// Apply sorting, with the ID as a tiebreaker so pages are stable
 	orderClause := fmt.Sprintf("%s %s", sortBy, sortOrder)
-	query = query.Order(orderClause)
+	query = query.Order(orderClause).Order("id ASC")
 
 	// Get total count
 	var total int64
 	if err := query.Model(&models.URL{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to count URLs",
-			"error":   err.Error(),
-		})
+		respondQueryError(c, "Failed to count URLs", err)
 		return
 	}
 
 	// Get URLs with pagination
 	var urls []models.URL
-	if err := query.Offset(offset).Limit(limit).Find(&urls).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve URLs",
-			"error":   err.Error(),
-		})
+	if err := query.Preload("Tags").Offset(offset).Limit(limit).Find(&urls).Error; err != nil {
+		respondQueryError(c, "Failed to retrieve URLs", err)
 		return
 	}
 
+	urlIDs := make([]uint, len(urls))
+	for i, url := range urls {
+		urlIDs[i] = url.ID
+	}
+	lastCrawled, err := lastCrawledAt(db, urlIDs)
+	if err != nil {
+		respondQueryError(c, "Failed to retrieve crawl times", err)
+		return
+	}
+
+	// Optionally attach each URL's most recent crawl result, loaded in one query
+	var latest map[uint]*LatestResultSummary
+	if c.Query("include") == "latest_result" {
+		if latest, err = h.latestResults(db, urlIDs); err != nil {
+			respondQueryError(c, "Failed to retrieve latest results", err)
+			return
+		}
+	}
+
 	// Convert to response format
 	var urlResponses []URLResponse
 	for _, url := range urls {
-		urlResponses = append(urlResponses, URLResponse{URL: &url})
+		urlResponses = append(urlResponses, URLResponse{URL: &url, LatestResult: latest[url.ID], LastCrawled: lastCrawled[url.ID]})
 	}
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+	setPaginationHeaders(c, pagination)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": URLListResponse{
-			Data: urlResponses,
-			Pagination: PaginationResponse{
-				Page:       page,
-				Limit:      limit,
-				Total:      total,
-				TotalPages: totalPages,
-			},
+			Data:       urlResponses,
+			Pagination: pagination,
+			Links:      paginationLinks(c, pagination),
 		},
 	})
 }
 
+// UseStatusCache makes URL changes invalidate the status cache polls are served from
+func (h *URLHandler) UseStatusCache(statuses StatusInvalidator) {
+	h.statuses = statuses
+}
+
+// invalidateStatus drops the user's cached statuses after their URLs changed
+func (h *URLHandler) invalidateStatus(userID uint) {
+	if h.statuses != nil {
+		h.statuses.InvalidateStatus(userID)
+	}
+}
+
 // CreateURL adds a new URL for the authenticated user
 func (h *URLHandler) CreateURL(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
 	var req CreateURLRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -161,6 +266,26 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 		})
 		return
 	}
+	if !isAllowedDomain(req.URL) {
+		respondDomainNotAllowed(c)
+		return
+	}
+
+	// Enforce the per-user URL quota
+	if maxURLs := config.GetEnvInt("MAX_URLS_PER_USER", 0); maxURLs > 0 {
+		var urlCount int64
+		if err := h.db.Model(&models.URL{}).Where("user_id = ?", userID).Count(&urlCount).Error; err != nil {
+			respondInternalError(c, "Failed to check URL quota", err)
+			return
+		}
+		if urlCount >= int64(maxURLs) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("URL limit reached: you can register at most %d URLs", maxURLs),
+			})
+			return
+		}
+	}
 
 	// Check if URL already exists for this user
 	var existingURL models.URL
@@ -174,19 +299,29 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 
 	// Create new URL entry
 	newURL := models.URL{
-		URL:    req.URL,
-		UserID: userID.(uint),
-		Status: models.StatusQueued,
+		URL:            req.URL,
+		UserID:         userID,
+		Status:         models.StatusQueued,
+		AcceptLanguage: req.AcceptLanguage,
+	}
+	req.CrawlConfigRequest.apply(&newURL)
+
+	if req.Auth != nil {
+		if err := applyURLAuth(&newURL, req.Auth); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid auth configuration",
+				"error":   err.Error(),
+			})
+			return
+		}
 	}
 
 	if err := h.db.Create(&newURL).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to create URL",
-			"error":   err.Error(),
-		})
+		respondInternalError(c, "Failed to create URL", err)
 		return
 	}
+	h.invalidateStatus(userID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -197,12 +332,8 @@ func (h *URLHandler) CreateURL(c *gin.Context) {
 
 // GetURL returns a specific URL by ID
 func (h *URLHandler) GetURL(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -215,37 +346,42 @@ func (h *URLHandler) GetURL(c *gin.Context) {
 		return
 	}
 
-	var url models.URL
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).Preload("CrawlResults").First(&url).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "URL not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve URL",
-			"error":   err.Error(),
-		})
+	url, err := findOwned[models.URL](h.db.Preload("CrawlResults").Preload("Tags"), uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to retrieve URL")
+		return
+	}
+
+	// Attaching tags or adding results doesn't touch the URL's updated_at, so include them too
+	etagParts := []any{url.ID, url.UpdatedAt.UnixNano()}
+	for _, result := range url.CrawlResults {
+		etagParts = append(etagParts, result.ID, result.UpdatedAt.UnixNano())
+	}
+	for _, tag := range url.Tags {
+		etagParts = append(etagParts, tag.ID, tag.Name)
+	}
+	if notModified(c, computeETag(etagParts...)) {
 		return
 	}
 
+	// The URL's own results are preloaded, so the last crawl time needs no extra query
+	var lastCrawled *time.Time
+	for _, result := range url.CrawlResults {
+		if result.ParentID == nil && (lastCrawled == nil || result.CreatedAt.After(*lastCrawled)) {
+			lastCrawled = &result.CreatedAt
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    URLResponse{URL: &url, CrawlResults: url.CrawlResults},
+		"data":    URLResponse{URL: &url, CrawlResults: url.CrawlResults, LastCrawled: lastCrawled},
 	})
 }
 
 // UpdateURL updates an existing URL
 func (h *URLHandler) UpdateURL(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -260,11 +396,7 @@ func (h *URLHandler) UpdateURL(c *gin.Context) {
 
 	var req UpdateURLRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -276,53 +408,60 @@ func (h *URLHandler) UpdateURL(c *gin.Context) {
 		})
 		return
 	}
+	if !isAllowedDomain(req.URL) {
+		respondDomainNotAllowed(c)
+		return
+	}
 
 	// Find and update URL
-	var url models.URL
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).First(&url).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "URL not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to find URL",
-			"error":   err.Error(),
-		})
+	url, err := findOwned[models.URL](h.db, uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to find URL")
 		return
 	}
 
 	// Update URL
 	url.URL = req.URL
 	url.Status = models.StatusQueued // Reset status when URL is updated
+	if req.AcceptLanguage != nil {
+		url.AcceptLanguage = *req.AcceptLanguage
+	}
+	req.CrawlConfigRequest.apply(&url)
+
+	if req.Auth != nil {
+		if err := applyURLAuth(&url, req.Auth); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid auth configuration",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
 
 	if err := h.db.Save(&url).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to update URL",
-			"error":   err.Error(),
-		})
+		respondInternalError(c, "Failed to update URL", err)
+		return
+	}
+	h.invalidateStatus(userID)
+
+	lastCrawled, err := lastCrawledAt(h.db, []uint{url.ID})
+	if err != nil {
+		respondInternalError(c, "Failed to retrieve crawl time", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "URL updated successfully",
-		"data":    URLResponse{URL: &url},
+		"data":    URLResponse{URL: &url, LastCrawled: lastCrawled[url.ID]},
 	})
 }
 
 // DeleteURL deletes a specific URL
 func (h *URLHandler) DeleteURL(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -335,15 +474,16 @@ func (h *URLHandler) DeleteURL(c *gin.Context) {
 		return
 	}
 
-	// Delete URL (soft delete due to GORM)
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.URL{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to delete URL",
-			"error":   err.Error(),
-		})
+	// Soft-delete the URL with its results and links, and clean up its tag associations
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		_, err := softDeleteURLs(tx, userID, []uint{uint(id)})
+		return err
+	})
+	if err != nil {
+		respondInternalError(c, "Failed to delete URL", err)
 		return
 	}
+	h.invalidateStatus(userID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -353,12 +493,8 @@ func (h *URLHandler) DeleteURL(c *gin.Context) {
 
 // BulkDeleteURLs deletes multiple URLs
 func (h *URLHandler) BulkDeleteURLs(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -367,11 +503,7 @@ func (h *URLHandler) BulkDeleteURLs(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "Invalid request data",
-			"error":   err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -383,58 +515,144 @@ func (h *URLHandler) BulkDeleteURLs(c *gin.Context) {
 		return
 	}
 
-	// Delete URLs
-	result := h.db.Where("id IN ? AND user_id = ?", req.IDs, userID).Delete(&models.URL{})
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	// Soft-delete the URLs with their results and links, and clean up their tag associations
+	var deleted int64
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		deleted, err = softDeleteURLs(tx, userID, req.IDs)
+		return err
+	})
+	if err != nil {
+		respondInternalError(c, "Failed to delete URLs", err)
+		return
+	}
+	h.invalidateStatus(userID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Successfully deleted %d URL(s)", deleted),
+	})
+}
+
+// RestoreURL restores a soft-deleted URL along with the results deleted with it
+func (h *URLHandler) RestoreURL(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "Failed to delete URLs",
-			"error":   result.Error.Error(),
+			"message": "Invalid URL ID",
 		})
 		return
 	}
 
+	// Only URLs that are actually in the trash can be restored
+	var url models.URL
+	if err := h.db.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).First(&url).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Deleted URL not found",
+			})
+			return
+		}
+		respondInternalError(c, "Failed to find URL", err)
+		return
+	}
+
+	// Bring back the results and links that were deleted with the URL
+	if err := h.db.Transaction(func(tx *gorm.DB) error { return restoreURL(tx, &url) }); err != nil {
+		respondInternalError(c, "Failed to restore URL", err)
+		return
+	}
+	h.invalidateStatus(userID)
+
+	lastCrawled, err := lastCrawledAt(h.db, []uint{url.ID})
+	if err != nil {
+		respondInternalError(c, "Failed to retrieve crawl time", err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": fmt.Sprintf("Successfully deleted %d URL(s)", result.RowsAffected),
+		"message": "URL restored successfully",
+		"data":    URLResponse{URL: &url, LastCrawled: lastCrawled[url.ID]},
+	})
+}
+
+// GetTrashedURLs returns the user's soft-deleted URLs
+func (h *URLHandler) GetTrashedURLs(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var urls []models.URL
+	if err := h.db.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at desc").
+		Find(&urls).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve deleted URLs", err)
+		return
+	}
+
+	var urlResponses []URLResponse
+	for i := range urls {
+		urlResponses = append(urlResponses, URLResponse{URL: &urls[i]})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    urlResponses,
 	})
 }
 
 // GetURLsStatus returns the current status of all URLs for real-time updates
 func (h *URLHandler) GetURLsStatus(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
+	// Taken before querying so rows updated meanwhile are returned again on the next poll
+	serverTime := time.Now().UTC()
+
+	query := h.db.Where("user_id = ?", userID).Select("id, url, status, error_message, updated_at")
+
+	// Only return URLs changed after updated_since, so polling clients can fetch deltas
+	if updatedSince := c.Query("updated_since"); updatedSince != "" {
+		since, err := time.Parse(time.RFC3339Nano, updatedSince)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid updated_since: must be an RFC 3339 timestamp",
+			})
+			return
+		}
+		query = query.Where("updated_at > ?", since)
+	}
+
 	var urls []models.URL
-	if err := h.db.Where("user_id = ?", userID).Select("id, url, status, error_message, updated_at").Find(&urls).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve URL status",
-			"error":   err.Error(),
-		})
+	if err := query.Find(&urls).Error; err != nil {
+		respondInternalError(c, "Failed to retrieve URL status", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    urls,
+		"success":     true,
+		"data":        urls,
+		"server_time": serverTime.Format(time.RFC3339Nano),
 	})
 }
 
 // GetURLStatus returns the status of a specific URL
 func (h *URLHandler) GetURLStatus(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -447,20 +665,9 @@ func (h *URLHandler) GetURLStatus(c *gin.Context) {
 		return
 	}
 
-	var url models.URL
-	if err := h.db.Where("id = ? AND user_id = ?", id, userID).Select("id, url, status, error_message, updated_at").First(&url).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "URL not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve URL status",
-			"error":   err.Error(),
-		})
+	url, err := findOwned[models.URL](h.db.Select("id, url, status, error_message, updated_at"), uint(id), userID)
+	if err != nil {
+		respondFindError(c, err, "URL not found", "Failed to retrieve URL status")
 		return
 	}
 
@@ -478,27 +685,102 @@ func isValidURL(str string) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
+// isAllowedDomain reports whether the URL's host may be crawled under CRAWL_DOMAIN_ALLOWLIST
+func isAllowedDomain(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && config.CrawlDomainAllowed(u.Hostname())
+}
+
+// respondDomainNotAllowed rejects a URL whose host isn't on CRAWL_DOMAIN_ALLOWLIST
+func respondDomainNotAllowed(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"message": "Crawling this domain is not allowed",
+	})
+}
+
+// encodeResultCursor builds an opaque cursor from the last-seen result's sort key and id
+func encodeResultCursor(createdAt time.Time, id uint) string {
+	raw := fmt.Sprintf("%s|%d", createdAt.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeResultCursor parses a cursor produced by encodeResultCursor
+func decodeResultCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, fmt.Errorf("malformed cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return createdAt, uint(id), nil
+}
+
 // Results Dashboard API endpoints
 
 type CrawlResultResponse struct {
-	ID              uint           `json:"id"`
-	URL             string         `json:"url"`
-	Title           string         `json:"title"`
-	HTMLVersion     string         `json:"html_version"`
-	HasLoginForm    bool           `json:"has_login_form"`
-	H1Count         int            `json:"h1_count"`
-	H2Count         int            `json:"h2_count"`
-	H3Count         int            `json:"h3_count"`
-	H4Count         int            `json:"h4_count"`
-	H5Count         int            `json:"h5_count"`
-	H6Count         int            `json:"h6_count"`
-	InternalLinks   int            `json:"internal_links"`
-	ExternalLinks   int            `json:"external_links"`
-	BrokenLinks     int            `json:"broken_links"`
-	Status          string         `json:"status"`
-	CrawledAt       string         `json:"crawled_at"`
-	ChartData       *LinkChartData `json:"chart_data,omitempty"`
-	BrokenLinksList []models.Link  `json:"broken_links_list,omitempty"`
+	ID                uint           `json:"id"`
+	URL               string         `json:"url"`
+	ParentID          *uint          `json:"parent_id,omitempty"` // Set for pages reached through crawl depth
+	Depth             int            `json:"depth,omitempty"`
+	Title             string         `json:"title"`
+	HTMLVersion       string         `json:"html_version"`
+	HasLoginForm      bool           `json:"has_login_form"`
+	WordCount         int            `json:"word_count"`
+	IsThinContent     bool           `json:"is_thin_content"`
+	ScriptCount       *int           `json:"script_count,omitempty"`
+	StylesheetCount   *int           `json:"stylesheet_count,omitempty"`
+	InlineScriptCount *int           `json:"inline_script_count,omitempty"`
+	HasSignupForm     *bool          `json:"has_signup_form,omitempty"`
+	HasSearchForm     *bool          `json:"has_search_form,omitempty"`
+	HasTitle          *bool          `json:"has_title,omitempty"`
+	HasFavicon        *bool          `json:"has_favicon,omitempty"`
+	HasViewportMeta   *bool          `json:"has_viewport_meta,omitempty"`
+	ResponseStatus    int            `json:"response_status"`
+	RedirectedOffHost bool           `json:"redirected_off_host,omitempty"`
+	CanonicalURL      string         `json:"canonical_url,omitempty"`
+	CanonicalMismatch bool           `json:"canonical_mismatch,omitempty"`
+	OGTitle           string         `json:"og_title,omitempty"`
+	OGDescription     string         `json:"og_description,omitempty"`
+	OGImage           string         `json:"og_image,omitempty"`
+	ContentLanguage   string         `json:"content_language,omitempty"`
+	Charset           string         `json:"charset,omitempty"`
+	CertValid         *bool          `json:"certificate_valid,omitempty"`
+	CertExpiresAt     *time.Time     `json:"certificate_expires_at,omitempty"`
+	ContentHash       string         `json:"content_hash,omitempty"`
+	Changed           *bool          `json:"changed,omitempty"`
+	H1Count           int            `json:"h1_count"`
+	H2Count           int            `json:"h2_count"`
+	H3Count           int            `json:"h3_count"`
+	H4Count           int            `json:"h4_count"`
+	H5Count           int            `json:"h5_count"`
+	H6Count           int            `json:"h6_count"`
+	TagCounts         map[string]int `json:"tag_counts,omitempty"`
+	InternalLinks     int            `json:"internal_links"`
+	ExternalLinks     int            `json:"external_links"`
+	BrokenLinks       int            `json:"broken_links"`
+	LinksChecked      int            `json:"links_checked"`
+	LinksTotal        int            `json:"links_total"`
+	IsPartial         bool           `json:"is_partial,omitempty"`
+	Status            string         `json:"status"`
+	CrawledAt         time.Time      `json:"crawled_at"`
+	ChartData         *LinkChartData `json:"chart_data,omitempty"`
+	BrokenLinksList   []models.Link  `json:"broken_links_list,omitempty"`
+	Warnings          []string       `json:"warnings,omitempty"`
 }
 
 type LinkChartData struct {
@@ -510,45 +792,118 @@ type LinkChartData struct {
 
 type CrawlResultsListResponse struct {
 	Data       []CrawlResultResponse `json:"data"`
-	Pagination PaginationResponse    `json:"pagination"`
+	Pagination *PaginationResponse   `json:"pagination,omitempty"`
+	Links      *PaginationLinks      `json:"_links,omitempty"` // Page-based pagination only
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// newCrawlResultResponse converts a crawl result into its list response format
+func newCrawlResultResponse(result models.CrawlResult, crawlURL string) CrawlResultResponse {
+	if result.PageURL != "" {
+		crawlURL = result.PageURL
+	}
+
+	return CrawlResultResponse{
+		ID:             result.ID,
+		URL:            crawlURL,
+		ParentID:       result.ParentID,
+		Depth:          result.Depth,
+		Title:          result.Title,
+		HTMLVersion:    result.HTMLVersion,
+		HasLoginForm:   result.HasLoginForm,
+		WordCount:      result.WordCount,
+		IsThinContent:  result.IsThinContent,
+		ResponseStatus: result.ResponseStatus,
+		H1Count:        result.H1Count,
+		H2Count:        result.H2Count,
+		H3Count:        result.H3Count,
+		H4Count:        result.H4Count,
+		H5Count:        result.H5Count,
+		H6Count:        result.H6Count,
+		InternalLinks:  result.InternalLinks,
+		ExternalLinks:  result.ExternalLinks,
+		BrokenLinks:    result.BrokenLinks,
+		LinksChecked:   result.LinksChecked,
+		LinksTotal:     result.LinksTotal,
+		IsPartial:      result.IsPartial,
+		Status:         crawlResultStatus(result.ResponseStatus),
+		CrawledAt:      result.CreatedAt.UTC(),
+	}
+}
+
+// crawlResultStatus reports "error" for results recorded from an HTTP error page
+func crawlResultStatus(responseStatus int) string {
+	if responseStatus >= 400 {
+		return string(models.StatusError)
+	}
+	return string(models.StatusCompleted)
 }
 
 // GetResults returns paginated, sortable, filterable crawl results
 func (h *URLHandler) GetResults(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
 	search := c.Query("search")
 	status := c.Query("status")
-	sortBy := c.DefaultQuery("sort_by", "crawled_at")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
+	sortBy, sortOrder, ok := parseSort(c, resultSortColumns, "crawled_at")
+	if !ok {
+		return
+	}
 
 	if page < 1 {
 		page = 1
 	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
+	limit = h.pagination.Limit(limit, h.pagination.DefaultPageSize)
 
 	offset := (page - 1) * limit
 
+	db, cancel := queryDB(c, h.db)
+	defer cancel()
+
 	// Build query for crawl results (only show results where crawl was completed)
-	query := h.db.Table("crawl_results").
+	query := db.Table("crawl_results").
 		Joins("JOIN urls ON crawl_results.url_id = urls.id").
-		Where("urls.user_id = ?", userID)
+		Where("urls.user_id = ? AND urls.deleted_at IS NULL AND crawl_results.deleted_at IS NULL", userID)
 
 	// Apply filters
+	searchMode := c.DefaultQuery("search_mode", "contains")
+	if searchMode != "prefix" && searchMode != "contains" && searchMode != "fulltext" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid search_mode: must be prefix, contains or fulltext",
+		})
+		return
+	}
+
+	// Fall back to contains matching when full-text search isn't available
+	if searchMode == "fulltext" && !h.fullTextSearch {
+		searchMode = "contains"
+	}
+
+	var relevanceOrder *clause.Expr
 	if search != "" {
-		query = query.Where("urls.url LIKE ? OR crawl_results.title LIKE ?", "%"+search+"%", "%"+search+"%")
+		switch searchMode {
+		case "prefix":
+			query = query.Where("urls.url LIKE ? OR crawl_results.title LIKE ?", search+"%", search+"%")
+		case "fulltext":
+			var matchSQL string
+			if db.Dialector.Name() == "postgres" {
+				matchSQL = "to_tsvector('simple', coalesce(crawl_results.title, '')) @@ plainto_tsquery('simple', ?)"
+				relevanceOrder = &clause.Expr{SQL: "ts_rank(to_tsvector('simple', coalesce(crawl_results.title, '')), plainto_tsquery('simple', ?)) DESC", Vars: []interface{}{search}}
+			} else {
+				matchSQL = "MATCH (crawl_results.title) AGAINST (? IN NATURAL LANGUAGE MODE)"
+				relevanceOrder = &clause.Expr{SQL: "MATCH (crawl_results.title) AGAINST (? IN NATURAL LANGUAGE MODE) DESC", Vars: []interface{}{search}}
+			}
+			query = query.Where(matchSQL, search)
+		default:
+			query = query.Where("urls.url LIKE ? OR crawl_results.title LIKE ?", "%"+search+"%", "%"+search+"%")
+		}
 	}
 
 	if status != "" && status != "all" {
@@ -557,14 +912,133 @@ func (h *URLHandler) GetResults(c *gin.Context) {
 		}
 	}
 
+	// Apply numeric link threshold filters
+	linkFilters := []struct {
+		param  string
+		clause string
+	}{
+		{"min_broken_links", "crawl_results.broken_links >= ?"},
+		{"max_broken_links", "crawl_results.broken_links <= ?"},
+		{"min_internal_links", "crawl_results.internal_links >= ?"},
+		{"max_internal_links", "crawl_results.internal_links <= ?"},
+		{"min_external_links", "crawl_results.external_links >= ?"},
+		{"max_external_links", "crawl_results.external_links <= ?"},
+	}
+	for _, filter := range linkFilters {
+		value := c.Query(filter.param)
+		if value == "" {
+			continue
+		}
+		threshold, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("Invalid %s: must be an integer", filter.param),
+			})
+			return
+		}
+		query = query.Where(filter.clause, threshold)
+	}
+
+	if hasLoginForm := c.Query("has_login_form"); hasLoginForm != "" {
+		value, err := strconv.ParseBool(hasLoginForm)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid has_login_form: must be true or false",
+			})
+			return
+		}
+		query = query.Where("crawl_results.has_login_form = ?", value)
+	}
+
+	if thinContent := c.Query("is_thin_content"); thinContent != "" {
+		value, err := strconv.ParseBool(thinContent)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid is_thin_content: must be true or false",
+			})
+			return
+		}
+		query = query.Where("crawl_results.is_thin_content = ?", value)
+	}
+
+	// html_version matches exactly; non_html5 matches every version other than HTML5
+	if htmlVersion := c.Query("html_version"); htmlVersion == "non_html5" {
+		query = query.Where("crawl_results.html_version <> ?", "HTML5")
+	} else if htmlVersion != "" {
+		query = query.Where("crawl_results.html_version = ?", htmlVersion)
+	}
+
+	// Cursor (keyset) pagination is used when a cursor param is present, even if empty
+	if cursor, useCursor := c.GetQuery("cursor"); useCursor {
+		if sortBy != "crawled_at" || !strings.EqualFold(sortOrder, "desc") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Cursor pagination is only supported with sort_by=crawled_at and sort_order=desc",
+			})
+			return
+		}
+
+		if cursor != "" {
+			cursorTime, cursorID, err := decodeResultCursor(cursor)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Invalid cursor",
+				})
+				return
+			}
+			query = query.Where("crawl_results.created_at < ? OR (crawl_results.created_at = ? AND crawl_results.id < ?)",
+				cursorTime, cursorTime, cursorID)
+		}
+
+		// Fetch one extra row to know whether another page exists
+		var results []struct {
+			models.CrawlResult
+			CrawlURL string `json:"crawl_url"`
+		}
+
+		if err := query.
+			Select("crawl_results.*, urls.url as crawl_url").
+			Order("crawl_results.created_at desc, crawl_results.id desc").
+			Limit(limit + 1).
+			Find(&results).Error; err != nil {
+			respondQueryError(c, "Failed to retrieve results", err)
+			return
+		}
+
+		var nextCursor string
+		if len(results) > limit {
+			results = results[:limit]
+			last := results[len(results)-1]
+			nextCursor = encodeResultCursor(last.CreatedAt, last.ID)
+		}
+
+		var crawlResponses []CrawlResultResponse
+		for _, result := range results {
+			crawlResponses = append(crawlResponses, newCrawlResultResponse(result.CrawlResult, result.CrawlURL))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": CrawlResultsListResponse{
+				Data:       crawlResponses,
+				NextCursor: nextCursor,
+			},
+		})
+		return
+	}
+
+	if !checkPageDepth(c, h.pagination, page, limit, true) {
+		return
+	}
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to count results",
-			"error":   err.Error(),
-		})
+		respondQueryError(c, "Failed to count results", err)
 		return
 	}
 
@@ -577,9 +1051,7 @@ func (h *URLHandler) GetResults(c *gin.Context) {
 		orderClause = fmt.Sprintf("crawl_results.title %s", sortOrder)
 	case "links":
 		orderClause = fmt.Sprintf("(crawl_results.internal_links + crawl_results.external_links) %s", sortOrder)
-	case "crawled_at":
-		orderClause = fmt.Sprintf("crawl_results.created_at %s", sortOrder)
-	default:
+	default: // crawled_at, and relevance when there's no full-text search to rank by
 		orderClause = fmt.Sprintf("crawl_results.created_at %s", sortOrder)
 	}
 
@@ -589,67 +1061,54 @@ func (h *URLHandler) GetResults(c *gin.Context) {
 		CrawlURL string `json:"crawl_url"`
 	}
 
+	// Full-text searches are ranked by relevance unless a sort column was requested.
+	// The ID breaks ties so pages are stable; an order expression replaces any order
+	// columns, so the tiebreaker is appended to it directly.
+	if relevanceOrder != nil && (c.Query("sort_by") == "" || sortBy == "relevance") {
+		relevanceOrder.SQL += ", crawl_results.id ASC"
+		query = query.Clauses(clause.OrderBy{Expression: *relevanceOrder})
+	} else {
+		query = query.Order(orderClause).Order("crawl_results.id ASC")
+	}
+
 	if err := query.
 		Select("crawl_results.*, urls.url as crawl_url").
-		Order(orderClause).
 		Offset(offset).
 		Limit(limit).
 		Find(&results).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve results",
-			"error":   err.Error(),
-		})
+		respondQueryError(c, "Failed to retrieve results", err)
 		return
 	}
 
 	// Convert to response format
 	var crawlResponses []CrawlResultResponse
 	for _, result := range results {
-		crawlResponses = append(crawlResponses, CrawlResultResponse{
-			ID:            result.ID,
-			URL:           result.CrawlURL,
-			Title:         result.Title,
-			HTMLVersion:   result.HTMLVersion,
-			HasLoginForm:  result.HasLoginForm,
-			H1Count:       result.H1Count,
-			H2Count:       result.H2Count,
-			H3Count:       result.H3Count,
-			H4Count:       result.H4Count,
-			H5Count:       result.H5Count,
-			H6Count:       result.H6Count,
-			InternalLinks: result.InternalLinks,
-			ExternalLinks: result.ExternalLinks,
-			BrokenLinks:   result.BrokenLinks,
-			Status:        "completed",
-			CrawledAt:     result.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+		crawlResponses = append(crawlResponses, newCrawlResultResponse(result.CrawlResult, result.CrawlURL))
 	}
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+	setPaginationHeaders(c, pagination)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": CrawlResultsListResponse{
-			Data: crawlResponses,
-			Pagination: PaginationResponse{
-				Page:       page,
-				Limit:      limit,
-				Total:      total,
-				TotalPages: totalPages,
-			},
+			Data:       crawlResponses,
+			Pagination: &pagination,
+			Links:      paginationLinks(c, pagination),
 		},
 	})
 }
 
 // GetResultDetail returns detailed crawl result with chart data
 func (h *URLHandler) GetResultDetail(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -670,21 +1129,18 @@ func (h *URLHandler) GetResultDetail(c *gin.Context) {
 
 	if err := h.db.Table("crawl_results").
 		Joins("JOIN urls ON crawl_results.url_id = urls.id").
-		Where("crawl_results.id = ? AND urls.user_id = ?", id, userID).
+		Where("crawl_results.id = ? AND urls.user_id = ? AND crawl_results.deleted_at IS NULL", id, userID).
 		Select("crawl_results.*, urls.url as crawl_url").
 		First(&result).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "Result not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve result",
-			"error":   err.Error(),
-		})
+		respondFindError(c, err, "Result not found", "Failed to retrieve result")
+		return
+	}
+	if !c.GetBool("demo") {
+		recordResultView(h.db, userID, result.ID)
+	}
+
+	// Results don't change after a crawl, so polling clients can skip the download
+	if notModified(c, computeETag(result.ID, result.UpdatedAt.UnixNano(), result.CrawlURL)) {
 		return
 	}
 
@@ -700,26 +1156,61 @@ func (h *URLHandler) GetResultDetail(c *gin.Context) {
 		TotalLinks:    result.InternalLinks + result.ExternalLinks,
 	}
 
+	// Pages reached through crawl depth report their own URL
+	if result.PageURL != "" {
+		result.CrawlURL = result.PageURL
+	}
+
 	// Create response
 	response := CrawlResultResponse{
-		ID:              result.ID,
-		URL:             result.CrawlURL,
-		Title:           result.Title,
-		HTMLVersion:     result.HTMLVersion,
-		HasLoginForm:    result.HasLoginForm,
-		H1Count:         result.H1Count,
-		H2Count:         result.H2Count,
-		H3Count:         result.H3Count,
-		H4Count:         result.H4Count,
-		H5Count:         result.H5Count,
-		H6Count:         result.H6Count,
-		InternalLinks:   result.InternalLinks,
-		ExternalLinks:   result.ExternalLinks,
-		BrokenLinks:     result.BrokenLinks,
-		Status:          "completed",
-		CrawledAt:       result.CreatedAt.Format("2006-01-02 15:04:05"),
-		ChartData:       chartData,
-		BrokenLinksList: brokenLinks,
+		ID:                result.ID,
+		URL:               result.CrawlURL,
+		ParentID:          result.ParentID,
+		Depth:             result.Depth,
+		Title:             result.Title,
+		HTMLVersion:       result.HTMLVersion,
+		HasLoginForm:      result.HasLoginForm,
+		WordCount:         result.WordCount,
+		IsThinContent:     result.IsThinContent,
+		ScriptCount:       &result.ScriptCount,
+		StylesheetCount:   &result.StylesheetCount,
+		InlineScriptCount: &result.InlineScriptCount,
+		HasSignupForm:     &result.HasSignupForm,
+		HasSearchForm:     &result.HasSearchForm,
+		HasTitle:          &result.HasTitle,
+		HasFavicon:        &result.HasFavicon,
+		HasViewportMeta:   &result.HasViewportMeta,
+		ResponseStatus:    result.ResponseStatus,
+		RedirectedOffHost: result.RedirectedOffHost,
+		CanonicalURL:      result.CanonicalURL,
+		CanonicalMismatch: result.CanonicalMismatch,
+		OGTitle:           result.OGTitle,
+		OGDescription:     result.OGDescription,
+		OGImage:           result.OGImage,
+		ContentLanguage:   result.ContentLanguage,
+		Charset:           result.Charset,
+		CertValid:         result.CertificateValid,
+		CertExpiresAt:     result.CertificateExpiresAt,
+		ContentHash:       result.ContentHash,
+		Changed:           &result.Changed,
+		H1Count:           result.H1Count,
+		H2Count:           result.H2Count,
+		H3Count:           result.H3Count,
+		H4Count:           result.H4Count,
+		H5Count:           result.H5Count,
+		H6Count:           result.H6Count,
+		TagCounts:         result.TagCounts,
+		InternalLinks:     result.InternalLinks,
+		ExternalLinks:     result.ExternalLinks,
+		BrokenLinks:       result.BrokenLinks,
+		LinksChecked:      result.LinksChecked,
+		LinksTotal:        result.LinksTotal,
+		IsPartial:         result.IsPartial,
+		Status:            crawlResultStatus(result.ResponseStatus),
+		CrawledAt:         result.CreatedAt.UTC(),
+		ChartData:         chartData,
+		BrokenLinksList:   brokenLinks,
+		Warnings:          resultWarnings(result.CrawlResult),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -730,12 +1221,8 @@ func (h *URLHandler) GetResultDetail(c *gin.Context) {
 
 // GetLinks returns all links for a specific crawl result
 func (h *URLHandler) GetLinks(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "User not authenticated",
-		})
+	userID, ok := getUserID(c)
+	if !ok {
 		return
 	}
 
@@ -748,44 +1235,33 @@ func (h *URLHandler) GetLinks(c *gin.Context) {
 		return
 	}
 
+	db, cancel := queryDB(c, h.db)
+	defer cancel()
+
 	// Verify user owns this crawl result
-	var crawlResult models.CrawlResult
-	if err := h.db.Table("crawl_results").
-		Joins("JOIN urls ON crawl_results.url_id = urls.id").
-		Where("crawl_results.id = ? AND urls.user_id = ?", id, userID).
-		First(&crawlResult).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "Result not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to verify result ownership",
-			"error":   err.Error(),
-		})
+	if _, err := findOwnedResult(db, uint(id), userID); err != nil {
+		respondFindError(c, err, "Result not found", "Failed to verify result ownership")
 		return
 	}
 
 	// Parse query parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
 	linkType := c.Query("type") // "internal", "external", or "broken"
 	search := c.Query("search")
 
 	if page < 1 {
 		page = 1
 	}
-	if limit < 1 || limit > 100 {
-		limit = 50
+	limit = h.pagination.Limit(limit, h.pagination.DefaultLinksPageSize)
+	if !checkPageDepth(c, h.pagination, page, limit, false) {
+		return
 	}
 
 	offset := (page - 1) * limit
 
 	// Build query
-	query := h.db.Where("crawl_result_id = ?", id)
+	query := db.Where("crawl_result_id = ?", id)
 
 	// Apply filters
 	if linkType == "internal" {
@@ -796,6 +1272,24 @@ func (h *URLHandler) GetLinks(c *gin.Context) {
 		query = query.Where("is_broken = ?", true)
 	}
 
+	switch c.Query("rel") {
+	case "":
+	case "nofollow":
+		query = query.Where("nofollow = ?", true)
+	case "sponsored":
+		query = query.Where("sponsored = ?", true)
+	case "ugc":
+		query = query.Where("ugc = ?", true)
+	case "follow":
+		query = query.Where("nofollow = ? AND sponsored = ? AND ugc = ?", false, false, false)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid rel: must be nofollow, sponsored, ugc or follow",
+		})
+		return
+	}
+
 	if search != "" {
 		query = query.Where("url LIKE ?", "%"+search+"%")
 	}
@@ -803,37 +1297,31 @@ func (h *URLHandler) GetLinks(c *gin.Context) {
 	// Get total count
 	var total int64
 	if err := query.Model(&models.Link{}).Count(&total).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to count links",
-			"error":   err.Error(),
-		})
+		respondQueryError(c, "Failed to count links", err)
 		return
 	}
 
-	// Get links with pagination
+	// Get links with pagination, in a stable order
 	var links []models.Link
-	if err := query.Offset(offset).Limit(limit).Find(&links).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "Failed to retrieve links",
-			"error":   err.Error(),
-		})
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&links).Error; err != nil {
+		respondQueryError(c, "Failed to retrieve links", err)
 		return
 	}
 
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+	setPaginationHeaders(c, pagination)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"links": links,
-			"pagination": PaginationResponse{
-				Page:       page,
-				Limit:      limit,
-				Total:      total,
-				TotalPages: totalPages,
-			},
+			"links":      links,
+			"pagination": pagination,
 		},
 	})
 }
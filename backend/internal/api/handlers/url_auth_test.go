@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/crawler"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestCrawlURLWithBasicAuth(t *testing.T) {
+	t.Setenv("CREDENTIALS_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "protected")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "crawler" || password != "s3cret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="members"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Members only</title></head><body></body></html>`)
+	}))
+	defer server.Close()
+
+	router := testRouter(user.ID)
+	router.POST("/urls", NewURLHandler(db).CreateURL)
+
+	create := func(path, password string) uint {
+		t.Helper()
+		w := doJSON(router, http.MethodPost, "/urls", map[string]any{
+			"url":  server.URL + path,
+			"auth": map[string]string{"type": "basic", "username": "crawler", "password": password},
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: status = %d, want 201: %s", w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), password) {
+			t.Errorf("create response contains the password: %s", w.Body)
+		}
+		var body struct {
+			Data struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		return body.Data.ID
+	}
+
+	id := create("/", "s3cret")
+	var stored models.URL
+	if err := db.First(&stored, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.AuthType != models.AuthTypeBasic || stored.AuthCredentials == "" || strings.Contains(stored.AuthCredentials, "s3cret") {
+		t.Errorf("stored credentials = %q (%s), want them encrypted", stored.AuthCredentials, stored.AuthType)
+	}
+
+	cs := crawler.NewCrawlerService(db)
+	crawl := func(id uint) error {
+		t.Helper()
+		if claimed, err := cs.ClaimURL(id); err != nil || !claimed {
+			t.Fatalf("claiming URL %d: %v, %v", id, claimed, err)
+		}
+		return cs.CrawlURL(id)
+	}
+	if err := crawl(id); err != nil {
+		t.Fatalf("crawl with the right password: %v", err)
+	}
+	var result models.CrawlResult
+	if err := db.Where("url_id = ?", id).First(&result).Error; err != nil {
+		t.Fatal(err)
+	}
+	if result.Title != "Members only" {
+		t.Errorf("title = %q, want the protected page", result.Title)
+	}
+
+	wrong := create("/other", "guess")
+	if err := crawl(wrong); err == nil {
+		t.Error("crawl with the wrong password succeeded")
+	}
+}
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// urlsRouter serves the URL listing, deletion, trash and restore endpoints as userID
+func urlsRouter(db *gorm.DB, userID uint) http.Handler {
+	handler := NewURLHandler(db)
+	router := testRouter(userID)
+	router.GET("/urls", handler.GetURLs)
+	router.GET("/urls/trash", handler.GetTrashedURLs)
+	router.DELETE("/urls/:id", handler.DeleteURL)
+	router.POST("/urls/:id/restore", handler.RestoreURL)
+	return router
+}
+
+// listTrashIDs calls GET /urls/trash and returns the IDs of the URLs listed
+func listTrashIDs(t *testing.T, router http.Handler) []uint {
+	t.Helper()
+	w := doJSON(router, http.MethodGet, "/urls/trash", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /urls/trash: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var body struct {
+		Data []struct {
+			ID uint `json:"id"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &body)
+
+	ids := make([]uint, 0, len(body.Data))
+	for _, u := range body.Data {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestDeleteAndRestoreURL(t *testing.T) {
+	db := testutil.NewDB(t)
+	owner := createUser(t, db, "owner")
+	other := createUser(t, db, "other")
+	router := urlsRouter(db, owner.ID)
+
+	kept := createURL(t, db, owner.ID, "https://kept.example.com")
+	deleted := createURL(t, db, owner.ID, "https://deleted.example.com")
+
+	if w := doJSON(router, http.MethodDelete, fmt.Sprintf("/urls/%d", deleted.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ids := listURLIDs(t, router, ""); !slices.Equal(ids, []uint{kept.ID}) {
+		t.Errorf("URLs after delete = %v, want [%d]", ids, kept.ID)
+	}
+	if ids := listTrashIDs(t, router); !slices.Equal(ids, []uint{deleted.ID}) {
+		t.Errorf("trash = %v, want [%d]", ids, deleted.ID)
+	}
+	if ids := listTrashIDs(t, urlsRouter(db, other.ID)); len(ids) != 0 {
+		t.Errorf("another user's trash = %v, want it empty", ids)
+	}
+
+	// Someone else can't restore the URL
+	if w := doJSON(urlsRouter(db, other.ID), http.MethodPost, fmt.Sprintf("/urls/%d/restore", deleted.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("restore by another user: status = %d, want 404", w.Code)
+	}
+
+	w := doJSON(router, http.MethodPost, fmt.Sprintf("/urls/%d/restore", deleted.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var restored struct {
+		Data struct {
+			ID  uint   `json:"id"`
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &restored)
+	if restored.Data.ID != deleted.ID || restored.Data.URL != deleted.URL {
+		t.Errorf("restored URL = %+v, want %d %s", restored.Data, deleted.ID, deleted.URL)
+	}
+
+	ids := listURLIDs(t, router, "")
+	slices.Sort(ids)
+	if !slices.Equal(ids, []uint{kept.ID, deleted.ID}) {
+		t.Errorf("URLs after restore = %v, want [%d %d]", ids, kept.ID, deleted.ID)
+	}
+	if ids := listTrashIDs(t, router); len(ids) != 0 {
+		t.Errorf("trash after restore = %v, want it empty", ids)
+	}
+
+	// Only URLs in the trash can be restored
+	for _, id := range []uint{deleted.ID, kept.ID, 9999} {
+		if w := doJSON(router, http.MethodPost, fmt.Sprintf("/urls/%d/restore", id), nil); w.Code != http.StatusNotFound {
+			t.Errorf("restoring URL %d that isn't deleted: status = %d, want 404", id, w.Code)
+		}
+	}
+}
+
+func TestURLChangesInvalidateCachedStatuses(t *testing.T) {
+	t.Setenv("STATUS_CACHE_TTL", "1m")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "poller")
+	urls := NewURLHandler(db)
+	crawls := NewCrawlHandler(db)
+	urls.UseStatusCache(crawls.CrawlerService())
+	router := testRouter(user.ID)
+	router.POST("/urls", urls.CreateURL)
+	router.PUT("/urls/:id", urls.UpdateURL)
+	router.DELETE("/urls/:id", urls.DeleteURL)
+	router.POST("/urls/:id/restore", urls.RestoreURL)
+	router.GET("/status/crawl", crawls.GetCrawlStatus)
+
+	// polled returns the URLs the cached status poll lists, by address
+	polled := func() map[string]models.CrawlStatus {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, "/status/crawl", nil)
+		var body struct {
+			Data struct {
+				URLs []models.URL `json:"urls"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		statuses := make(map[string]models.CrawlStatus)
+		for _, u := range body.Data.URLs {
+			statuses[u.URL] = u.Status
+		}
+		return statuses
+	}
+
+	done := createURL(t, db, user.ID, "https://done.example")
+	if err := db.Model(&done).Update("status", models.StatusCompleted).Error; err != nil {
+		t.Fatal(err)
+	}
+	polled()
+
+	w := doJSON(router, http.MethodPost, "/urls", map[string]string{"url": "https://new.example"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	if _, ok := polled()["https://new.example"]; !ok {
+		t.Error("a created URL is missing from the next poll")
+	}
+
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/urls/%d", done.ID), map[string]string{"url": "https://done.example/moved"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if status := polled()["https://done.example/moved"]; status != models.StatusQueued {
+		t.Errorf("updated URL polled as %q, want it queued again", status)
+	}
+
+	if w := doJSON(router, http.MethodDelete, fmt.Sprintf("/urls/%d", done.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if _, ok := polled()["https://done.example/moved"]; ok {
+		t.Error("a deleted URL is still in the next poll")
+	}
+
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/urls/%d/restore", done.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if _, ok := polled()["https://done.example/moved"]; !ok {
+		t.Error("a restored URL is missing from the next poll")
+	}
+}
+
+func TestDeleteURLCascadesToResultsAndLinks(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "cascade")
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/results", handler.GetResults)
+	router.DELETE("/results/:id", handler.DeleteResult)
+	router.DELETE("/urls", handler.BulkDeleteURLs)
+	router.DELETE("/urls/:id", handler.DeleteURL)
+	router.POST("/urls/:id/restore", handler.RestoreURL)
+
+	kept := createURL(t, db, user.ID, "https://kept.example.com")
+	keptResult := createResult(t, db, kept.ID, models.CrawlResult{})
+	deleted := createURL(t, db, user.ID, "https://deleted.example.com")
+	removedEarlier := createResult(t, db, deleted.ID, models.CrawlResult{})
+	latest := createResult(t, db, deleted.ID, models.CrawlResult{})
+	createLinks(t, db, latest.ID, "https://deleted.example.com/a", "https://deleted.example.com/b")
+
+	// A result deleted on its own stays deleted when its URL is restored
+	if w := doJSON(router, http.MethodDelete, fmt.Sprintf("/results/%d", removedEarlier.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("deleting a result: status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	activeLinks := func() int64 {
+		t.Helper()
+		var count int64
+		if err := db.Model(&models.Link{}).Where("crawl_result_id = ?", latest.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	if w := doJSON(router, http.MethodDelete, fmt.Sprintf("/urls/%d", deleted.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ids, _ := listResults(t, router, "sort_order=asc"); !slices.Equal(ids, []uint{keptResult.ID}) {
+		t.Errorf("results after deleting the URL = %v, want [%d]", ids, keptResult.ID)
+	}
+	if n := activeLinks(); n != 0 {
+		t.Errorf("%d links of the deleted URL are still active, want 0", n)
+	}
+
+	if w := doJSON(router, http.MethodPost, fmt.Sprintf("/urls/%d/restore", deleted.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ids, _ := listResults(t, router, "sort_order=asc"); !slices.Equal(ids, []uint{keptResult.ID, latest.ID}) {
+		t.Errorf("results after restoring the URL = %v, want [%d %d]", ids, keptResult.ID, latest.ID)
+	}
+	if n := activeLinks(); n != 2 {
+		t.Errorf("%d links are active after the restore, want 2", n)
+	}
+
+	// Bulk deletes cascade the same way
+	if w := doJSON(router, http.MethodDelete, "/urls", map[string][]uint{"ids": {kept.ID, deleted.ID}}); w.Code != http.StatusOK {
+		t.Fatalf("bulk delete: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if ids, _ := listResults(t, router, ""); len(ids) != 0 {
+		t.Errorf("results after the bulk delete = %v, want none", ids)
+	}
+	if n := activeLinks(); n != 0 {
+		t.Errorf("%d links are active after the bulk delete, want 0", n)
+	}
+}
+
+func TestCrawlConfigOverrides(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "overrides")
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.POST("/urls", handler.CreateURL)
+	router.GET("/urls/:id", handler.GetURL)
+	router.PUT("/urls/:id", handler.UpdateURL)
+
+	type overrides struct {
+		MaxLinksToCheck     *int  `json:"max_links_to_check"`
+		FollowRedirects     *bool `json:"follow_redirects"`
+		CrawlTimeoutSeconds *int  `json:"crawl_timeout_seconds"`
+	}
+	getOverrides := func(id uint) overrides {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/urls/%d", id), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /urls/%d: status = %d, want 200: %s", id, w.Code, w.Body)
+		}
+		var body struct {
+			Data overrides `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		return body.Data
+	}
+
+	w := doJSON(router, http.MethodPost, "/urls", map[string]any{
+		"url":                   "https://example.com",
+		"max_links_to_check":    25,
+		"follow_redirects":      false,
+		"crawl_timeout_seconds": 5,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201: %s", w.Code, w.Body)
+	}
+	var created struct {
+		Data struct {
+			ID uint `json:"id"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &created)
+
+	got := getOverrides(created.Data.ID)
+	if got.MaxLinksToCheck == nil || *got.MaxLinksToCheck != 25 ||
+		got.FollowRedirects == nil || *got.FollowRedirects ||
+		got.CrawlTimeoutSeconds == nil || *got.CrawlTimeoutSeconds != 5 {
+		t.Errorf("overrides = %+v, want 25 links, no redirects and a 5s timeout", got)
+	}
+
+	// Omitted overrides are kept on update
+	w = doJSON(router, http.MethodPut, fmt.Sprintf("/urls/%d", created.Data.ID), map[string]any{
+		"url":                   "https://example.com",
+		"crawl_timeout_seconds": 60,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, want 200: %s", w.Code, w.Body)
+	}
+	got = getOverrides(created.Data.ID)
+	if got.MaxLinksToCheck == nil || *got.MaxLinksToCheck != 25 || got.CrawlTimeoutSeconds == nil || *got.CrawlTimeoutSeconds != 60 {
+		t.Errorf("overrides after update = %+v, want 25 links kept and a 60s timeout", got)
+	}
+
+	// A URL created without overrides uses the global configuration
+	unset := createURL(t, db, user.ID, "https://example.org")
+	if got := getOverrides(unset.ID); got != (overrides{}) {
+		t.Errorf("overrides of a URL without any = %+v, want none", got)
+	}
+
+	for _, timeout := range []int{0, 301} {
+		w := doJSON(router, http.MethodPost, "/urls", map[string]any{"url": "https://example.net", "crawl_timeout_seconds": timeout})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("timeout %d: status = %d, want 400", timeout, w.Code)
+		}
+	}
+}
+
+func TestGetURLsStatusUpdatedSince(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "poller")
+	router := testRouter(user.ID)
+	router.GET("/status/urls", NewURLHandler(db).GetURLsStatus)
+
+	// poll calls GET /status/urls and returns the IDs listed and the server time
+	poll := func(query string) ([]uint, string) {
+		t.Helper()
+		w := doJSON(router, http.MethodGet, "/status/urls?"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /status/urls?%s: status = %d, want 200: %s", query, w.Code, w.Body)
+		}
+		var body struct {
+			Data []struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+			ServerTime string `json:"server_time"`
+		}
+		decodeBody(t, w, &body)
+
+		ids := make([]uint, 0, len(body.Data))
+		for _, u := range body.Data {
+			ids = append(ids, u.ID)
+		}
+		slices.Sort(ids)
+		return ids, body.ServerTime
+	}
+
+	hourAgo := time.Now().Add(-time.Hour)
+	stale := models.URL{URL: "https://stale.example", UserID: user.ID, Status: models.StatusCompleted, UpdatedAt: hourAgo}
+	if err := db.Create(&stale).Error; err != nil {
+		t.Fatal(err)
+	}
+	fresh := createURL(t, db, user.ID, "https://fresh.example")
+
+	all, serverTime := poll("")
+	if !slices.Equal(all, []uint{stale.ID, fresh.ID}) {
+		t.Errorf("URLs without updated_since = %v, want both", all)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, serverTime); err != nil {
+		t.Fatalf("server_time %q isn't RFC 3339: %v", serverTime, err)
+	}
+
+	since := hourAgo.Add(time.Minute).UTC().Format(time.RFC3339Nano)
+	if ids, _ := poll("updated_since=" + url.QueryEscape(since)); !slices.Equal(ids, []uint{fresh.ID}) {
+		t.Errorf("URLs updated since %s = %v, want only [%d]", since, ids, fresh.ID)
+	}
+
+	// The server time is the next updated_since: only rows changed afterwards come back
+	if ids, _ := poll("updated_since=" + url.QueryEscape(serverTime)); len(ids) != 0 {
+		t.Errorf("URLs right after the last poll = %v, want none", ids)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := db.Model(&stale).Update("status", models.StatusQueued).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := poll("updated_since=" + url.QueryEscape(serverTime)); !slices.Equal(ids, []uint{stale.ID}) {
+		t.Errorf("URLs changed since the last poll = %v, want [%d]", ids, stale.ID)
+	}
+
+	if w := doJSON(router, http.MethodGet, "/status/urls?updated_since=yesterday", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid updated_since: status = %d, want 400", w.Code)
+	}
+}
+
+func TestGetURLsHasResultsFilter(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "filterer")
+	router := urlsRouter(db, user.ID)
+
+	crawled := createURL(t, db, user.ID, "https://crawled.example.com")
+	createResult(t, db, crawled.ID, models.CrawlResult{ResponseStatus: http.StatusOK})
+	failed := createURL(t, db, user.ID, "https://failed.example.com")
+	createResult(t, db, failed.ID, models.CrawlResult{ResponseStatus: http.StatusNotFound})
+	deleted := createURL(t, db, user.ID, "https://deleted.example.org")
+	deletedResult := createResult(t, db, deleted.ID, models.CrawlResult{ResponseStatus: http.StatusOK})
+	if err := db.Delete(&deletedResult).Error; err != nil {
+		t.Fatal(err)
+	}
+	never := createURL(t, db, user.ID, "https://never.example.org")
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"has_results=true", []uint{crawled.ID}},
+		{"has_results=false", []uint{failed.ID, deleted.ID, never.ID}},
+		{"has_results=false&search=example.org", []uint{deleted.ID, never.ID}},
+		{"has_results=true&search=example.org", []uint{}},
+		{"", []uint{crawled.ID, failed.ID, deleted.ID, never.ID}},
+	}
+	for _, tt := range tests {
+		got := listURLIDs(t, router, "sort_by=created_at&sort_order=asc&"+tt.query)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: URLs = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	if w := doJSON(router, http.MethodGet, "/urls?has_results=sometimes", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid has_results: status = %d, want 400", w.Code)
+	}
+}
+
+func TestCrawlDomainAllowlist(t *testing.T) {
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "example.com,*.example.org")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "locked")
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.POST("/urls", handler.CreateURL)
+	router.PUT("/urls/:id", handler.UpdateURL)
+
+	for address, want := range map[string]int{
+		"https://example.com/page":      http.StatusCreated,
+		"https://docs.example.org/":     http.StatusCreated,
+		"https://www.example.com/":      http.StatusForbidden,
+		"https://example.org/":          http.StatusForbidden,
+		"https://elsewhere.example.net": http.StatusForbidden,
+	} {
+		if w := doJSON(router, http.MethodPost, "/urls", map[string]string{"url": address}); w.Code != want {
+			t.Errorf("creating %s: status = %d, want %d: %s", address, w.Code, want, w.Body)
+		}
+	}
+
+	urlEntry := createURL(t, db, user.ID, "https://example.com/")
+	target := fmt.Sprintf("/urls/%d", urlEntry.ID)
+	if w := doJSON(router, http.MethodPut, target, map[string]string{"url": "https://elsewhere.example.net/"}); w.Code != http.StatusForbidden {
+		t.Errorf("updating to a rejected host: status = %d, want 403: %s", w.Code, w.Body)
+	}
+	if w := doJSON(router, http.MethodPut, target, map[string]string{"url": "https://blog.example.org/"}); w.Code != http.StatusOK {
+		t.Errorf("updating to an allowed host: status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
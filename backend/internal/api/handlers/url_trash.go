@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// softDeleteURLs soft-deletes a user's URLs along with their crawl results and links. All
+// rows are stamped with the same deletion time, so restoreURL brings back exactly what was
+// deleted with the URL. It returns the number of URLs deleted.
+func softDeleteURLs(tx *gorm.DB, userID uint, ids []uint) (int64, error) {
+	var urlIDs []uint
+	if err := tx.Model(&models.URL{}).Where("id IN ? AND user_id = ?", ids, userID).Pluck("id", &urlIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(urlIDs) == 0 {
+		return 0, nil
+	}
+
+	deletedAt := time.Now()
+	results := tx.Model(&models.CrawlResult{}).Select("id").Where("url_id IN ?", urlIDs)
+	if err := tx.Model(&models.Link{}).Where("crawl_result_id IN (?)", results).Update("deleted_at", deletedAt).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.CrawlResult{}).Where("url_id IN ?", urlIDs).Update("deleted_at", deletedAt).Error; err != nil {
+		return 0, err
+	}
+	result := tx.Model(&models.URL{}).Where("id IN ?", urlIDs).Update("deleted_at", deletedAt)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if err := tx.Exec("DELETE FROM url_tags WHERE url_id IN ?", urlIDs).Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// restoreURL undoes softDeleteURLs for one URL. Results and links deleted on their own
+// before the URL was deleted stay deleted.
+func restoreURL(tx *gorm.DB, url *models.URL) error {
+	deletedAt := url.DeletedAt.Time
+
+	results := tx.Unscoped().Model(&models.CrawlResult{}).Select("id").Where("url_id = ? AND deleted_at = ?", url.ID, deletedAt)
+	if err := tx.Unscoped().Model(&models.Link{}).
+		Where("crawl_result_id IN (?) AND deleted_at = ?", results, deletedAt).
+		Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Model(&models.CrawlResult{}).
+		Where("url_id = ? AND deleted_at = ?", url.ID, deletedAt).
+		Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(url).Update("deleted_at", nil).Error
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"skyell-backend/internal/netguard"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ValidateURLRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// ValidateURL checks that a URL is well-formed and reachable without saving it
+func (h *CrawlHandler) ValidateURL(c *gin.Context) {
+	if _, ok := getUserID(c); !ok {
+		return
+	}
+
+	var req ValidateURLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	// Only http(s) URLs can be crawled
+	if !isValidURL(req.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid URL format",
+		})
+		return
+	}
+	u, _ := url.Parse(req.URL)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Only http and https URLs can be crawled",
+		})
+		return
+	}
+	if !isAllowedDomain(req.URL) {
+		respondDomainNotAllowed(c)
+		return
+	}
+	// Resolution failures are left to the probe to report
+	if err := netguard.CheckHost(c.Request.Context(), u.Hostname()); errors.Is(err, netguard.ErrInternalAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "URL points to an internal address",
+		})
+		return
+	}
+
+	result := h.crawlerService.Probe(c.Request.Context(), req.URL)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// embeddedFieldName stands in for embedded structs in validation namespaces. Their fields
+// are flattened into the parent in JSON, so fieldPath drops it.
+const embeddedFieldName = "<embedded>"
+
+func init() {
+	// Report fields by their JSON name rather than the Go struct field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" && field.Anonymous {
+				return embeddedFieldName
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// respondBindError writes a 400 for a failed ShouldBindJSON, with per-field errors
+// when the body parsed but failed validation
+func respondBindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"success": false,
+			"message": "Request body too large",
+		})
+		return
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	fieldErrors := make([]FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"success": false,
+		"message": "Validation failed",
+		"errors":  fieldErrors,
+	})
+}
+
+// fieldPath returns the field's JSON path without the top-level struct name, e.g. "auth.type"
+func fieldPath(fe validator.FieldError) string {
+	namespace := strings.ReplaceAll(fe.Namespace(), embeddedFieldName+".", "")
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// validationMessage renders a human-readable message for common validation rules
+func validationMessage(fe validator.FieldError) string {
+	field := fieldPath(fe)
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "min":
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be at least %s characters", field, fe.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("%s must contain at least %s items", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max":
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("%s must be at most %s characters", field, fe.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("%s must contain at most %s items", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, fe.Param())
+	default:
+		return fmt.Sprintf("%s failed %s validation", field, fe.Tag())
+	}
+}
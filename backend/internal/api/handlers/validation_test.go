@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/api/middleware"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+// validationResponse is the body of a 400 for a request that failed validation
+type validationResponse struct {
+	Success bool         `json:"success"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func TestRegisterValidationErrors(t *testing.T) {
+	db := testutil.NewDB(t)
+	router := testRouter(0)
+	router.POST("/auth/register", NewAuthHandler(db).Register)
+
+	w := doJSON(router, http.MethodPost, "/auth/register", map[string]string{
+		"username": "ab",
+		"email":    "not-an-email",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+
+	var body validationResponse
+	decodeBody(t, w, &body)
+	if body.Success || body.Message != "Validation failed" {
+		t.Errorf("success, message = %v, %q, want false, %q", body.Success, body.Message, "Validation failed")
+	}
+
+	want := []FieldError{
+		{Field: "username", Rule: "min", Message: "username must be at least 3 characters"},
+		{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		{Field: "password", Rule: "required", Message: "password is required"},
+	}
+	if !slices.Equal(body.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", body.Errors, want)
+	}
+}
+
+func TestCreateURLValidationErrors(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "validator")
+	router := testRouter(user.ID)
+	router.POST("/urls", NewURLHandler(db).CreateURL)
+
+	w := doJSON(router, http.MethodPost, "/urls", map[string]any{
+		"url":                "https://example.com",
+		"max_links_to_check": 5000,
+		"auth":               map[string]string{"type": "digest"},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+
+	var body validationResponse
+	decodeBody(t, w, &body)
+	want := []FieldError{
+		{Field: "auth.type", Rule: "oneof", Message: "auth.type must be one of: none basic bearer"},
+		{Field: "max_links_to_check", Rule: "max", Message: "max_links_to_check must be at most 1000"},
+	}
+	if !slices.Equal(body.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", body.Errors, want)
+	}
+
+	// A body that isn't JSON has no field errors
+	w = doJSON(router, http.MethodPost, "/urls", "not an object")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body: status = %d, want 400", w.Code)
+	}
+	body = validationResponse{}
+	decodeBody(t, w, &body)
+	if body.Message != "Invalid request data" || len(body.Errors) != 0 {
+		t.Errorf("malformed body: %+v, want a plain invalid request error", body)
+	}
+}
+
+func TestOversizedBodyIsRejected(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "64")
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "oversized")
+
+	router := testRouter(user.ID)
+	router.Use(middleware.BodyLimit())
+	router.POST("/urls", NewURLHandler(db).CreateURL)
+
+	body := `{"url": "https://example.com/` + strings.Repeat("a", 128) + `"}`
+	for _, declared := range []bool{true, false} {
+		var reader io.Reader = strings.NewReader(body)
+		if !declared {
+			reader = io.MultiReader(reader) // Hides the length, so binding hits the limit
+		}
+		req := httptest.NewRequest(http.MethodPost, "/urls", reader)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("length declared %v: status = %d, want 413: %s", declared, w.Code, w.Body)
+		}
+	}
+
+	var count int64
+	db.Model(&models.URL{}).Count(&count)
+	if count != 0 {
+		t.Errorf("%d URLs were created from oversized bodies", count)
+	}
+}
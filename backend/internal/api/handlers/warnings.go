@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"fmt"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+)
+
+// resultWarnings summarizes the SEO and health issues of a crawl result. The H1 and
+// broken link thresholds are WARN_MAX_H1_COUNT and WARN_MAX_BROKEN_LINKS.
+func resultWarnings(result models.CrawlResult) []string {
+	warnings := []string{}
+
+	if result.Title == "" {
+		warnings = append(warnings, "Page has no title")
+	}
+	if !result.HasMetaDescription {
+		warnings = append(warnings, "Page has no meta description")
+	}
+	if result.H1Count == 0 {
+		warnings = append(warnings, "Page has no H1 heading")
+	} else if maxH1 := config.GetEnvInt("WARN_MAX_H1_COUNT", 1); result.H1Count > maxH1 {
+		warnings = append(warnings, fmt.Sprintf("Page has %d H1 headings (at most %d recommended)", result.H1Count, maxH1))
+	}
+	if result.CanonicalMismatch {
+		warnings = append(warnings, fmt.Sprintf("Canonical URL points to a different page (%s)", result.CanonicalURL))
+	}
+	if maxBroken := config.GetEnvInt("WARN_MAX_BROKEN_LINKS", 5); result.BrokenLinks > maxBroken {
+		warnings = append(warnings, fmt.Sprintf("Page has %d broken links (more than %d)", result.BrokenLinks, maxBroken))
+	}
+
+	return warnings
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestResultDetailWarnings(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := createUser(t, db, "seo")
+	urlEntry := createURL(t, db, user.ID, "https://example.com")
+
+	handler := NewURLHandler(db)
+	router := testRouter(user.ID)
+	router.GET("/results/:id", handler.GetResultDetail)
+	warnings := func(result models.CrawlResult) []string {
+		t.Helper()
+		result = createResult(t, db, urlEntry.ID, result)
+		w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d", result.ID), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET result: status = %d, want 200: %s", w.Code, w.Body)
+		}
+		var body struct {
+			Data struct {
+				Warnings []string `json:"warnings"`
+			} `json:"data"`
+		}
+		decodeBody(t, w, &body)
+		return body.Data.Warnings
+	}
+
+	// Two H1s and no title
+	got := warnings(models.CrawlResult{H1Count: 2, MultipleH1: true, HasMetaDescription: true})
+	want := []string{"Page has no title", "Page has 2 H1 headings (at most 1 recommended)"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	got = warnings(models.CrawlResult{BrokenLinks: 6})
+	want = []string{
+		"Page has no title",
+		"Page has no meta description",
+		"Page has no H1 heading",
+		"Page has 6 broken links (more than 5)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+
+	if got := warnings(models.CrawlResult{Title: "Fine", HasTitle: true, HasMetaDescription: true, H1Count: 1, BrokenLinks: 5}); len(got) != 0 {
+		t.Errorf("warnings of a healthy page = %q, want none", got)
+	}
+
+	// A canonical URL pointing elsewhere is flagged in the detail and warned about
+	elsewhere := createResult(t, db, urlEntry.ID, models.CrawlResult{Title: "Copy", HasTitle: true, HasMetaDescription: true, H1Count: 1,
+		CanonicalURL: "https://example.com/original", CanonicalMismatch: true})
+	w := doJSON(router, http.MethodGet, fmt.Sprintf("/results/%d", elsewhere.ID), nil)
+	var detail struct {
+		Data struct {
+			CanonicalMismatch bool     `json:"canonical_mismatch"`
+			Warnings          []string `json:"warnings"`
+		} `json:"data"`
+	}
+	decodeBody(t, w, &detail)
+	want = []string{"Canonical URL points to a different page (https://example.com/original)"}
+	if !detail.Data.CanonicalMismatch || !slices.Equal(detail.Data.Warnings, want) {
+		t.Errorf("canonical mismatch %v, warnings %q; want true, %q", detail.Data.CanonicalMismatch, detail.Data.Warnings, want)
+	}
+
+	// The thresholds are configurable
+	t.Setenv("WARN_MAX_H1_COUNT", "3")
+	t.Setenv("WARN_MAX_BROKEN_LINKS", "1")
+	got = warnings(models.CrawlResult{Title: "Busy", HasMetaDescription: true, H1Count: 3, BrokenLinks: 2})
+	want = []string{"Page has 2 broken links (more than 1)"}
+	if !slices.Equal(got, want) {
+		t.Errorf("warnings with custom thresholds = %q, want %q", got, want)
+	}
+}
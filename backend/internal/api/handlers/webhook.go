@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/netguard"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SetWebhookRequest struct {
+	URL string `json:"url" binding:"required,max=500"`
+}
+
+// SetWebhook sets the URL notified when the user's crawls finish and issues a new signing
+// secret. The secret is only returned here, so receivers should store it.
+func (h *AuthHandler) SetWebhook(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req SetWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid webhook URL: must be an http or https URL",
+		})
+		return
+	}
+	// Deliveries are checked again on every connection, since DNS can change after this
+	if err := netguard.CheckHost(c.Request.Context(), u.Hostname()); errors.Is(err, netguard.ErrInternalAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid webhook URL: must not point to an internal address",
+		})
+		return
+	}
+
+	secret, err := generateResetToken()
+	if err != nil {
+		respondInternalError(c, "Failed to generate webhook secret", err)
+		return
+	}
+
+	if err := h.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"webhook_url":    req.URL,
+		"webhook_secret": secret,
+	}).Error; err != nil {
+		respondInternalError(c, "Failed to save webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook saved successfully",
+		"data": gin.H{
+			"webhook_url":    req.URL,
+			"webhook_secret": secret,
+		},
+	})
+}
+
+// DeleteWebhook stops crawl notifications for the user
+func (h *AuthHandler) DeleteWebhook(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"webhook_url":    "",
+		"webhook_secret": "",
+	}).Error; err != nil {
+		respondInternalError(c, "Failed to delete webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook deleted successfully",
+	})
+}
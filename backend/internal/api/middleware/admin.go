@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminOnly allows only the users whose ID is listed in ADMIN_USER_IDS; everyone else gets
+// 403. The user must still exist, so a token outliving a deleted admin account grants
+// nothing. Must run after AuthRequired.
+func AdminOnly(db *gorm.DB) gin.HandlerFunc {
+	// Invalid entries are rejected at startup; the valid ones are still used
+	ids, _ := config.AdminUserIDs()
+	admins := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		admins[id] = true
+	}
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var user models.User
+		userID, ok := c.Get("user_id")
+		if !ok || !admins[userID.(uint)] || db.Select("id").First(&user, "id = ?", userID).Error != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Admin access required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// HashAPIKey returns the stored form of an API key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyAuth authenticates requests that carry an X-API-Key header, setting the same
+// user context as a JWT. Requests without the header are left to AuthRequired, which
+// skips its token check for requests authenticated here.
+func APIKeyAuth(db *gorm.DB) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		var apiKey models.APIKey
+		if err := db.Preload("User").
+			Where("key_hash = ? AND revoked = ?", HashAPIKey(key), false).
+			First(&apiKey).Error; err != nil || apiKey.User.ID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Invalid or revoked API key",
+			})
+			c.Abort()
+			return
+		}
+
+		now := time.Now()
+		db.Model(&apiKey).UpdateColumn("last_used_at", now)
+
+		c.Set("user_id", apiKey.UserID)
+		c.Set("username", apiKey.User.Username)
+		c.Set("email", apiKey.User.Email)
+		c.Set("api_key_id", apiKey.ID)
+
+		c.Next()
+	})
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"strings"
@@ -14,12 +15,36 @@ type JWTClaims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
+
+	// TokenType is "access" or "refresh"; tokens issued before it existed have none
+	TokenType string `json:"token_type,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// AuthRequired is a middleware that validates JWT tokens
+// Token types carried in the token_type claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// errRefreshToken is returned when a refresh token is presented as an access token
+var errRefreshToken = errors.New("refresh tokens can't be used to authenticate requests")
+
+// IsAccessToken reports whether the claims belong to an access token. Tokens issued
+// before the token_type claim existed count as access tokens.
+func (c *JWTClaims) IsAccessToken() bool {
+	return c.TokenType == "" || c.TokenType == TokenTypeAccess
+}
+
+// AuthRequired is a middleware that validates JWT tokens. Requests already
+// authenticated by APIKeyAuth pass through.
 func AuthRequired() gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
+		if _, ok := c.Get("api_key_id"); ok {
+			c.Next()
+			return
+		}
+
 		tokenString := extractTokenFromHeader(c)
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -86,6 +111,9 @@ func validateToken(tokenString string) (*JWTClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		if !claims.IsAccessToken() {
+			return nil, errRefreshToken
+		}
 		return claims, nil
 	}
 
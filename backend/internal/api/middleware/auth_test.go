@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signToken signs claims for user 7 carrying tokenType with the test secret
+func signToken(t *testing.T, tokenType string) string {
+	t.Helper()
+	claims := JWTClaims{
+		UserID:    7,
+		Username:  "tester",
+		Email:     "tester@example.com",
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthRequiredTokenTypes(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	router := gin.New()
+	router.GET("/urls", AuthRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id")})
+	})
+
+	tests := []struct {
+		name, tokenType string
+		want            int
+	}{
+		{"access token", TokenTypeAccess, http.StatusOK},
+		{"token without a type", "", http.StatusOK},
+		{"refresh token", TokenTypeRefresh, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/urls", nil)
+			req.Header.Set("Authorization", "Bearer "+signToken(t, tt.tokenType))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit caps request bodies at MAX_REQUEST_BYTES (default 1 MiB). Requests that
+// declare a larger Content-Length are rejected with 413 up front; bodies that turn out
+// larger fail with *http.MaxBytesError when read, which handlers map to 413.
+func BodyLimit() gin.HandlerFunc {
+	maxBytes := int64(config.GetEnvInt("MAX_REQUEST_BYTES", 1<<20))
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"success": false,
+				"message": "Request body too large",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	})
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimitRouter echoes the size of request bodies read through BodyLimit, answering
+// 413 when reading hits the limit
+func bodyLimitRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(BodyLimit())
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+	return router
+}
+
+func TestBodyLimit(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "16")
+	router := bodyLimitRouter()
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+	}{
+		{"within the limit", strings.NewReader(strings.Repeat("a", 16)), http.StatusOK},
+		{"declared too large", strings.NewReader(strings.Repeat("a", 17)), http.StatusRequestEntityTooLarge},
+		// A reader that isn't a strings.Reader leaves the length undeclared, so the limit
+		// is only hit while reading
+		{"streamed too large", io.MultiReader(strings.NewReader(strings.Repeat("a", 17))), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo", tt.body))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBodyLimitDisabled(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BYTES", "0")
+	router := bodyLimitRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(strings.Repeat("a", 2<<20))))
+	if w.Code != http.StatusOK || w.Body.String() != "2097152" {
+		t.Errorf("status = %d, body = %q; want the whole body read", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"skyell-backend/internal/demo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DemoAccess serves requests without credentials as the shared demo user while DEMO_MODE
+// is on, marking them with the "demo" context key. Requests with credentials, and every
+// request while DEMO_MODE is off, must authenticate as with AuthRequired. Run it after
+// APIKeyAuth and OptionalAuth.
+func DemoAccess() gin.HandlerFunc {
+	required := AuthRequired()
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if _, ok := c.Get("user_id"); ok {
+			c.Next()
+			return
+		}
+
+		demoUserID := demo.UserID()
+		if demoUserID == 0 || c.GetHeader("Authorization") != "" {
+			required(c)
+			return
+		}
+
+		c.Set("user_id", demoUserID)
+		c.Set("demo", true)
+		c.Next()
+	})
+}
+
+// DemoReadOnly rejects writes made as the demo user with 403, so the shared data set only
+// changes when it is reset. Must run after AuthRequired.
+func DemoReadOnly() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if userID, ok := c.Get("user_id"); !ok || demo.UserID() == 0 || userID.(uint) != demo.UserID() {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "The demo account is read-only",
+		})
+		c.Abort()
+	})
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exposeErrorDetails reports whether internal error details are returned to clients.
+// They're hidden in release mode (GIN_MODE=release) unless DEBUG=true.
+func exposeErrorDetails() bool {
+	return config.GetEnvBool("DEBUG", gin.Mode() != gin.ReleaseMode)
+}
+
+// newCorrelationID returns a short random ID linking a client-facing error to the server log
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// RespondInternalError logs err with a correlation ID and writes a 500. The error
+// details are only included in the response outside of production.
+func RespondInternalError(c *gin.Context, message string, err error) {
+	correlationID := newCorrelationID()
+	log.Printf("[%s] %s %s: %s: %v", correlationID, c.Request.Method, c.Request.URL.Path, message, err)
+
+	body := gin.H{
+		"success":        false,
+		"message":        message,
+		"correlation_id": correlationID,
+	}
+	if exposeErrorDetails() {
+		body["error"] = err.Error()
+	}
+	c.JSON(http.StatusInternalServerError, body)
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// internalErrorBody runs RespondInternalError and decodes the response
+func internalErrorBody(t *testing.T) map[string]any {
+	t.Helper()
+	router := gin.New()
+	router.GET("/fail", func(c *gin.Context) {
+		RespondInternalError(c, "Failed to do the thing", errors.New("db: connection refused"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body, err)
+	}
+	if body["message"] != "Failed to do the thing" || body["correlation_id"] == "" || body["correlation_id"] == nil {
+		t.Errorf("body = %v, want the message and a correlation ID", body)
+	}
+	return body
+}
+
+func TestRespondInternalErrorHidesDetailsInProduction(t *testing.T) {
+	t.Setenv("DEBUG", "false")
+	if body := internalErrorBody(t); body["error"] != nil {
+		t.Errorf("error details %q were exposed", body["error"])
+	}
+}
+
+func TestRespondInternalErrorShowsDetailsInDebug(t *testing.T) {
+	t.Setenv("DEBUG", "true")
+	if body := internalErrorBody(t); body["error"] != "db: connection refused" {
+		t.Errorf("error = %v, want the error details", body["error"])
+	}
+}
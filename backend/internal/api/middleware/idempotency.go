@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client-chosen key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder captures the response body so it can be stored for replay
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the stored response when an authenticated user repeats a request
+// with the same Idempotency-Key, instead of running the handler again. Keys are scoped
+// per user and kept for IDEMPOTENCY_KEY_TTL. Requests without the header pass through.
+// Must run after AuthRequired.
+func Idempotency(db *gorm.DB) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		userID, exists := c.Get("user_id")
+		if key == "" || !exists {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Idempotency-Key must be at most 255 characters",
+			})
+			c.Abort()
+			return
+		}
+
+		// Fingerprint the request so a key can't be reused for a different request
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"success": false,
+					"message": "Request body too large",
+				})
+				c.Abort()
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Failed to read request body",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		endpoint := c.Request.Method + " " + c.Request.URL.Path
+		hash := sha256.Sum256(append([]byte(endpoint+"\n"), body...))
+		requestHash := hex.EncodeToString(hash[:])
+
+		// Drop an expired record for this key so it can be reused
+		if err := db.Where(&models.IdempotencyKey{UserID: userID.(uint), Key: key}).
+			Where("expires_at < ?", time.Now()).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			RespondInternalError(c, "Failed to process Idempotency-Key", err)
+			c.Abort()
+			return
+		}
+
+		// Reserve the key; the unique index makes concurrent duplicates fail here
+		record := models.IdempotencyKey{
+			UserID:      userID.(uint),
+			Key:         key,
+			Endpoint:    endpoint,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(config.GetEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)),
+		}
+		if err := db.Create(&record).Error; err != nil {
+			replayIdempotentResponse(c, db, userID, key, requestHash)
+			return
+		}
+
+		// Release the key if the handler panics, or it would stay "in progress" until it expires
+		finished := false
+		defer func() {
+			if !finished {
+				releaseIdempotencyKey(db, &record)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		finished = true
+
+		// Server errors aren't cached so the client can retry them
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			releaseIdempotencyKey(db, &record)
+			return
+		}
+
+		if err := db.Model(&record).Updates(map[string]interface{}{
+			"status_code":   status,
+			"response_body": recorder.body.String(),
+		}).Error; err != nil {
+			// The response is already sent; retries get a 409 until the key expires rather than
+			// running the handler again
+			log.Printf("Failed to store the response for Idempotency-Key %d: %v", record.ID, err)
+		}
+	})
+}
+
+// releaseIdempotencyKey deletes a reservation so the key can be used again
+func releaseIdempotencyKey(db *gorm.DB, record *models.IdempotencyKey) {
+	if err := db.Delete(record).Error; err != nil {
+		log.Printf("Failed to release Idempotency-Key %d: %v", record.ID, err)
+	}
+}
+
+// replayIdempotentResponse answers a request whose key was already used
+func replayIdempotentResponse(c *gin.Context, db *gorm.DB, userID interface{}, key, requestHash string) {
+	var existing models.IdempotencyKey
+	if err := db.Where(&models.IdempotencyKey{UserID: userID.(uint), Key: key}).First(&existing).Error; err != nil {
+		RespondInternalError(c, "Failed to process Idempotency-Key", err)
+		c.Abort()
+		return
+	}
+
+	switch {
+	case existing.RequestHash != requestHash:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "Idempotency-Key was already used for a different request",
+		})
+	case existing.StatusCode == 0:
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "A request with this Idempotency-Key is still being processed",
+		})
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(existing.StatusCode, "application/json; charset=utf-8", []byte(existing.ResponseBody))
+	}
+	c.Abort()
+}
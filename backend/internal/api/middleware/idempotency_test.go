@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestIdempotencyMasksDatabaseErrors(t *testing.T) {
+	for _, debug := range []string{"false", "true"} {
+		t.Run("DEBUG="+debug, func(t *testing.T) {
+			t.Setenv("DEBUG", debug)
+
+			db := testutil.NewDB(t)
+			sqlDB, _ := db.DB()
+			sqlDB.Close() // Reserving and looking up the key both fail
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) { c.Set("user_id", uint(1)) })
+			router.POST("/crawl", Idempotency(db), func(c *gin.Context) {
+				t.Error("handler ran although the key couldn't be reserved")
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/crawl", nil)
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", w.Code)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding %q: %v", w.Body, err)
+			}
+			if body["correlation_id"] == nil {
+				t.Errorf("body = %v, want a correlation ID", body)
+			}
+			if _, exposed := body["error"]; exposed != (debug == "true") {
+				t.Errorf("error details exposed = %v with DEBUG=%s", exposed, debug)
+			}
+		})
+	}
+}
+
+// idempotentRouter serves POST /run behind Idempotency as user 1, counting handler runs
+func idempotentRouter(db *gorm.DB, handler func(c *gin.Context)) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(func(c *gin.Context) { c.Set("user_id", uint(1)) })
+	router.POST("/run", Idempotency(db), handler)
+	return router
+}
+
+// postWithKey sends POST /run with an Idempotency-Key
+func postWithKey(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysRepeatedRequests(t *testing.T) {
+	db := testutil.NewDB(t)
+	runs := 0
+	router := idempotentRouter(db, func(c *gin.Context) {
+		runs++
+		c.JSON(http.StatusOK, gin.H{"run": runs})
+	})
+
+	first := postWithKey(router, "key-1", `{"a":1}`)
+	second := postWithKey(router, "key-1", `{"a":1}`)
+	if runs != 1 {
+		t.Fatalf("handler ran %d times, want once", runs)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", second.Code, second.Body, first.Code, first.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replayed response isn't marked Idempotent-Replayed")
+	}
+
+	if w := postWithKey(router, "key-1", `{"a":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key, different body: status = %d, want 422", w.Code)
+	}
+	if postWithKey(router, "key-2", `{"a":1}`); runs != 2 {
+		t.Errorf("a new key didn't run the handler")
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	db := testutil.NewDB(t)
+	runs := 0
+	router := idempotentRouter(db, func(c *gin.Context) {
+		runs++
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false})
+	})
+
+	postWithKey(router, "key-1", "")
+	postWithKey(router, "key-1", "")
+	if runs != 2 {
+		t.Errorf("handler ran %d times, want the failed request retried", runs)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	db := testutil.NewDB(t)
+	runs := 0
+	router := idempotentRouter(db, func(c *gin.Context) {
+		runs++
+		if runs == 1 {
+			panic("handler bug")
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+
+	if w := postWithKey(router, "key-1", ""); w.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking handler: status = %d, want 500", w.Code)
+	}
+	var reserved int64
+	db.Model(&models.IdempotencyKey{}).Count(&reserved)
+	if reserved != 0 {
+		t.Errorf("%d keys still reserved after the panic", reserved)
+	}
+
+	if w := postWithKey(router, "key-1", ""); w.Code != http.StatusOK || runs != 2 {
+		t.Errorf("retry after the panic: status = %d, runs = %d, want 200 and a second run", w.Code, runs)
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenBucket holds up to capacity tokens, refilled continuously at rate tokens per second
+type tokenBucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// rateLimiter keeps one token bucket per user, or per client IP for demo visitors
+type rateLimiter struct {
+	mu        sync.Mutex
+	capacity  float64
+	rate      float64 // Tokens added per second
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// take refills the user's bucket and consumes a token if one is available. It returns
+// whether the request is allowed, the tokens left and when the bucket will be full again.
+func (rl *rateLimiter) take(key string, now time.Time) (bool, int, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.sweep(now)
+
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.capacity, lastFill: now}
+		rl.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(rl.capacity, bucket.tokens+now.Sub(bucket.lastFill).Seconds()*rl.rate)
+	bucket.lastFill = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	secondsToFull := (rl.capacity - bucket.tokens) / rl.rate
+	reset := now.Add(time.Duration(secondsToFull * float64(time.Second)))
+	return allowed, int(bucket.tokens), reset
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute, so the map
+// only holds recently active users
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	rl.lastSweep = now
+
+	for key, bucket := range rl.buckets {
+		if bucket.tokens+now.Sub(bucket.lastFill).Seconds()*rl.rate >= rl.capacity {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// RateLimit limits each authenticated user to RATE_LIMIT_PER_MINUTE requests with bursts
+// of up to RATE_LIMIT_BURST, using a token bucket. Every response carries X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset (Unix time the bucket is full again) so
+// clients can throttle themselves; requests over the limit get 429 with Retry-After.
+// Anonymous demo visitors share the demo user, so they are limited per client IP instead.
+// Setting RATE_LIMIT_PER_MINUTE=0 disables it. Must run after AuthRequired.
+func RateLimit() gin.HandlerFunc {
+	perMinute := config.GetEnvInt("RATE_LIMIT_PER_MINUTE", 300)
+	if perMinute <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	burst := config.GetEnvInt("RATE_LIMIT_BURST", perMinute)
+	if burst <= 0 {
+		burst = perMinute
+	}
+
+	limiter := &rateLimiter{
+		capacity: float64(burst),
+		rate:     float64(perMinute) / 60,
+		buckets:  make(map[string]*tokenBucket),
+	}
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		key := strconv.FormatUint(uint64(userID.(uint)), 10)
+		if c.GetBool("demo") {
+			key = "demo:" + c.ClientIP()
+		}
+
+		now := time.Now()
+		allowed, remaining, reset := limiter.take(key, now)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if !allowed {
+			// Time until the next token is available
+			retryAfter := int(math.Ceil(60 / float64(perMinute)))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"message": "Rate limit exceeded, please slow down",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	})
+}
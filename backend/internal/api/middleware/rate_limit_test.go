@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitedRouter serves GET /ping through RateLimit, as the user in the X-User header
+// (anonymous when missing), marking requests demo ones when X-Demo is set
+func rateLimitedRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if header := c.GetHeader("X-User"); header != "" {
+			id, _ := strconv.Atoi(header)
+			c.Set("user_id", uint(id))
+		}
+		if c.GetHeader("X-Demo") != "" {
+			c.Set("demo", true)
+		}
+		c.Next()
+	})
+	router.Use(RateLimit())
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// ping sends GET /ping with the given headers
+func ping(router http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "60")
+	t.Setenv("RATE_LIMIT_BURST", "3")
+	router := rateLimitedRouter()
+	user := map[string]string{"X-User": "1"}
+
+	for _, wantRemaining := range []string{"2", "1", "0"} {
+		w := ping(router, user)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 within the burst", w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("X-RateLimit-Remaining = %q, want %q", got, wantRemaining)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("X-RateLimit-Limit = %q, want the burst of 3", got)
+		}
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil || reset < time.Now().Unix() {
+			t.Errorf("X-RateLimit-Reset = %q, want a Unix time that isn't in the past", w.Header().Get("X-RateLimit-Reset"))
+		}
+	}
+
+	w := ping(router, user)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 once the burst is used", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Retry-After = %q, remaining = %q; want 1 and 0", w.Header().Get("Retry-After"), w.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	// Other users and demo visitors have buckets of their own
+	if w := ping(router, map[string]string{"X-User": "2"}); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "2" {
+		t.Errorf("another user: status = %d, remaining = %q; want 200 and 2", w.Code, w.Header().Get("X-RateLimit-Remaining"))
+	}
+	if w := ping(router, map[string]string{"X-User": "1", "X-Demo": "1"}); w.Code != http.StatusOK {
+		t.Errorf("demo visitor: status = %d, want 200", w.Code)
+	}
+
+	// Anonymous requests aren't limited here
+	if w := ping(router, nil); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("anonymous: status = %d, limit header %q; want 200 and no headers", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "0")
+	router := rateLimitedRouter()
+
+	for i := 0; i < 5; i++ {
+		if w := ping(router, map[string]string{"X-User": "1"}); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("status = %d, limit header %q; want 200 and no headers", w.Code, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	limiter := &rateLimiter{capacity: 2, rate: 1, buckets: make(map[string]*tokenBucket)}
+	start := time.Now()
+
+	limiter.take("user", start)
+	limiter.take("user", start)
+	if allowed, _, _ := limiter.take("user", start); allowed {
+		t.Fatal("took a token from an empty bucket")
+	}
+
+	allowed, remaining, reset := limiter.take("user", start.Add(1500*time.Millisecond))
+	if !allowed || remaining != 0 {
+		t.Errorf("after 1.5s: allowed %v, remaining %d; want a token taken and 0 left", allowed, remaining)
+	}
+	// Half a token is left, so the bucket is full again 1.5s later
+	if want := start.Add(3 * time.Second); !reset.Equal(want) {
+		t.Errorf("reset = %v, want %v", reset.Sub(start), want.Sub(start))
+	}
+}
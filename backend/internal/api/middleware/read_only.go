@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"skyell-backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readOnlyExempt are the write endpoints still served in read-only mode, so users can
+// sign in and keep their sessions while writes are blocked, and admins can still pause
+// and resume the crawl queue, which doesn't write to the database
+var readOnlyExempt = map[string]bool{
+	"/api/v1/auth/login":         true,
+	"/api/v1/auth/refresh":       true,
+	"/api/v1/admin/queue/pause":  true,
+	"/api/v1/admin/queue/resume": true,
+}
+
+// ReadOnly rejects writes with 503 while READ_ONLY=true, e.g. during migrations.
+// GET, HEAD and OPTIONS requests are still served.
+func ReadOnly() gin.HandlerFunc {
+	if !config.GetEnvBool("READ_ONLY", false) {
+		return func(c *gin.Context) { c.Next() }
+	}
+	log.Println("READ_ONLY is enabled: write requests will be rejected with 503")
+
+	return gin.HandlerFunc(func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if readOnlyExempt[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "The service is in read-only maintenance mode; changes are temporarily disabled",
+		})
+		c.Abort()
+	})
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readOnlyRouter serves a few API routes behind ReadOnly, each answering 200
+func readOnlyRouter() *gin.Engine {
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(ReadOnly())
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/urls", ok)
+	api.POST("/urls", ok)
+	api.DELETE("/urls/:id", ok)
+	api.POST("/auth/login", ok)
+	api.POST("/auth/refresh", ok)
+	api.POST("/auth/register", ok)
+	api.POST("/admin/queue/pause", ok)
+	api.POST("/admin/queue/resume", ok)
+	return router
+}
+
+func TestReadOnly(t *testing.T) {
+	tests := []struct {
+		method, target string
+		readOnly       int // Status in read-only mode
+	}{
+		{http.MethodGet, "/api/v1/urls", http.StatusOK},
+		{http.MethodPost, "/api/v1/urls", http.StatusServiceUnavailable},
+		{http.MethodDelete, "/api/v1/urls/1", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/auth/register", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/v1/auth/login", http.StatusOK},
+		{http.MethodPost, "/api/v1/auth/refresh", http.StatusOK},
+		{http.MethodPost, "/api/v1/admin/queue/pause", http.StatusOK},
+		{http.MethodPost, "/api/v1/admin/queue/resume", http.StatusOK},
+	}
+
+	for _, enabled := range []bool{true, false} {
+		t.Setenv("READ_ONLY", strconv.FormatBool(enabled))
+		router := readOnlyRouter()
+
+		for _, tt := range tests {
+			want := http.StatusOK
+			if enabled {
+				want = tt.readOnly
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+			if w.Code != want {
+				t.Errorf("READ_ONLY=%v: %s %s: status = %d, want %d", enabled, tt.method, tt.target, w.Code, want)
+			}
+		}
+	}
+}
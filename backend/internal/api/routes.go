@@ -13,55 +13,125 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	authHandler := handlers.NewAuthHandler(db)
 	urlHandler := handlers.NewURLHandler(db)
 	crawlHandler := handlers.NewCrawlHandler(db)
+	urlHandler.UseStatusCache(crawlHandler.CrawlerService())
+	tagHandler := handlers.NewTagHandler(db)
 
-	// API v1 group
+	// API v1 group; writes are rejected while in read-only mode
 	api := r.Group("/api/v1")
-	
+	api.Use(middleware.ReadOnly())
+
+	// Machine-readable API description (public)
+	api.GET("/openapi.json", handlers.OpenAPI)
+
 	// Authentication routes (public)
 	auth := api.Group("/auth")
 	{
 		auth.POST("/register", authHandler.Register)
 		auth.POST("/login", authHandler.Login)
 		auth.POST("/refresh", authHandler.RefreshToken)
+		auth.POST("/forgot-password", authHandler.ForgotPassword)
+		auth.POST("/reset-password", authHandler.ResetPassword)
+		auth.PATCH("/me", middleware.AuthRequired(), authHandler.UpdateProfile)
+		auth.PUT("/webhook", middleware.AuthRequired(), authHandler.SetWebhook)
+		auth.DELETE("/webhook", middleware.AuthRequired(), authHandler.DeleteWebhook)
+		auth.POST("/api-keys", middleware.AuthRequired(), authHandler.CreateAPIKey)
+		auth.GET("/api-keys", middleware.AuthRequired(), authHandler.ListAPIKeys)
+		auth.DELETE("/api-keys/:id", middleware.AuthRequired(), authHandler.RevokeAPIKey)
+	}
+
+	// One limiter for every route, so a user's requests all draw from the same bucket
+	rateLimit := middleware.RateLimit()
+
+	// Results readable without signing in when DEMO_MODE is on, as the shared demo user
+	demoResults := api.Group("/results")
+	demoResults.Use(middleware.APIKeyAuth(db), middleware.OptionalAuth(), middleware.DemoAccess(), rateLimit)
+	{
+		demoResults.GET("", urlHandler.GetResults)          // GET /api/v1/results - paginated results
+		demoResults.GET("/:id", urlHandler.GetResultDetail) // GET /api/v1/results/:id - detailed result
 	}
 
 	// Protected routes - require authentication
 	protected := api.Group("")
-	protected.Use(middleware.AuthRequired())
+	protected.Use(middleware.APIKeyAuth(db), middleware.AuthRequired(), middleware.DemoReadOnly(), rateLimit)
 	{
 		// URL management endpoints
 		urls := protected.Group("/urls")
 		{
-			urls.GET("", urlHandler.GetURLs)           // GET /api/v1/urls - list user's URLs
-			urls.POST("", urlHandler.CreateURL)        // POST /api/v1/urls - add new URL
-			urls.GET("/:id", urlHandler.GetURL)        // GET /api/v1/urls/:id - get specific URL
-			urls.PUT("/:id", urlHandler.UpdateURL)     // PUT /api/v1/urls/:id - update URL
-			urls.DELETE("/:id", urlHandler.DeleteURL)  // DELETE /api/v1/urls/:id - delete URL
-			urls.DELETE("", urlHandler.BulkDeleteURLs) // DELETE /api/v1/urls - bulk delete URLs
+			urls.GET("", urlHandler.GetURLs)                      // GET /api/v1/urls - list user's URLs
+			urls.POST("", urlHandler.CreateURL)                   // POST /api/v1/urls - add new URL
+			urls.POST("/validate", crawlHandler.ValidateURL)      // POST /api/v1/urls/validate - check a URL is reachable without saving it
+			urls.GET("/trash", urlHandler.GetTrashedURLs)         // GET /api/v1/urls/trash - list soft-deleted URLs
+			urls.GET("/:id", urlHandler.GetURL)                   // GET /api/v1/urls/:id - get specific URL
+			urls.PUT("/:id", urlHandler.UpdateURL)                // PUT /api/v1/urls/:id - update URL
+			urls.DELETE("/:id", urlHandler.DeleteURL)             // DELETE /api/v1/urls/:id - delete URL
+			urls.POST("/:id/restore", urlHandler.RestoreURL)      // POST /api/v1/urls/:id/restore - restore deleted URL
+			urls.GET("/:id/compare", urlHandler.CompareResults)   // GET /api/v1/urls/:id/compare - diff two crawl results
+			urls.GET("/:id/events", urlHandler.GetURLEvents)      // GET /api/v1/urls/:id/events - crawl audit trail
+			urls.GET("/:id/trend", urlHandler.GetURLTrend)        // GET /api/v1/urls/:id/trend - link counts over time
+			urls.DELETE("", urlHandler.BulkDeleteURLs)            // DELETE /api/v1/urls - bulk delete URLs
+			urls.POST("/:id/tags", tagHandler.AttachTag)          // POST /api/v1/urls/:id/tags - attach tag to URL
+			urls.DELETE("/:id/tags/:tagId", tagHandler.DetachTag) // DELETE /api/v1/urls/:id/tags/:tagId - detach tag from URL
+		}
+
+		// Tag endpoints
+		tags := protected.Group("/tags")
+		{
+			tags.GET("", tagHandler.GetTags)    // GET /api/v1/tags - list user's tags
+			tags.POST("", tagHandler.CreateTag) // POST /api/v1/tags - create tag
 		}
 
 		// Crawl control endpoints
 		crawl := protected.Group("/crawl")
 		{
-			crawl.POST("/start/:id", crawlHandler.StartCrawl)     // POST /api/v1/crawl/start/:id - start crawling URL
-			crawl.POST("/stop/:id", crawlHandler.StopCrawl)       // POST /api/v1/crawl/stop/:id - stop crawling URL
-			crawl.POST("/bulk-start", crawlHandler.BulkStartCrawl) // POST /api/v1/crawl/bulk-start - start multiple crawls
-			crawl.POST("/bulk-stop", crawlHandler.BulkStopCrawl)   // POST /api/v1/crawl/bulk-stop - stop multiple crawls
+			crawl.POST("/start/:id", middleware.Idempotency(db), crawlHandler.StartCrawl)      // POST /api/v1/crawl/start/:id - start crawling URL
+			crawl.POST("/stop/:id", crawlHandler.StopCrawl)                                    // POST /api/v1/crawl/stop/:id - stop crawling URL
+			crawl.POST("/bulk-start", middleware.Idempotency(db), crawlHandler.BulkStartCrawl) // POST /api/v1/crawl/bulk-start - start multiple crawls
+			crawl.POST("/bulk-stop", crawlHandler.BulkStopCrawl)                               // POST /api/v1/crawl/bulk-stop - stop multiple crawls
+			crawl.POST("/stop-all", crawlHandler.StopAllCrawls)                                // POST /api/v1/crawl/stop-all - stop all running crawls
 		}
 
 		// Results endpoints
 		results := protected.Group("/results")
 		{
-			results.GET("", urlHandler.GetResults)           // GET /api/v1/results - paginated results
-			results.GET("/:id", urlHandler.GetResultDetail)  // GET /api/v1/results/:id - detailed result
-			results.GET("/:id/links", urlHandler.GetLinks)   // GET /api/v1/results/:id/links - links for result
+			results.GET("/stream", crawlHandler.StreamStatus)    // GET /api/v1/results/stream - crawl status changes as server-sent events
+			results.GET("/recent", urlHandler.GetRecentResults)  // GET /api/v1/results/recent - recently viewed results
+			results.DELETE("/prune", urlHandler.PruneResults)    // DELETE /api/v1/results/prune - delete old results
+			results.DELETE("/:id", urlHandler.DeleteResult)      // DELETE /api/v1/results/:id - delete a result and its links
+			results.GET("/:id/links", urlHandler.GetLinks)       // GET /api/v1/results/:id/links - links for result
+			results.GET("/:id/export", urlHandler.ExportResult)  // GET /api/v1/results/:id/export - export result as JSON or CSV
+			results.GET("/:id/snapshot", urlHandler.GetSnapshot) // GET /api/v1/results/:id/snapshot - HTML the crawler analyzed
+		}
+
+		// Links across all of the user's results
+		protected.GET("/links", urlHandler.GetAllLinks) // GET /api/v1/links - search links across all results
+
+		// Full account export
+		protected.GET("/export/all", urlHandler.ExportAll) // GET /api/v1/export/all - NDJSON of all URLs, results and links
+
+		// Dashboard stats
+		protected.GET("/stats", urlHandler.GetStats) // GET /api/v1/stats - aggregate stats for the dashboard
+
+		// Crawl queue administration (users listed in ADMIN_USER_IDS)
+		admin := protected.Group("/admin")
+		admin.Use(middleware.AdminOnly(db))
+		{
+			admin.GET("/queue", crawlHandler.GetQueueStatus)      // GET /api/v1/admin/queue - queue depth and worker states
+			admin.POST("/queue/pause", crawlHandler.PauseQueue)   // POST /api/v1/admin/queue/pause - stop starting queued crawls
+			admin.POST("/queue/resume", crawlHandler.ResumeQueue) // POST /api/v1/admin/queue/resume - start queued crawls again
 		}
 
 		// Status endpoints for real-time updates
 		status := protected.Group("/status")
 		{
-			status.GET("/urls", urlHandler.GetURLsStatus)     // GET /api/v1/status/urls - get all URLs status
-			status.GET("/url/:id", urlHandler.GetURLStatus)   // GET /api/v1/status/url/:id - get specific URL status
+			status.GET("/urls", urlHandler.GetURLsStatus)        // GET /api/v1/status/urls - get all URLs status
+			status.GET("/url/:id", urlHandler.GetURLStatus)      // GET /api/v1/status/url/:id - get specific URL status
+			status.GET("/crawl", crawlHandler.GetCrawlStatus)    // GET /api/v1/status/crawl - URL statuses with a per-status summary
+			status.POST("/batch", crawlHandler.BatchCrawlStatus) // POST /api/v1/status/batch - statuses of the URL IDs in the body
 		}
 	}
-} 
\ No newline at end of file
+
+	// JSON errors for unknown routes and unsupported methods, matching the API's response shape
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(handlers.NotFound)
+	r.NoMethod(handlers.MethodNotAllowed)
+}
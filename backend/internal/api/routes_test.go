@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUnknownRoutesAndMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, testutil.NewDB(t))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantCode   string
+		wantAllow  string
+	}{
+		{"unknown path", http.MethodGet, "/api/v1/nothing-here", http.StatusNotFound, "not_found", ""},
+		{"outside the API", http.MethodGet, "/favicon.ico", http.StatusNotFound, "not_found", ""},
+		{"wrong method", http.MethodGet, "/api/v1/auth/login", http.StatusMethodNotAllowed, "method_not_allowed", "POST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var body struct {
+				Success *bool  `json:"success"`
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %q isn't JSON: %v", w.Body, err)
+			}
+			if body.Success == nil || *body.Success || body.Message == "" || body.Code != tt.wantCode {
+				t.Errorf("body = %s, want success false, a message and code %q", w.Body, tt.wantCode)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocumentMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	SetupRoutes(router, testutil.NewDB(t))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("document isn't JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x document", doc.OpenAPI)
+	}
+
+	documented := make(map[string]bool)
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for _, known := range []string{"POST /auth/login", "GET /urls", "GET /urls/{id}", "POST /crawl/start/{id}", "GET /results/{id}", "GET /status/urls"} {
+		if !documented[known] {
+			t.Errorf("%s isn't documented", known)
+		}
+	}
+
+	// Every API route is documented, and nothing that doesn't exist is
+	served := make(map[string]bool)
+	for _, route := range router.Routes() {
+		path, ok := strings.CutPrefix(route.Path, "/api/v1")
+		if !ok || path == "/openapi.json" {
+			continue
+		}
+		served[route.Method+" "+ginParam.ReplaceAllString(path, "{$1}")] = true
+	}
+	for route := range served {
+		if !documented[route] {
+			t.Errorf("%s is served but not documented", route)
+		}
+	}
+	for route := range documented {
+		if !served[route] {
+			t.Errorf("%s is documented but not served", route)
+		}
+	}
+
+	// Every schema reference resolves
+	for _, ref := range schemaRef.FindAllStringSubmatch(w.Body.String(), -1) {
+		if _, ok := doc.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("reference to the undefined schema %s", ref[1])
+		}
+	}
+}
+
+func TestRateLimitIsSharedAcrossRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "60")
+	t.Setenv("RATE_LIMIT_BURST", "10")
+	db := testutil.NewDB(t)
+	router := gin.New()
+	SetupRoutes(router, db)
+
+	user := models.User{Username: "limited", Email: "limited@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	authorization := bearer(t, user.ID)
+
+	// Results and URLs sit in different route groups but draw from the same bucket
+	for i, target := range []string{"/api/v1/results", "/api/v1/urls", "/api/v1/results", "/api/v1/urls"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, want 200: %s", target, w.Code, w.Body)
+		}
+		if remaining, want := w.Header().Get("X-RateLimit-Remaining"), strconv.Itoa(9-i); remaining != want {
+			t.Errorf("request %d (GET %s): X-RateLimit-Remaining = %s, want %s", i+1, target, remaining, want)
+		}
+	}
+}
+
+var (
+	ginParam  = regexp.MustCompile(`:(\w+)`)
+	schemaRef = regexp.MustCompile(`"#/components/schemas/(\w+)"`)
+)
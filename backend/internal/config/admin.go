@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AdminUserIDs reads ADMIN_USER_IDS, the comma-separated IDs of the users allowed to use
+// the /admin endpoints. IDs are assigned by the server, so unlike an email a user can't
+// claim one to become an admin. Invalid entries are reported as an error and skipped.
+func AdminUserIDs() ([]uint, error) {
+	var ids []uint
+	var invalid []string
+	for _, entry := range GetEnvList("ADMIN_USER_IDS", nil) {
+		id, err := strconv.ParseUint(entry, 10, 32)
+		if err != nil || id == 0 {
+			invalid = append(invalid, entry)
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(invalid) > 0 {
+		return ids, fmt.Errorf("ADMIN_USER_IDS must list user IDs, got %q", invalid)
+	}
+	return ids, nil
+}
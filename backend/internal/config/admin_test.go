@@ -0,0 +1,27 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAdminUserIDs(t *testing.T) {
+	t.Setenv("ADMIN_USER_IDS", "3, 17,3")
+	if ids, err := AdminUserIDs(); err != nil || !slices.Equal(ids, []uint{3, 17, 3}) {
+		t.Errorf("AdminUserIDs() = %v, %v, want [3 17 3]", ids, err)
+	}
+
+	t.Setenv("ADMIN_USER_IDS", "3,boss@example.com,0,-1")
+	ids, err := AdminUserIDs()
+	if err == nil {
+		t.Error("invalid entries were accepted")
+	}
+	if !slices.Equal(ids, []uint{3}) {
+		t.Errorf("AdminUserIDs() = %v, want the valid [3] kept", ids)
+	}
+
+	t.Setenv("ADMIN_USER_IDS", "")
+	if ids, err := AdminUserIDs(); err != nil || len(ids) != 0 {
+		t.Errorf("AdminUserIDs() = %v, %v, want no admins when unset", ids, err)
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "strings"
+
+// CrawlDomainAllowed reports whether a host may be crawled under CRAWL_DOMAIN_ALLOWLIST.
+// Entries match a host exactly, or any of its subdomains when written as *.example.com.
+// When the list is unset every host is allowed.
+func CrawlDomainAllowed(host string) bool {
+	allowlist := GetEnvList("CRAWL_DOMAIN_ALLOWLIST", nil)
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range allowlist {
+		entry = strings.ToLower(entry)
+		if suffix, wildcard := strings.CutPrefix(entry, "*."); wildcard {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == entry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestCrawlDomainAllowed(t *testing.T) {
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "example.com, *.Example.org")
+	tests := map[string]bool{
+		"example.com":        true,
+		"EXAMPLE.com.":       true,
+		"www.example.com":    false,
+		"example.org":        false,
+		"docs.example.org":   true,
+		"a.b.example.org":    true,
+		"badexample.org":     false,
+		"example.org.evil.a": false,
+		"other.example":      false,
+	}
+	for host, want := range tests {
+		if got := CrawlDomainAllowed(host); got != want {
+			t.Errorf("CrawlDomainAllowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "")
+	if !CrawlDomainAllowed("anything.example") {
+		t.Error("a host was rejected with no allow-list set")
+	}
+}
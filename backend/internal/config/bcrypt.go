@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Costs outside this range are accepted but either weak or slow enough to stall logins
+const (
+	minRecommendedBcryptCost = 10
+	maxRecommendedBcryptCost = 14
+)
+
+// BcryptCost reads the password hashing cost from BCRYPT_COST, defaulting to
+// bcrypt.DefaultCost. Costs outside bcrypt's 4-31 range are reported as an error
+// and replaced by the default.
+func BcryptCost() (int, error) {
+	cost := GetEnvInt("BCRYPT_COST", bcrypt.DefaultCost)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost, fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	return cost, nil
+}
+
+// RecommendedBcryptCost reports whether a cost is within the recommended range
+func RecommendedBcryptCost(cost int) bool {
+	return cost >= minRecommendedBcryptCost && cost <= maxRecommendedBcryptCost
+}
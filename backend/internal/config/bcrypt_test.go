@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptCost(t *testing.T) {
+	tests := []struct {
+		env     string
+		want    int
+		wantErr bool
+	}{
+		{"", bcrypt.DefaultCost, false},
+		{"12", 12, false},
+		{"4", 4, false},
+		{"31", 31, false},
+		{"3", bcrypt.DefaultCost, true},
+		{"32", bcrypt.DefaultCost, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("BCRYPT_COST", tt.env)
+		cost, err := BcryptCost()
+		if cost != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("BCRYPT_COST=%q: got %d, %v; want %d, error %v", tt.env, cost, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRecommendedBcryptCost(t *testing.T) {
+	for cost, want := range map[int]bool{4: false, 9: false, 10: true, 14: true, 15: false} {
+		if got := RecommendedBcryptCost(cost); got != want {
+			t.Errorf("RecommendedBcryptCost(%d) = %v, want %v", cost, got, want)
+		}
+	}
+}
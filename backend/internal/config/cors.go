@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-contrib/cors"
+)
+
+var (
+	defaultAllowedOrigins = []string{"http://localhost:3005"} // Default for local development
+	defaultAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	defaultAllowedHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", "If-None-Match", "Idempotency-Key", "X-API-Key"}
+
+	// Pagination, caching and rate limit headers set by the API, readable by browser clients
+	exposedHeaders = []string{
+		"X-Total-Count", "X-Page", "X-Per-Page", "X-Total-Pages", "Link", "ETag", "Idempotent-Replayed",
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After",
+	}
+)
+
+// CORSConfig builds the CORS configuration from ALLOWED_ORIGINS, ALLOWED_METHODS,
+// ALLOWED_HEADERS, ALLOW_CREDENTIALS and CORS_MAX_AGE
+func CORSConfig() (cors.Config, error) {
+	config := cors.DefaultConfig()
+	config.AllowMethods = GetEnvList("ALLOWED_METHODS", defaultAllowedMethods)
+	config.AllowHeaders = GetEnvList("ALLOWED_HEADERS", defaultAllowedHeaders)
+	config.ExposeHeaders = exposedHeaders
+	config.AllowCredentials = GetEnvBool("ALLOW_CREDENTIALS", true)
+	config.MaxAge = GetEnvDuration("CORS_MAX_AGE", 12*time.Hour)
+
+	origins := GetEnvList("ALLOWED_ORIGINS", defaultAllowedOrigins)
+	for _, origin := range origins {
+		if origin != "*" {
+			continue
+		}
+
+		// Browsers reject credentialed requests to a wildcard origin
+		if config.AllowCredentials {
+			return config, fmt.Errorf("ALLOWED_ORIGINS=* cannot be combined with ALLOW_CREDENTIALS=true")
+		}
+		if len(origins) > 1 {
+			return config, fmt.Errorf("ALLOWED_ORIGINS=* cannot be combined with other origins")
+		}
+
+		config.AllowAllOrigins = true
+		return config, config.Validate()
+	}
+
+	config.AllowOrigins = origins
+	return config, config.Validate()
+}
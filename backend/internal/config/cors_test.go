@@ -0,0 +1,80 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCORSConfigDefaults(t *testing.T) {
+	for _, key := range []string{"ALLOWED_ORIGINS", "ALLOWED_METHODS", "ALLOWED_HEADERS", "ALLOW_CREDENTIALS", "CORS_MAX_AGE"} {
+		t.Setenv(key, "")
+	}
+
+	config, err := CORSConfig()
+	if err != nil {
+		t.Fatalf("CORSConfig: %v", err)
+	}
+	if !slices.Equal(config.AllowOrigins, defaultAllowedOrigins) || config.AllowAllOrigins {
+		t.Errorf("origins = %v, want %v", config.AllowOrigins, defaultAllowedOrigins)
+	}
+	if !slices.Equal(config.AllowMethods, defaultAllowedMethods) || !slices.Equal(config.AllowHeaders, defaultAllowedHeaders) {
+		t.Errorf("methods, headers = %v, %v, want the defaults", config.AllowMethods, config.AllowHeaders)
+	}
+	if !config.AllowCredentials || config.MaxAge != 12*time.Hour {
+		t.Errorf("credentials, max age = %v, %v, want true, 12h", config.AllowCredentials, config.MaxAge)
+	}
+}
+
+func TestCORSConfigFromEnv(t *testing.T) {
+	t.Setenv("ALLOWED_ORIGINS", "https://app.example.com, https://admin.example.com")
+	t.Setenv("ALLOWED_METHODS", "GET,POST")
+	t.Setenv("ALLOWED_HEADERS", "Content-Type, Authorization")
+	t.Setenv("ALLOW_CREDENTIALS", "false")
+	t.Setenv("CORS_MAX_AGE", "10m")
+
+	config, err := CORSConfig()
+	if err != nil {
+		t.Fatalf("CORSConfig: %v", err)
+	}
+	if want := []string{"https://app.example.com", "https://admin.example.com"}; !slices.Equal(config.AllowOrigins, want) {
+		t.Errorf("origins = %v, want %v", config.AllowOrigins, want)
+	}
+	if want := []string{"GET", "POST"}; !slices.Equal(config.AllowMethods, want) {
+		t.Errorf("methods = %v, want %v", config.AllowMethods, want)
+	}
+	if want := []string{"Content-Type", "Authorization"}; !slices.Equal(config.AllowHeaders, want) {
+		t.Errorf("headers = %v, want %v", config.AllowHeaders, want)
+	}
+	if config.AllowCredentials || config.MaxAge != 10*time.Minute {
+		t.Errorf("credentials, max age = %v, %v, want false, 10m", config.AllowCredentials, config.MaxAge)
+	}
+}
+
+func TestCORSConfigWildcardOrigin(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     string
+		credentials string
+		wantErr     bool
+	}{
+		{"without credentials", "*", "false", false},
+		{"with credentials", "*", "true", true},
+		{"credentials by default", "*", "", true},
+		{"mixed with other origins", "*,https://app.example.com", "false", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOWED_ORIGINS", tt.origins)
+			t.Setenv("ALLOW_CREDENTIALS", tt.credentials)
+
+			config, err := CORSConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CORSConfig error = %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (!config.AllowAllOrigins || config.AllowCredentials) {
+				t.Errorf("config = %+v, want all origins without credentials", config)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PaginationConfig holds the page sizes used by the list endpoints
+type PaginationConfig struct {
+	DefaultPageSize      int // URLs and crawl results
+	DefaultLinksPageSize int // Links of a result
+	MaxPageSize          int
+	MaxOffset            int // Rows a page may start after; 0 allows any depth
+}
+
+// Pagination reads DEFAULT_PAGE_SIZE, DEFAULT_LINKS_PAGE_SIZE, MAX_PAGE_SIZE and MAX_OFFSET.
+// A default larger than the max is reported as an error and lowered to the max; every
+// invalid setting is reported.
+func Pagination() (PaginationConfig, error) {
+	p := PaginationConfig{
+		DefaultPageSize:      GetEnvInt("DEFAULT_PAGE_SIZE", 10),
+		DefaultLinksPageSize: GetEnvInt("DEFAULT_LINKS_PAGE_SIZE", 50),
+		MaxPageSize:          GetEnvInt("MAX_PAGE_SIZE", 100),
+		MaxOffset:            GetEnvInt("MAX_OFFSET", 10000),
+	}
+
+	if p.MaxPageSize < 1 {
+		return p, fmt.Errorf("MAX_PAGE_SIZE must be at least 1, got %d", p.MaxPageSize)
+	}
+
+	var errs []error
+	if p.DefaultPageSize < 1 || p.DefaultPageSize > p.MaxPageSize {
+		errs = append(errs, fmt.Errorf("DEFAULT_PAGE_SIZE must be between 1 and MAX_PAGE_SIZE (%d), got %d", p.MaxPageSize, p.DefaultPageSize))
+		p.DefaultPageSize = min(max(p.DefaultPageSize, 1), p.MaxPageSize)
+	}
+	if p.DefaultLinksPageSize < 1 || p.DefaultLinksPageSize > p.MaxPageSize {
+		errs = append(errs, fmt.Errorf("DEFAULT_LINKS_PAGE_SIZE must be between 1 and MAX_PAGE_SIZE (%d), got %d", p.MaxPageSize, p.DefaultLinksPageSize))
+		p.DefaultLinksPageSize = min(max(p.DefaultLinksPageSize, 1), p.MaxPageSize)
+	}
+	if p.MaxOffset < 0 {
+		errs = append(errs, fmt.Errorf("MAX_OFFSET must not be negative, got %d", p.MaxOffset))
+		p.MaxOffset = 10000
+	}
+	return p, errors.Join(errs...)
+}
+
+// Limit resolves a requested page size: missing or invalid values fall back to def
+// and values above the max are clamped to it
+func (p PaginationConfig) Limit(requested, def int) int {
+	if requested < 1 {
+		return def
+	}
+	return min(requested, p.MaxPageSize)
+}
+
+// TooDeep reports whether the given page starts more than MaxOffset rows into the list.
+// It compares without multiplying, so huge page numbers can't overflow.
+func (p PaginationConfig) TooDeep(page, limit int) bool {
+	return p.MaxOffset > 0 && page-1 > p.MaxOffset/limit
+}
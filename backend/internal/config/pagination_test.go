@@ -0,0 +1,112 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    PaginationConfig
+		wantErr string
+	}{
+		{"defaults", nil, PaginationConfig{DefaultPageSize: 10, DefaultLinksPageSize: 50, MaxPageSize: 100, MaxOffset: 10000}, ""},
+		{
+			"from env",
+			map[string]string{"DEFAULT_PAGE_SIZE": "25", "DEFAULT_LINKS_PAGE_SIZE": "200", "MAX_PAGE_SIZE": "500", "MAX_OFFSET": "0"},
+			PaginationConfig{DefaultPageSize: 25, DefaultLinksPageSize: 200, MaxPageSize: 500, MaxOffset: 0},
+			"",
+		},
+		{
+			"default above the max",
+			map[string]string{"DEFAULT_PAGE_SIZE": "30", "MAX_PAGE_SIZE": "20"},
+			PaginationConfig{DefaultPageSize: 20, DefaultLinksPageSize: 20, MaxPageSize: 20, MaxOffset: 10000},
+			"DEFAULT_PAGE_SIZE must be between 1 and MAX_PAGE_SIZE (20), got 30\nDEFAULT_LINKS_PAGE_SIZE",
+		},
+		{
+			"default below one",
+			map[string]string{"DEFAULT_PAGE_SIZE": "0"},
+			PaginationConfig{DefaultPageSize: 1, DefaultLinksPageSize: 50, MaxPageSize: 100, MaxOffset: 10000},
+			"DEFAULT_PAGE_SIZE",
+		},
+		{
+			"negative offset",
+			map[string]string{"MAX_OFFSET": "-1"},
+			PaginationConfig{DefaultPageSize: 10, DefaultLinksPageSize: 50, MaxPageSize: 100, MaxOffset: 10000},
+			"MAX_OFFSET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DEFAULT_PAGE_SIZE", "DEFAULT_LINKS_PAGE_SIZE", "MAX_PAGE_SIZE", "MAX_OFFSET"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got, err := Pagination()
+			if got != tt.want {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want one about %s", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("max below one", func(t *testing.T) {
+		t.Setenv("MAX_PAGE_SIZE", "0")
+		if _, err := Pagination(); err == nil {
+			t.Error("MAX_PAGE_SIZE=0 was accepted")
+		}
+	})
+}
+
+func TestPaginationLimit(t *testing.T) {
+	p := PaginationConfig{DefaultPageSize: 10, MaxPageSize: 100, MaxOffset: 1000}
+
+	for _, tt := range []struct{ requested, want int }{{0, 10}, {-5, 10}, {1, 1}, {100, 100}, {101, 100}, {1 << 30, 100}} {
+		if got := p.Limit(tt.requested, p.DefaultPageSize); got != tt.want {
+			t.Errorf("Limit(%d) = %d, want %d", tt.requested, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		page, limit int
+		want        bool
+	}{{1, 100, false}, {11, 100, false}, {12, 100, true}, {1 << 62, 10, true}} {
+		if got := p.TooDeep(tt.page, tt.limit); got != tt.want {
+			t.Errorf("TooDeep(%d, %d) = %v, want %v", tt.page, tt.limit, got, tt.want)
+		}
+	}
+	if (PaginationConfig{MaxOffset: 0}).TooDeep(1<<30, 100) {
+		t.Error("a MaxOffset of 0 should allow any depth")
+	}
+}
+
+func TestPaginationTooDeep(t *testing.T) {
+	p := PaginationConfig{MaxOffset: 100}
+	tests := []struct {
+		page, limit int
+		want        bool
+	}{
+		{1, 10, false},
+		{11, 10, false}, // Starts exactly 100 rows in
+		{12, 10, true},
+		{4, 30, false}, // 90 rows in
+		{5, 30, true},  // 120 rows in
+		{1 << 62, 100, true},
+	}
+	for _, tt := range tests {
+		if got := p.TooDeep(tt.page, tt.limit); got != tt.want {
+			t.Errorf("TooDeep(%d, %d) = %v, want %v", tt.page, tt.limit, got, tt.want)
+		}
+	}
+
+	if (PaginationConfig{}).TooDeep(1<<62, 100) {
+		t.Error("a MaxOffset of 0 limited the depth")
+	}
+}
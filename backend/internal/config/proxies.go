@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// TrustedProxies returns TRUSTED_PROXIES, the addresses (IPs or CIDR ranges) of the
+// reverse proxies whose X-Forwarded-* headers are honoured. None are trusted when unset.
+func TrustedProxies() ([]string, error) {
+	proxies := GetEnvList("TRUSTED_PROXIES", nil)
+	for _, proxy := range proxies {
+		if _, err := parseProxy(proxy); err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", proxy)
+		}
+	}
+	return proxies, nil
+}
+
+// TrustedProxy reports whether ip, the address a request came from, is in TRUSTED_PROXIES
+func TrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, proxy := range GetEnvList("TRUSTED_PROXIES", nil) {
+		if prefix, err := parseProxy(proxy); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseProxy parses a TRUSTED_PROXIES entry, treating a bare IP as a single-address range
+func parseProxy(proxy string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(proxy); err == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	return netip.ParsePrefix(proxy)
+}
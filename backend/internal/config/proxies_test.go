@@ -0,0 +1,44 @@
+package config
+
+import "testing"
+
+func TestTrustedProxy(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1, 2001:db8::/32")
+	tests := map[string]bool{
+		"10.1.2.3":         true,
+		"::ffff:10.1.2.3":  true,
+		"192.0.2.1":        true,
+		"192.0.2.2":        false,
+		"2001:db8::1":      true,
+		"2001:db9::1":      false,
+		"203.0.113.7":      false,
+		"":                 false,
+		"not-an-address":   false,
+		"192.0.2.1:443":    false,
+		"[2001:db8::1]:80": false,
+	}
+	for ip, want := range tests {
+		if got := TrustedProxy(ip); got != want {
+			t.Errorf("TrustedProxy(%q) = %v, want %v", ip, got, want)
+		}
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "")
+	if TrustedProxy("10.1.2.3") {
+		t.Error("a proxy was trusted with TRUSTED_PROXIES unset")
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+	if proxies, err := TrustedProxies(); err != nil || len(proxies) != 2 {
+		t.Errorf("TrustedProxies() = %v, %v, want both entries", proxies, err)
+	}
+
+	for _, invalid := range []string{"proxy.internal", "10.0.0.0/33", "192.0.2.1:8080"} {
+		t.Setenv("TRUSTED_PROXIES", invalid)
+		if _, err := TrustedProxies(); err == nil {
+			t.Errorf("TRUSTED_PROXIES=%s: want an error", invalid)
+		}
+	}
+}
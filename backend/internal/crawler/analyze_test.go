@@ -0,0 +1,374 @@
+package crawler
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"golang.org/x/net/html"
+)
+
+// serveHTML starts a test server answering every path but /favicon.ico with page
+func serveHTML(t *testing.T, page string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// analyzeHTML serves page from a test server and analyzes it at path, returning the
+// data and the server's base URL
+func analyzeHTML(t *testing.T, path, page string) (*CrawlData, string) {
+	t.Helper()
+	server := serveHTML(t, page)
+
+	data, err := NewCrawlerService(nil).fetchAndAnalyze(context.Background(), server.URL+path, RenderOptions{FollowRedirects: true})
+	if err != nil {
+		t.Fatalf("fetchAndAnalyze: %v", err)
+	}
+	return data, server.URL
+}
+
+func TestCanonicalAndOpenGraph(t *testing.T) {
+	data, base := analyzeHTML(t, "/article?utm_source=feed", `<!DOCTYPE html>
+<html><head>
+<link rel="canonical" href="/article">
+<link rel="canonical" href="https://elsewhere.example/ignored">
+<meta property="og:title" content=" Article title ">
+<meta property="OG:description" content="What the article is about">
+<meta property="og:image" content="https://cdn.example/cover.png">
+<meta property="og:image" content="https://cdn.example/second.png">
+</head><body></body></html>`)
+
+	if data.CanonicalURL != base+"/article" {
+		t.Errorf("canonical URL = %q, want the first canonical resolved to %q", data.CanonicalURL, base+"/article")
+	}
+	if !data.CanonicalMismatch {
+		t.Error("a canonical URL without the query string should be a mismatch")
+	}
+	if data.OGTitle != "Article title" {
+		t.Errorf("og:title = %q, want %q", data.OGTitle, "Article title")
+	}
+	if data.OGDescription != "What the article is about" {
+		t.Errorf("og:description = %q", data.OGDescription)
+	}
+	if data.OGImage != "https://cdn.example/cover.png" {
+		t.Errorf("og:image = %q, want the first one", data.OGImage)
+	}
+}
+
+func TestCanonicalMismatch(t *testing.T) {
+	final, _ := url.Parse("https://www.example.com/page")
+	tests := []struct {
+		canonical string
+		final     *url.URL
+		want      bool
+	}{
+		{"", nil, false},
+		{"https://example.com/page", nil, false},
+		{"HTTPS://Example.com:443/page#top", nil, false},
+		{"https://example.com/other", nil, true},
+		{"https://www.example.com/page", final, false}, // Compared with the page after redirects
+		{"https://example.com/page", final, true},
+	}
+	for _, tt := range tests {
+		if got := canonicalMismatch(tt.canonical, "https://example.com/page", tt.final); got != tt.want {
+			t.Errorf("canonicalMismatch(%q, final %v) = %v, want %v", tt.canonical, tt.final, got, tt.want)
+		}
+	}
+}
+
+func TestSEOBasicsMissing(t *testing.T) {
+	db := testutil.NewDB(t)
+	server := serveHTML(t, `<!DOCTYPE html>
+<html><head><meta name="viewport" content=""></head><body><p>No basics here</p></body></html>`)
+
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	var result models.CrawlResult
+	if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+		t.Fatalf("loading the crawl result: %v", err)
+	}
+	if result.HasTitle || result.HasFavicon || result.HasViewportMeta {
+		t.Errorf("has title %v, favicon %v, viewport meta %v; want all false",
+			result.HasTitle, result.HasFavicon, result.HasViewportMeta)
+	}
+}
+
+func TestSEOBasicsPresent(t *testing.T) {
+	data, _ := analyzeHTML(t, "/", `<!DOCTYPE html>
+<html><head>
+<title>Basics</title>
+<meta name="Viewport" content="width=device-width, initial-scale=1">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body></body></html>`)
+
+	if data.Title != "Basics" {
+		t.Errorf("title = %q, want %q", data.Title, "Basics")
+	}
+	if !data.HasViewportMeta {
+		t.Error("viewport meta was not detected")
+	}
+	if !data.HasFavicon {
+		t.Error(`favicon declared with rel="shortcut icon" was not detected`)
+	}
+}
+
+func TestFaviconFallsBackToDefaultPath(t *testing.T) {
+	var faviconMethod string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			faviconMethod = r.Method
+			w.Header().Set("Content-Type", "image/x-icon")
+			return
+		}
+		w.Write([]byte(`<html><head><title>Default icon</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	data, err := NewCrawlerService(nil).fetchAndAnalyze(context.Background(), server.URL+"/", RenderOptions{FollowRedirects: true})
+	if err != nil {
+		t.Fatalf("fetchAndAnalyze: %v", err)
+	}
+	if !data.HasFavicon {
+		t.Error("a favicon served at /favicon.ico was not detected")
+	}
+	if faviconMethod != http.MethodHead {
+		t.Errorf("favicon was requested with %q, want HEAD", faviconMethod)
+	}
+}
+
+func TestFaviconTimeoutDoesNotFailTheCrawl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`<html><head><title>Slow icon</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	cs := NewCrawlerService(nil)
+	cs.client.Timeout = 300 * time.Millisecond
+
+	data, err := cs.fetchAndAnalyze(context.Background(), server.URL+"/", RenderOptions{FollowRedirects: true})
+	if err != nil {
+		t.Fatalf("fetchAndAnalyze: %v", err)
+	}
+	if data.Title != "Slow icon" {
+		t.Errorf("title = %q, want the page to be analyzed", data.Title)
+	}
+	if data.HasFavicon {
+		t.Error("a favicon request that timed out counted as a favicon")
+	}
+}
+
+func TestClassifyForm(t *testing.T) {
+	tests := []struct {
+		name string
+		form string
+		want FormType
+	}{
+		{"login", `<form><input type="email" name="email"><input type="password" name="password"><button>Sign in</button></form>`, FormLogin},
+		{"login by action", `<form action="/session/signin"><input name="handle"><input type="password"></form>`, FormLogin},
+		{"signup with confirmation", `<form><input name="username"><input type="password" name="password"><input type="password" name="password_confirm"><input type="submit" value="Continue"></form>`, FormSignup},
+		{"signup by submit text", `<form><input type="email" name="email"><input type="password"><button type="submit">Create account</button></form>`, FormSignup},
+		{"signup by terms", `<form><input name="user"><input type="password"><input type="checkbox" name="accept_terms"><button>Go</button></form>`, FormSignup},
+		{"search", `<form action="/find"><input type="text" name="q"><input type="submit" value="Go"></form>`, FormSearch},
+		{"search input", `<form><input type="search" name="q"></form>`, FormSearch},
+		{"newsletter", `<form><input type="email" name="email"><button>Subscribe</button></form>`, FormOther},
+		{"contact", `<form><input name="name"><input name="subject"><textarea name="body"></textarea><button>Send</button></form>`, FormOther},
+	}
+
+	cs := NewCrawlerService(nil)
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.form))
+		if err != nil {
+			t.Fatalf("%s: parsing the form: %v", tt.name, err)
+		}
+		form := findElement(doc, "form")
+		if form == nil {
+			t.Fatalf("%s: no form in %q", tt.name, tt.form)
+		}
+		if got := cs.classifyForm(form); got != tt.want {
+			t.Errorf("%s: classifyForm = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyFormWithConfiguredLoginKeywords(t *testing.T) {
+	const german = `<form action="/konto"><input name="benutzername"><input type="password" name="kennwort"><button>Anmelden</button></form>`
+	const noPassword = `<form action="/login"><input name="benutzername"><button>Weiter</button></form>`
+	classify := func(cs *CrawlerService, markup string) FormType {
+		t.Helper()
+		doc, err := html.Parse(strings.NewReader(markup))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cs.classifyForm(findElement(doc, "form"))
+	}
+
+	if got := classify(NewCrawlerService(nil), german); got != FormOther {
+		t.Errorf("German login form with the default keywords = %q, want %q", got, FormOther)
+	}
+
+	t.Setenv("LOGIN_FIELD_KEYWORDS", " Benutzer , ,anmeldename")
+	cs := NewCrawlerService(nil)
+	if got := classify(cs, german); got != FormLogin {
+		t.Errorf("German login form with configured keywords = %q, want %q", got, FormLogin)
+	}
+	// A login form still needs a password field
+	if got := classify(cs, noPassword); got == FormLogin {
+		t.Errorf("form without a password = %q, want it not to be a login form", got)
+	}
+}
+
+func TestFormFlags(t *testing.T) {
+	tests := []struct {
+		name                     string
+		page                     string
+		login, signup, searching bool
+	}{
+		{"login page", `<form><input name="login"><input type="password"><button>Log in</button></form>`, true, false, false},
+		{"signup page", `<form><input type="email"><input type="password"><input type="password"><button>Register</button></form>`, false, true, false},
+		{"search page", `<form role="search"><input name="q"><button>Search</button></form>`, false, false, true},
+		{"header search and signup", `<form><input type="search" name="q"></form>
+<form><input name="user"><input type="password"><button>Sign up</button></form>`, false, true, true},
+		{"no forms", `<p>Nothing to fill in</p>`, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := analyzeHTML(t, "/", "<!DOCTYPE html><html><head><title>Forms</title></head><body>"+tt.page+"</body></html>")
+			if data.HasLoginForm != tt.login || data.HasSignupForm != tt.signup || data.HasSearchForm != tt.searching {
+				t.Errorf("login %v, signup %v, search %v; want %v, %v, %v",
+					data.HasLoginForm, data.HasSignupForm, data.HasSearchForm, tt.login, tt.signup, tt.searching)
+			}
+		})
+	}
+}
+
+// findElement returns the first element named tag in a depth-first walk from n
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestNonUTF8PagesAreDecoded(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantTitle   string
+		wantCharset string
+	}{
+		{
+			"charset in the header",
+			"text/html; charset=ISO-8859-1",
+			"<html><head><title>Caf\xe9 cr\xe8me</title></head><body></body></html>",
+			"Café crème", "windows-1252",
+		},
+		{
+			"charset in a meta tag",
+			"text/html",
+			`<html><head><meta charset="Shift_JIS"><title>` + "\x93\xfa\x96\x7b\x8c\xea" + `</title></head><body></body></html>`,
+			"日本語", "shift_jis",
+		},
+		{
+			"header wins over the meta tag",
+			"text/html; charset=windows-1252",
+			`<html><head><meta charset="utf-8"><title>` + "\x93quoted\x94" + `</title></head><body></body></html>`,
+			"“quoted”", "windows-1252",
+		},
+		{
+			"utf-8",
+			"text/html; charset=utf-8",
+			"<html><head><title>Café 日本語</title></head><body></body></html>",
+			"Café 日本語", "utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/favicon.ico" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			data, err := NewCrawlerService(nil).fetchAndAnalyze(context.Background(), server.URL+"/", RenderOptions{FollowRedirects: true})
+			if err != nil {
+				t.Fatalf("fetchAndAnalyze: %v", err)
+			}
+			if data.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", data.Title, tt.wantTitle)
+			}
+			if data.Charset != tt.wantCharset {
+				t.Errorf("charset = %q, want %q", data.Charset, tt.wantCharset)
+			}
+		})
+	}
+}
+
+func TestScriptAndStylesheetCounts(t *testing.T) {
+	data, base := analyzeHTML(t, "/blog/post", `<!DOCTYPE html>
+<html><head>
+<link rel="stylesheet" href="/css/site.css">
+<link rel="Preload Stylesheet" href="../blog/theme.css">
+<link rel="stylesheet" href="https://cdn.example/reset.css">
+<link rel="stylesheet" href="/css/site.css">
+<link rel="preload" href="/fonts/a.woff2">
+<link rel="stylesheet" href="  ">
+<script src="app.js"></script>
+<script type="module" src="https://cdn.example/lib.js"></script>
+<script src="/blog/app.js"></script>
+<script>window.ready = true;</script>
+<script type="module">import "./x.js";</script>
+<script type="application/ld+json">{"@type": "Article"}</script>
+<script>   </script>
+<style>body { margin: 0 }</style>
+</head><body><script type="text/javascript">track();</script></body></html>`)
+
+	wantScripts := []string{base + "/blog/app.js", "https://cdn.example/lib.js"}
+	wantStylesheets := []string{base + "/blog/theme.css", base + "/css/site.css", "https://cdn.example/reset.css"}
+	if got := slices.Sorted(maps.Keys(data.Scripts)); !slices.Equal(got, wantScripts) {
+		t.Errorf("scripts = %v, want %v", got, wantScripts)
+	}
+	if got := slices.Sorted(maps.Keys(data.Stylesheets)); !slices.Equal(got, wantStylesheets) {
+		t.Errorf("stylesheets = %v, want %v", got, wantStylesheets)
+	}
+	if data.InlineScriptCount != 3 {
+		t.Errorf("inline scripts = %d, want 3 (JSON-LD and empty scripts don't count)", data.InlineScriptCount)
+	}
+}
@@ -0,0 +1,119 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestStaleRunDoesNotUnregisterRestartedCrawl(t *testing.T) {
+	cs := NewCrawlerService(nil)
+
+	firstCtx, firstCancel := context.WithCancel(context.Background())
+	first := cs.register(1, firstCancel)
+	if !cs.Cancel(1) {
+		t.Fatal("Cancel found no running crawl")
+	}
+	if firstCtx.Err() == nil {
+		t.Fatal("Cancel didn't cancel the crawl")
+	}
+
+	// The URL is started again before the stopped run finishes
+	secondCtx, secondCancel := context.WithCancel(context.Background())
+	second := cs.register(1, secondCancel)
+	cs.unregister(1, first)
+
+	if !cs.Cancel(1) {
+		t.Fatal("the stopped run's unregister removed the restarted crawl")
+	}
+	if secondCtx.Err() == nil {
+		t.Error("Cancel didn't cancel the restarted crawl")
+	}
+
+	cs.unregister(1, second)
+	if cs.Cancel(1) {
+		t.Error("Cancel found a crawl after every run finished")
+	}
+}
+
+func TestStoppedCrawlKeepsPartialResult(t *testing.T) {
+	reached := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><head><title>Stopped</title></head><body>
+<a href="/ok">OK</a><a href="/missing">Missing</a><a href="/slow">Slow</a><a href="/never">Never</a>
+</body></html>`))
+		case "/ok":
+		case "/slow":
+			// Hold the check until the crawl is stopped
+			once.Do(func() { close(reached) })
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := db.Model(&urlEntry).Update("max_links_to_check", 10).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cs := NewCrawlerService(db)
+	done := make(chan error, 1)
+	go func() { done <- cs.CrawlURL(urlEntry.ID) }()
+
+	select {
+	case <-reached:
+	case <-time.After(10 * time.Second):
+		t.Fatal("link checks never reached the slow link")
+	}
+	// Stop the crawl the way StopCrawl does: reset the status, then cancel
+	if err := db.Model(&urlEntry).Update("status", models.StatusQueued).Error; err != nil {
+		t.Fatal(err)
+	}
+	cs.Cancel(urlEntry.ID)
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("CrawlURL = %v, want context.Canceled", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the stopped crawl didn't return")
+	}
+
+	var result models.CrawlResult
+	if err := db.Preload("Links").Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+		t.Fatalf("no partial result was saved: %v", err)
+	}
+	if !result.IsPartial || result.Title != "Stopped" {
+		t.Errorf("result partial %v, title %q; want a partial result of the analyzed page", result.IsPartial, result.Title)
+	}
+	// The links before the slow one were checked; the slow one was cut short and doesn't count
+	if result.LinksChecked != 2 || result.BrokenLinks != 1 {
+		t.Errorf("links checked %d, broken %d; want 2 and 1", result.LinksChecked, result.BrokenLinks)
+	}
+	if len(result.Links) != 4 {
+		t.Errorf("saved %d links, want all 4 found on the page", len(result.Links))
+	}
+
+	var saved models.URL
+	if err := db.First(&saved, urlEntry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.StatusQueued {
+		t.Errorf("status = %s, want %s as set by the stop", saved.Status, models.StatusQueued)
+	}
+}
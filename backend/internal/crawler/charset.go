@@ -0,0 +1,23 @@
+package crawler
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html/charset"
+)
+
+// decodeBody returns the page body as UTF-8 along with the charset it was declared in.
+// The charset comes from a byte order mark, the Content-Type header or a <meta charset>
+// tag, in that order of precedence, defaulting to windows-1252 as browsers do.
+func decodeBody(page *Page) ([]byte, string, error) {
+	encoding, name, _ := charset.DetermineEncoding(page.Body, page.Header.Get("Content-Type"))
+	if page.UTF8 || name == "utf-8" {
+		return page.Body, name, nil
+	}
+
+	body, err := encoding.NewDecoder().Bytes(page.Body)
+	if err != nil {
+		return nil, name, fmt.Errorf("failed to decode %s body: %w", name, err)
+	}
+	return body, name, nil
+}
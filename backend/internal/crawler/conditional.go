@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"errors"
+	"maps"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// errNotModified is returned by fetchAndAnalyze when the server answered a conditional
+// request with 304 Not Modified
+var errNotModified = errors.New("page not modified since the previous crawl")
+
+// previousResult loads the latest successful result of a URL's own page, or nil if there
+// is none
+func (cs *CrawlerService) previousResult(urlID uint) *models.CrawlResult {
+	var previous models.CrawlResult
+	if err := cs.db.
+		Where("url_id = ? AND parent_id IS NULL AND content_hash <> '' AND response_status < ? AND is_partial = ?", urlID, 400, false).
+		Order("created_at DESC, id DESC").
+		First(&previous).Error; err != nil {
+		return nil
+	}
+	return &previous
+}
+
+// conditionalOptions adds If-None-Match and If-Modified-Since headers built from the
+// previous result's validators, leaving the given options untouched
+func conditionalOptions(opts RenderOptions, previous *models.CrawlResult) RenderOptions {
+	if previous == nil || (previous.ETag == "" && previous.LastModified == "") {
+		return opts
+	}
+
+	opts.Headers = maps.Clone(opts.Headers)
+	if opts.Headers == nil {
+		opts.Headers = make(map[string]string)
+	}
+	if previous.ETag != "" {
+		opts.Headers["If-None-Match"] = previous.ETag
+	}
+	if previous.LastModified != "" {
+		opts.Headers["If-Modified-Since"] = previous.LastModified
+	}
+	return opts
+}
+
+// reuseResult records a crawl of an unmodified page as a copy of the previous result and
+// its links, marked as unchanged
+func (cs *CrawlerService) reuseResult(previous *models.CrawlResult) (*models.CrawlResult, error) {
+	result := *previous
+	result.ID = 0
+	result.CreatedAt = time.Time{}
+	result.UpdatedAt = time.Time{}
+	result.Changed = false
+	result.Links = nil
+
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&result).Error; err != nil {
+			return err
+		}
+		if err := copySnapshot(tx, previous.ID, result.ID); err != nil {
+			return err
+		}
+
+		var batch []models.Link
+		return tx.Where("crawl_result_id = ?", previous.ID).
+			Order("id asc").
+			FindInBatches(&batch, 500, func(batchTx *gorm.DB, _ int) error {
+				for i := range batch {
+					batch[i].ID = 0
+					batch[i].CrawlResultID = result.ID
+					batch[i].CreatedAt = time.Time{}
+					batch[i].UpdatedAt = time.Time{}
+				}
+				return tx.Create(&batch).Error
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -1,130 +1,660 @@
 package crawler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
 	"regexp"
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/metrics"
 	"skyell-backend/internal/models"
+	"skyell-backend/internal/netguard"
+	"skyell-backend/internal/secrets"
+	"skyell-backend/internal/tracing"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 	"golang.org/x/net/html"
 	"gorm.io/gorm"
 )
 
+// DefaultUserAgent identifies the crawler when CRAWLER_USER_AGENT isn't set
+const DefaultUserAgent = "Skyell-Crawler/1.0 (+https://skyell-fullstack.vercel.app)"
+
+// headingTags are always counted so the H1Count..H6Count columns stay populated
+var headingTags = []string{"h1", "h2", "h3", "h4", "h5", "h6"}
+
+// defaultCountedTags are the structural tags counted in addition to headings when CRAWLER_COUNTED_TAGS isn't set
+var defaultCountedTags = []string{"nav", "header", "footer", "main", "article", "section", "aside"}
+
+// defaultLoginFieldKeywords mark an input's name or id as a username field when LOGIN_FIELD_KEYWORDS isn't set
+var defaultLoginFieldKeywords = []string{"user", "email", "login"}
+
+// loginActionKeywords mark a form's action URL as a login endpoint
+var loginActionKeywords = []string{"login", "signin", "sign-in", "auth"}
+
+// defaultSkipLinkExtensions are file types not fetched during link checks when SKIP_LINK_EXTENSIONS isn't set
+var defaultSkipLinkExtensions = []string{
+	".pdf", ".zip", ".gz", ".tar", ".rar", ".7z", ".exe", ".dmg",
+	".jpg", ".jpeg", ".png", ".gif", ".webp", ".svg", ".ico",
+	".mp3", ".mp4", ".avi", ".mov", ".webm",
+}
+
 type CrawlerService struct {
-	db     *gorm.DB
-	client *http.Client
+	db           *gorm.DB
+	client       *http.Client
+	userAgent    string
+	extraHeaders map[string]string
+
+	// probeClient is the crawler client with connections to internal addresses refused, for probes
+	// of URLs users submit
+	probeClient *http.Client
+
+	// skipExtensions are lowercased file extensions excluded from link checks
+	skipExtensions map[string]bool
+
+	// countedTags are the lowercased element names tallied into CrawlData.TagCounts
+	countedTags map[string]bool
+
+	// loginKeywords are the lowercased name/id fragments of a login form's username field
+	loginKeywords []string
+
+	// checkMethod is how link checks probe a link (LINK_CHECK_METHOD)
+	checkMethod linkCheckMethod
+
+	// renderer fetches pages for analysis (raw HTTP or headless Chrome)
+	renderer Renderer
+
+	// statuses caches each user's status snapshot for polling clients
+	statuses *statusCache
+
+	// broker pushes status changes to streaming clients
+	broker *statusBroker
+
+	// queue runs claimed crawls on a bounded pool of workers
+	queue *crawlQueue
+
+	// cancels holds every in-flight crawl, keyed by URL ID
+	mu      sync.Mutex
+	cancels map[uint]*crawlRun
+}
+
+// crawlRun is one crawl of a URL; a restarted crawl of the same URL is a new run
+type crawlRun struct {
+	cancel context.CancelFunc
 }
 
 func NewCrawlerService(db *gorm.DB) *CrawlerService {
+	maxRedirects := config.GetEnvInt("MAX_REDIRECTS", 10)
+	if maxRedirects < 0 {
+		maxRedirects = 10
+	}
+
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: newTransport(),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Allow up to 10 redirects
-			if len(via) >= 10 {
-				return fmt.Errorf("stopped after 10 redirects")
+			// Allow up to the configured number of redirects
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			// CRAWL_DOMAIN_ALLOWLIST covers every page fetched, not just the URL submitted
+			if !config.CrawlDomainAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("redirected to %s, which is not allowed to be crawled", req.URL.Hostname())
 			}
 			return nil
 		},
 	}
 
-	return &CrawlerService{
-		db:     db,
-		client: client,
+	probeClient := &http.Client{
+		Timeout:       client.Timeout,
+		Transport:     netguard.Transport(client.Transport.(*http.Transport)),
+		CheckRedirect: client.CheckRedirect,
+	}
+
+	cs := &CrawlerService{
+		db:             db,
+		client:         client,
+		probeClient:    probeClient,
+		userAgent:      config.GetEnv("CRAWLER_USER_AGENT", DefaultUserAgent),
+		extraHeaders:   parseExtraHeaders(os.Getenv("CRAWLER_EXTRA_HEADERS")),
+		skipExtensions: parseSkipExtensions(config.GetEnvList("SKIP_LINK_EXTENSIONS", defaultSkipLinkExtensions)),
+		countedTags:    parseCountedTags(config.GetEnvList("CRAWLER_COUNTED_TAGS", defaultCountedTags)),
+		loginKeywords:  parseKeywords(config.GetEnvList("LOGIN_FIELD_KEYWORDS", defaultLoginFieldKeywords)),
+		checkMethod:    parseLinkCheckMethod(config.GetEnv("LINK_CHECK_METHOD", string(linkCheckHeadThenGet))),
+		statuses: newStatusCache(
+			config.GetEnvDuration("STATUS_CACHE_TTL", 5*time.Second),
+			config.GetEnvInt("STATUS_CACHE_MAX_USERS", 1000),
+		),
+		broker:  newStatusBroker(),
+		cancels: make(map[uint]*crawlRun),
+	}
+	cs.renderer = newRenderer(cs)
+	cs.queue = newCrawlQueue(
+		config.GetEnvInt("CRAWLER_MAX_CONCURRENT", 10),
+		config.GetEnvInt("MAX_CONCURRENT_CRAWLS_PER_USER", 0),
+		cs.runJob,
+	)
+
+	return cs
+}
+
+// parseSkipExtensions normalizes extensions like "PDF" or ".pdf" to ".pdf"
+func parseSkipExtensions(extensions []string) map[string]bool {
+	skip := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		skip[ext] = true
+	}
+	return skip
+}
+
+// parseCountedTags builds the set of counted tags, always including h1-h6
+func parseCountedTags(tags []string) map[string]bool {
+	counted := make(map[string]bool, len(headingTags)+len(tags))
+	for _, tag := range headingTags {
+		counted[tag] = true
 	}
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			counted[tag] = true
+		}
+	}
+	return counted
+}
+
+// parseKeywords lowercases keywords, dropping empty ones
+func parseKeywords(keywords []string) []string {
+	parsed := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			parsed = append(parsed, keyword)
+		}
+	}
+	return parsed
+}
+
+// shouldCheckLink reports whether a link is fetched during link checks; binary files
+// and non-HTTP links are still counted but never requested
+func (cs *CrawlerService) shouldCheckLink(link string) bool {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if linkURL.Scheme != "http" && linkURL.Scheme != "https" {
+		return false // data:, javascript:, mailto: and friends
+	}
+	return !cs.skipExtensions[strings.ToLower(path.Ext(linkURL.Path))]
+}
+
+// parseExtraHeaders parses headers in the form "Key:Value;Key:Value"
+func parseExtraHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ";") {
+		key, value, found := strings.Cut(pair, ":")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// newRequest builds an outgoing request carrying the crawler's User-Agent and extra headers
+func (cs *CrawlerService) newRequest(ctx context.Context, method, targetURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range cs.extraHeaders {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("User-Agent", cs.userAgent)
+
+	// Continue the crawl's trace on the fetched site
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return req, nil
 }
 
 type CrawlData struct {
-	Title         string
-	HTMLVersion   string
-	HasLoginForm  bool
-	HeadingCounts map[string]int
-	InternalLinks []string
-	ExternalLinks []string
-	BrokenLinks   []string
-}
-
-// CrawlURL performs the actual crawling and analysis of a URL
-func (cs *CrawlerService) CrawlURL(urlID uint) error {
-	// Get the URL from database
+	ContentHash       string
+	ContentLanguage   string
+	ETag              string // Validators of the response, for conditional re-crawls
+	LastModified      string
+	HTML              []byte // The decoded page, kept only when STORE_SNAPSHOTS is enabled
+	Charset           string
+	TLS               *TLSInfo // Certificate of the page when served over https
+	ResponseStatus    int
+	RedirectedOffHost bool
+	Title             string
+	HTMLVersion       string
+	HasLoginForm      bool
+	HasSignupForm     bool
+	HasSearchForm     bool
+	HasFavicon        bool
+	HasViewportMeta   bool
+	HasDescription    bool // <meta name="description"> with content
+	WordCount         int  // Words of visible text in the body
+	CanonicalURL      string
+	CanonicalMismatch bool // CanonicalURL differs from the page's final URL
+	OGTitle           string
+	OGDescription     string
+	OGImage           string
+	TagCounts         map[string]int // Counts of h1-h6 and the configured structural tags
+	InternalLinks     []string
+	ExternalLinks     []string
+	BrokenLinks       []string
+
+	// Distinct external scripts and stylesheets, resolved against the page URL
+	Scripts           map[string]bool
+	Stylesheets       map[string]bool
+	InlineScriptCount int
+
+	// LinkOccurrences counts how many times each unique link appeared on the page
+	LinkOccurrences map[string]int
+
+	// LinkRels holds the rel classification of each unique link's first occurrence
+	LinkRels map[string]LinkRel
+}
+
+// LinkRel classifies a link by the rel values search engines care about
+type LinkRel struct {
+	Nofollow  bool
+	Sponsored bool
+	UGC       bool
+}
+
+// parseLinkRel reads the rel attribute of an <a> element; links without rel are followed
+func parseLinkRel(n *html.Node) LinkRel {
+	return LinkRel{
+		Nofollow:  hasRel(n, "nofollow"),
+		Sponsored: hasRel(n, "sponsored"),
+		UGC:       hasRel(n, "ugc"),
+	}
+}
+
+// ClaimURL atomically moves a URL into the running state.
+// It returns false if the URL is already running, so only one crawl can be in flight per URL.
+func (cs *CrawlerService) ClaimURL(urlID uint) (bool, error) {
+	result := cs.db.Model(&models.URL{}).
+		Where("id = ? AND status != ?", urlID, models.StatusRunning).
+		Updates(map[string]interface{}{
+			"status":        models.StatusRunning,
+			"error_message": "",
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim URL: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Cancel stops the in-flight crawl of a URL, if any, and reports whether one was running
+func (cs *CrawlerService) Cancel(urlID uint) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	run, ok := cs.cancels[urlID]
+	if ok {
+		run.cancel()
+		delete(cs.cancels, urlID)
+	}
+	return ok
+}
+
+// register records a crawl that's starting and returns its run for unregister
+func (cs *CrawlerService) register(urlID uint, cancel context.CancelFunc) *crawlRun {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	run := &crawlRun{cancel: cancel}
+	cs.cancels[urlID] = run
+	return run
+}
+
+// unregister removes a finished crawl from the registry, unless the URL was stopped and
+// started again in the meantime, in which case the entry belongs to the new run
+func (cs *CrawlerService) unregister(urlID uint, run *crawlRun) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.cancels[urlID] == run {
+		delete(cs.cancels, urlID)
+	}
+}
+
+// finishURL records the outcome of a crawl, unless the crawl was stopped in the meantime,
+// and notifies the owner's webhook. crawlResult is nil when the crawl produced no result.
+func (cs *CrawlerService) finishURL(urlEntry *models.URL, status models.CrawlStatus, errorMessage string, crawlResult *models.CrawlResult) {
+	result := cs.db.Model(&models.URL{}).
+		Where("id = ? AND status = ?", urlEntry.ID, models.StatusRunning).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"error_message": errorMessage,
+		})
+	if result.Error != nil {
+		log.Printf("Failed to update status for URL %d: %v", urlEntry.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		return // Stopped in the meantime; the stop was already recorded
+	}
+
+	if status == models.StatusError {
+		cs.RecordEvent(urlEntry.ID, urlEntry.UserID, models.EventFailed, errorMessage)
+	} else {
+		cs.RecordEvent(urlEntry.ID, urlEntry.UserID, models.EventCompleted, "")
+	}
+
+	go cs.notifyWebhook(urlEntry, status, errorMessage, crawlResult)
+}
+
+// CrawlURL performs the actual crawling and analysis of a URL.
+// The URL must have been claimed with ClaimURL first. The crawl can be stopped with Cancel.
+func (cs *CrawlerService) CrawlURL(urlID uint) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx, span := tracing.Start(ctx, "crawl", attribute.Int64("url.id", int64(urlID)))
+	defer func() { tracing.End(span, err) }()
+
+	run := cs.register(urlID, cancel)
+	defer cs.unregister(urlID, run)
+
+	// Get the URL from database, skipping it if the claim was released (e.g. stopped) in the meantime
 	var urlEntry models.URL
-	if err := cs.db.First(&urlEntry, urlID).Error; err != nil {
+	if err := cs.db.Where("id = ? AND status = ?", urlID, models.StatusRunning).First(&urlEntry).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("URL %d is not claimed for crawling", urlID)
+		}
 		return fmt.Errorf("failed to find URL: %w", err)
 	}
 
-	// Update status to running
-	urlEntry.Status = models.StatusRunning
-	cs.db.Save(&urlEntry)
+	metrics.CrawlsStarted.Inc()
+	metrics.CrawlsRunning.Inc()
+	start := time.Now()
+	defer func() {
+		metrics.CrawlsRunning.Dec()
+		metrics.CrawlDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// URLs saved before CRAWL_DOMAIN_ALLOWLIST was set may point elsewhere
+	if !domainAllowed(urlEntry.URL) {
+		err := fmt.Errorf("crawling this domain is not allowed")
+		cs.finishURL(&urlEntry, models.StatusError, err.Error(), nil)
+		metrics.CrawlsFailed.Inc()
+		return err
+	}
 
 	// Perform the crawl
-	crawlData, err := cs.fetchAndAnalyze(urlEntry.URL)
+	opts, err := renderOptions(&urlEntry)
+	if err != nil {
+		cs.finishURL(&urlEntry, models.StatusError, err.Error(), nil)
+		metrics.CrawlsFailed.Inc()
+		return err
+	}
+
+	// Ask the server to skip the page if it's unchanged since the previous crawl
+	previous := cs.previousResult(urlEntry.ID)
+	crawlData, err := cs.fetchAndAnalyze(ctx, urlEntry.URL, conditionalOptions(opts, previous))
+	if errors.Is(err, errNotModified) && previous != nil {
+		crawlResult, err := cs.reuseResult(previous)
+		if err != nil {
+			cs.finishURL(&urlEntry, models.StatusError, fmt.Sprintf("Failed to save results: %v", err), nil)
+			metrics.CrawlsFailed.Inc()
+			return fmt.Errorf("failed to save crawl results: %w", err)
+		}
+		cs.finishURL(&urlEntry, models.StatusCompleted, "", crawlResult)
+		metrics.CrawlsCompleted.Inc()
+		return nil
+	}
 	if err != nil {
+		// A stopped crawl already had its status reset by whoever stopped it
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return ctx.Err()
+		}
+
+		// Record the page's status code when the server responded with an error
+		if crawlData != nil && crawlData.ResponseStatus != 0 {
+			errorResult := models.CrawlResult{
+				URLID:          urlEntry.ID,
+				ResponseStatus: crawlData.ResponseStatus,
+			}
+			if err := cs.db.Create(&errorResult).Error; err != nil {
+				log.Printf("Failed to save error crawl result: %v", err)
+			}
+		}
+
 		// Update status to error
-		urlEntry.Status = models.StatusError
-		urlEntry.ErrorMessage = err.Error()
-		cs.db.Save(&urlEntry)
+		cs.finishURL(&urlEntry, models.StatusError, err.Error(), nil)
+		metrics.CrawlsFailed.Inc()
 		return err
 	}
 
 	// Check for broken links
-	brokenLinks := cs.checkLinkAccessibility(crawlData.InternalLinks, crawlData.ExternalLinks)
+	maxChecked := config.GetEnvInt("MAX_LINKS_CHECKED", 50)
+	if urlEntry.MaxLinksToCheck != nil {
+		maxChecked = *urlEntry.MaxLinksToCheck
+	}
+	brokenLinks, checkedAt := cs.checkLinks(ctx, crawlData, maxChecked)
 
-	// Create crawl result
-	crawlResult := models.CrawlResult{
-		URLID:         urlEntry.ID,
-		Title:         crawlData.Title,
-		HTMLVersion:   crawlData.HTMLVersion,
-		HasLoginForm:  crawlData.HasLoginForm,
-		H1Count:       crawlData.HeadingCounts["h1"],
-		H2Count:       crawlData.HeadingCounts["h2"],
-		H3Count:       crawlData.HeadingCounts["h3"],
-		H4Count:       crawlData.HeadingCounts["h4"],
-		H5Count:       crawlData.HeadingCounts["h5"],
-		H6Count:       crawlData.HeadingCounts["h6"],
-		InternalLinks: len(crawlData.InternalLinks),
-		ExternalLinks: len(crawlData.ExternalLinks),
-		BrokenLinks:   len(brokenLinks),
+	crawlResult := newCrawlResult(urlEntry.ID, crawlData, brokenLinks, checkedAt)
+	crawlResult.Changed = cs.contentChanged(urlEntry.ID, nil, crawlData.ContentHash)
+
+	// A crawl stopped during link checks keeps what was gathered so far as a partial result;
+	// the URL's status was already reset by whoever stopped it
+	if ctx.Err() != nil {
+		crawlResult.IsPartial = true
+		if err := cs.db.Create(&crawlResult).Error; err != nil {
+			log.Printf("Failed to save partial crawl result: %v", err)
+			return ctx.Err()
+		}
+		cs.storeLinks(&crawlResult, crawlData, brokenLinks, checkedAt)
+		cs.saveSnapshot(crawlResult.ID, crawlData)
+		return ctx.Err()
 	}
 
 	// Save crawl result
 	if err := cs.db.Create(&crawlResult).Error; err != nil {
-		urlEntry.Status = models.StatusError
-		urlEntry.ErrorMessage = fmt.Sprintf("Failed to save results: %v", err)
-		cs.db.Save(&urlEntry)
+		cs.finishURL(&urlEntry, models.StatusError, fmt.Sprintf("Failed to save results: %v", err), nil)
+		metrics.CrawlsFailed.Inc()
 		return fmt.Errorf("failed to save crawl results: %w", err)
 	}
 
-	// Save individual links
-	cs.saveLinks(crawlResult.ID, crawlData.InternalLinks, crawlData.ExternalLinks, brokenLinks)
+	// Save individual links, keeping the crawl result even if they can't be saved
+	cs.storeLinks(&crawlResult, crawlData, brokenLinks, checkedAt)
+	cs.saveSnapshot(crawlResult.ID, crawlData)
+
+	// Follow internal links when the URL has a crawl depth
+	if urlEntry.CrawlDepth != nil && *urlEntry.CrawlDepth > 0 {
+		cs.crawlLinkedPages(ctx, &urlEntry, &crawlResult, crawlData, opts, maxChecked)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
 
 	// Update URL status to completed
-	urlEntry.Status = models.StatusCompleted
-	urlEntry.ErrorMessage = ""
-	cs.db.Save(&urlEntry)
+	cs.finishURL(&urlEntry, models.StatusCompleted, "", &crawlResult)
+	metrics.CrawlsCompleted.Inc()
 
 	return nil
 }
 
-// fetchAndAnalyze fetches the URL and analyzes its content
-func (cs *CrawlerService) fetchAndAnalyze(targetURL string) (*CrawlData, error) {
+// newCrawlResult builds the result of an analyzed page; callers set Changed with contentChanged
+func newCrawlResult(urlID uint, crawlData *CrawlData, brokenLinks []string, checkedAt map[string]time.Time) models.CrawlResult {
+	crawlResult := models.CrawlResult{
+		URLID:             urlID,
+		ResponseStatus:    crawlData.ResponseStatus,
+		RedirectedOffHost: crawlData.RedirectedOffHost,
+		Title:             crawlData.Title,
+		HTMLVersion:       crawlData.HTMLVersion,
+		HasLoginForm:      crawlData.HasLoginForm,
+		HasSignupForm:     crawlData.HasSignupForm,
+		HasSearchForm:     crawlData.HasSearchForm,
+		HasTitle:          crawlData.Title != "",
+		HasFavicon:        crawlData.HasFavicon,
+		HasViewportMeta:   crawlData.HasViewportMeta,
+		MultipleH1:        crawlData.TagCounts["h1"] > 1,
+		MissingH1:         crawlData.TagCounts["h1"] == 0,
+		WordCount:         crawlData.WordCount,
+		ScriptCount:       len(crawlData.Scripts),
+		StylesheetCount:   len(crawlData.Stylesheets),
+		InlineScriptCount: crawlData.InlineScriptCount,
+		IsThinContent:     crawlData.WordCount < config.GetEnvInt("THIN_CONTENT_WORDS", 300),
+		CanonicalURL:      truncate(crawlData.CanonicalURL, 500),
+		CanonicalMismatch: crawlData.CanonicalMismatch,
+		OGTitle:           truncate(crawlData.OGTitle, 512),
+		OGDescription:     truncate(crawlData.OGDescription, 1024),
+		OGImage:           truncate(crawlData.OGImage, 500),
+		H1Count:           crawlData.TagCounts["h1"],
+		H2Count:           crawlData.TagCounts["h2"],
+		H3Count:           crawlData.TagCounts["h3"],
+		H4Count:           crawlData.TagCounts["h4"],
+		H5Count:           crawlData.TagCounts["h5"],
+		H6Count:           crawlData.TagCounts["h6"],
+		TagCounts:         crawlData.TagCounts,
+		InternalLinks:     len(crawlData.InternalLinks),
+		ExternalLinks:     len(crawlData.ExternalLinks),
+		BrokenLinks:       len(brokenLinks),
+		ContentHash:       crawlData.ContentHash,
+		ContentLanguage:   truncate(crawlData.ContentLanguage, 100),
+		ETag:              truncate(crawlData.ETag, 255),
+		LastModified:      truncate(crawlData.LastModified, 100),
+		Charset:           crawlData.Charset,
+		LinksChecked:      len(checkedAt),
+		LinksTotal:        len(crawlData.InternalLinks) + len(crawlData.ExternalLinks),
+	}
+	crawlResult.HasMetaDescription = crawlData.HasDescription
+	if crawlData.TLS != nil {
+		crawlResult.CertificateValid = &crawlData.TLS.Valid
+		crawlResult.CertificateExpiresAt = &crawlData.TLS.ExpiresAt
+	}
+
+	return crawlResult
+}
+
+// contentChanged reports whether a page's content hash differs from its previous successful
+// crawl, or whether it was never crawled before. pageURL is nil for the URL's own page.
+func (cs *CrawlerService) contentChanged(urlID uint, pageURL *string, contentHash string) bool {
+	query := cs.db.Select("content_hash").Where("url_id = ? AND content_hash <> ''", urlID)
+	if pageURL == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("page_url = ?", *pageURL)
+	}
+
+	var previous models.CrawlResult
+	if err := query.
+		Order("created_at DESC, id DESC").
+		First(&previous).Error; err != nil {
+		return true
+	}
+	return previous.ContentHash != contentHash
+}
+
+// authorizationHeader builds the Authorization header for a URL with stored credentials,
+// or returns "" when none are configured
+func authorizationHeader(urlEntry *models.URL) (string, error) {
+	if urlEntry.AuthType == "" || urlEntry.AuthType == models.AuthTypeNone {
+		return "", nil
+	}
+
+	credentials, err := secrets.Decrypt(urlEntry.AuthCredentials)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+
+	switch urlEntry.AuthType {
+	case models.AuthTypeBasic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+	case models.AuthTypeBearer:
+		return "Bearer " + credentials, nil
+	default:
+		return "", fmt.Errorf("unsupported auth type %q", urlEntry.AuthType)
+	}
+}
+
+// renderOptions builds the options for fetching a URL's page, applying its per-URL
+// overrides. The headers (stored credentials and the Accept-Language, per-URL or from
+// CRAWLER_ACCEPT_LANGUAGE) are sent only when fetching the page itself.
+func renderOptions(urlEntry *models.URL) (RenderOptions, error) {
+	opts := RenderOptions{FollowRedirects: true}
+	if urlEntry.FollowRedirects != nil {
+		opts.FollowRedirects = *urlEntry.FollowRedirects
+	}
+	if urlEntry.CrawlTimeoutSeconds != nil {
+		opts.Timeout = time.Duration(*urlEntry.CrawlTimeoutSeconds) * time.Second
+	}
+
+	headers := make(map[string]string)
+
+	authorization, err := authorizationHeader(urlEntry)
+	if err != nil {
+		return opts, err
+	}
+	if authorization != "" {
+		headers["Authorization"] = authorization
+	}
+
+	acceptLanguage := urlEntry.AcceptLanguage
+	if acceptLanguage == "" {
+		acceptLanguage = config.GetEnv("CRAWLER_ACCEPT_LANGUAGE", "")
+	}
+	if acceptLanguage != "" {
+		headers["Accept-Language"] = acceptLanguage
+	}
+	opts.Headers = headers
+
+	return opts, nil
+}
+
+// fetchAndAnalyze fetches the URL with the configured renderer and analyzes its content.
+// The page headers are only sent for the page itself, never for link checks.
+func (cs *CrawlerService) fetchAndAnalyze(ctx context.Context, targetURL string, opts RenderOptions) (crawlData *CrawlData, err error) {
 	// Fetch the webpage
-	resp, err := cs.client.Get(targetURL)
+	fetchCtx, span := tracing.Start(ctx, "fetch", attribute.String("url.full", targetURL))
+	page, err := cs.renderer.Render(fetchCtx, targetURL, opts)
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", page.StatusCode))
+	}
+	tracing.End(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	if page.StatusCode == http.StatusNotModified {
+		return nil, errNotModified
+	}
+	if page.StatusCode >= 400 {
+		return &CrawlData{ResponseStatus: page.StatusCode}, fmt.Errorf("HTTP error: %d %s", page.StatusCode, http.StatusText(page.StatusCode))
 	}
 
-	// Read the body
-	body, err := io.ReadAll(resp.Body)
+	_, span = tracing.Start(ctx, "parse")
+	defer func() { tracing.End(span, err) }()
+
+	// Decode pages in other charsets so titles and text aren't mangled
+	body, charsetName, err := decodeBody(page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	// Parse HTML
@@ -134,10 +664,23 @@ func (cs *CrawlerService) fetchAndAnalyze(targetURL string) (*CrawlData, error)
 	}
 
 	// Analyze the document
-	crawlData := &CrawlData{
-		HeadingCounts: make(map[string]int),
-		InternalLinks: []string{},
-		ExternalLinks: []string{},
+	contentHash := sha256.Sum256(page.Body)
+	crawlData = &CrawlData{
+		ContentHash:       hex.EncodeToString(contentHash[:]),
+		ContentLanguage:   page.Header.Get("Content-Language"),
+		ETag:              page.Header.Get("ETag"),
+		LastModified:      page.Header.Get("Last-Modified"),
+		Charset:           charsetName,
+		TLS:               page.TLS,
+		ResponseStatus:    page.StatusCode,
+		RedirectedOffHost: redirectedOffHost(targetURL, page.FinalURL),
+		TagCounts:         make(map[string]int),
+		Scripts:           make(map[string]bool),
+		Stylesheets:       make(map[string]bool),
+		InternalLinks:     []string{},
+		ExternalLinks:     []string{},
+		LinkOccurrences:   make(map[string]int),
+		LinkRels:          make(map[string]LinkRel),
 	}
 
 	// Extract base URL for relative link resolution
@@ -145,32 +688,116 @@ func (cs *CrawlerService) fetchAndAnalyze(targetURL string) (*CrawlData, error)
 
 	// Walk through the HTML tree
 	cs.walkNode(doc, crawlData, baseURL, string(body))
+	crawlData.WordCount = countWords(doc)
+	if snapshotsEnabled() {
+		crawlData.HTML = body
+	}
+	crawlData.CanonicalMismatch = canonicalMismatch(crawlData.CanonicalURL, targetURL, page.FinalURL)
+
+	// Browsers fall back to /favicon.ico when the page doesn't declare an icon
+	if !crawlData.HasFavicon {
+		crawlData.HasFavicon = cs.hasDefaultFavicon(ctx, baseURL)
+	}
 
 	return crawlData, nil
 }
 
+// hasDefaultFavicon checks whether /favicon.ico exists on the page's host. Errors count
+// as missing rather than failing the crawl.
+func (cs *CrawlerService) hasDefaultFavicon(ctx context.Context, baseURL *url.URL) bool {
+	faviconURL := baseURL.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+
+	req, err := cs.newRequest(ctx, http.MethodHead, faviconURL)
+	if err != nil {
+		return false
+	}
+
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode < 400
+}
+
 // walkNode recursively walks through HTML nodes to extract data
 func (cs *CrawlerService) walkNode(n *html.Node, data *CrawlData, baseURL *url.URL, htmlContent string) {
 	if n.Type == html.ElementNode {
-		switch strings.ToLower(n.Data) {
+		tag := strings.ToLower(n.Data)
+		if cs.countedTags[tag] {
+			data.TagCounts[tag]++
+		}
+
+		switch tag {
 		case "title":
 			if n.FirstChild != nil {
 				data.Title = strings.TrimSpace(n.FirstChild.Data)
 			}
-		case "h1", "h2", "h3", "h4", "h5", "h6":
-			data.HeadingCounts[n.Data]++
 		case "a":
 			// Extract links
 			for _, attr := range n.Attr {
 				if attr.Key == "href" {
-					cs.categorizeLink(attr.Val, data, baseURL)
+					cs.categorizeLink(attr.Val, parseLinkRel(n), data, baseURL)
 					break
 				}
 			}
+		case "link":
+			if hasRel(n, "icon") {
+				data.HasFavicon = true
+			}
+			if hasRel(n, "stylesheet") {
+				if resource := resolveResource(getAttr(n, "href"), baseURL); resource != "" {
+					data.Stylesheets[resource] = true
+				}
+			}
+
+			// Only the first canonical tag counts
+			if data.CanonicalURL == "" && hasRel(n, "canonical") {
+				if href := getAttr(n, "href"); href != "" {
+					if canonicalURL, err := url.Parse(strings.TrimSpace(href)); err == nil {
+						data.CanonicalURL = baseURL.ResolveReference(canonicalURL).String()
+					}
+				}
+			}
+		case "meta":
+			content := strings.TrimSpace(getAttr(n, "content"))
+			if strings.EqualFold(getAttr(n, "name"), "viewport") && content != "" {
+				data.HasViewportMeta = true
+			}
+			if strings.EqualFold(getAttr(n, "name"), "description") && content != "" {
+				data.HasDescription = true
+			}
+			switch strings.ToLower(getAttr(n, "property")) {
+			case "og:title":
+				if data.OGTitle == "" {
+					data.OGTitle = content
+				}
+			case "og:description":
+				if data.OGDescription == "" {
+					data.OGDescription = content
+				}
+			case "og:image":
+				if data.OGImage == "" {
+					data.OGImage = content
+				}
+			}
+		case "script":
+			if src := getAttr(n, "src"); src != "" {
+				if resource := resolveResource(src, baseURL); resource != "" {
+					data.Scripts[resource] = true
+				}
+			} else if isJavaScript(getAttr(n, "type")) && n.FirstChild != nil && strings.TrimSpace(n.FirstChild.Data) != "" {
+				data.InlineScriptCount++
+			}
 		case "form":
-			// Check for login form
-			if cs.isLoginForm(n) {
+			switch cs.classifyForm(n) {
+			case FormLogin:
 				data.HasLoginForm = true
+			case FormSignup:
+				data.HasSignupForm = true
+			case FormSearch:
+				data.HasSearchForm = true
 			}
 		}
 	}
@@ -186,8 +813,124 @@ func (cs *CrawlerService) walkNode(n *html.Node, data *CrawlData, baseURL *url.U
 	}
 }
 
+// resolveResource resolves a script or stylesheet reference against the page URL, or
+// returns "" when it's empty or invalid
+func resolveResource(ref string, baseURL *url.URL) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	resourceURL, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return baseURL.ResolveReference(resourceURL).String()
+}
+
+// isJavaScript reports whether a script type attribute denotes executable JavaScript,
+// as opposed to data blocks such as application/ld+json
+func isJavaScript(scriptType string) bool {
+	scriptType = strings.ToLower(strings.TrimSpace(scriptType))
+	return scriptType == "" || scriptType == "module" || strings.Contains(scriptType, "javascript") || strings.Contains(scriptType, "ecmascript")
+}
+
+// canonicalMismatch reports whether a page declares a canonical URL other than the page
+// itself, after redirects. Both sides are normalized so trivial differences don't count.
+func canonicalMismatch(canonicalURL, targetURL string, finalURL *url.URL) bool {
+	if canonicalURL == "" {
+		return false
+	}
+	canonical, err := url.Parse(canonicalURL)
+	if err != nil {
+		return false
+	}
+
+	pageURL := finalURL
+	if pageURL == nil {
+		if pageURL, err = url.Parse(targetURL); err != nil {
+			return false
+		}
+	}
+	return normalizeURL(canonical).String() != normalizeURL(pageURL).String()
+}
+
+// domainAllowed reports whether the URL's host may be crawled under CRAWL_DOMAIN_ALLOWLIST
+func domainAllowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && config.CrawlDomainAllowed(u.Hostname())
+}
+
+// redirectedOffHost reports whether the final URL after redirects is on a different host than the submitted one
+func redirectedOffHost(originalURL string, finalURL *url.URL) bool {
+	original, err := url.Parse(originalURL)
+	if err != nil || finalURL == nil {
+		return false
+	}
+	return !strings.EqualFold(original.Hostname(), finalURL.Hostname())
+}
+
+// getAttr returns the value of the named attribute, or "" if it isn't set
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// textContent returns the concatenated text of a node and its descendants
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+// invisibleTags hold no text a visitor reads
+var invisibleTags = map[string]bool{"head": true, "script": true, "style": true, "noscript": true, "template": true}
+
+// countWords counts the whitespace-separated words of a node's visible text
+func countWords(n *html.Node) int {
+	if n.Type == html.TextNode {
+		return len(strings.Fields(n.Data))
+	}
+	if n.Type == html.ElementNode && invisibleTags[strings.ToLower(n.Data)] {
+		return 0
+	}
+
+	count := 0
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		count += countWords(c)
+	}
+	return count
+}
+
+// hasRel checks whether a node's space-separated rel attribute contains the given value
+func hasRel(n *html.Node, rel string) bool {
+	for _, value := range strings.Fields(getAttr(n, "rel")) {
+		if strings.EqualFold(value, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate shortens a string to fit a column of the given size. Column sizes count
+// characters, so it cuts on a character boundary and never splits a multi-byte one.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-3]) + "..."
+}
+
 // categorizeLink categorizes a link as internal or external
-func (cs *CrawlerService) categorizeLink(href string, data *CrawlData, baseURL *url.URL) {
+func (cs *CrawlerService) categorizeLink(href string, rel LinkRel, data *CrawlData, baseURL *url.URL) {
 	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
 		return
 	}
@@ -198,44 +941,100 @@ func (cs *CrawlerService) categorizeLink(href string, data *CrawlData, baseURL *
 	}
 
 	// Resolve relative URLs
-	resolvedURL := baseURL.ResolveReference(linkURL)
+	resolvedURL := normalizeURL(baseURL.ResolveReference(linkURL))
+	link := resolvedURL.String()
+
+	// Only record each unique link once, counting repeat occurrences
+	data.LinkOccurrences[link]++
+	if data.LinkOccurrences[link] > 1 {
+		return
+	}
+	data.LinkRels[link] = rel
 
 	// Categorize as internal or external
-	if resolvedURL.Host == baseURL.Host || resolvedURL.Host == "" {
-		data.InternalLinks = append(data.InternalLinks, resolvedURL.String())
+	if strings.EqualFold(resolvedURL.Host, baseURL.Host) || resolvedURL.Host == "" {
+		data.InternalLinks = append(data.InternalLinks, link)
 	} else {
-		data.ExternalLinks = append(data.ExternalLinks, resolvedURL.String())
+		data.ExternalLinks = append(data.ExternalLinks, link)
 	}
 }
 
-// isLoginForm checks if a form is likely a login form
-func (cs *CrawlerService) isLoginForm(formNode *html.Node) bool {
-	hasPasswordField := false
+// normalizeURL lowercases the scheme and host, drops default ports and
+// strips the fragment so equivalent links compare equal
+func normalizeURL(u *url.URL) *url.URL {
+	normalized := *u
+	normalized.Scheme = strings.ToLower(normalized.Scheme)
+	normalized.Host = strings.ToLower(normalized.Host)
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+
+	if port := normalized.Port(); (normalized.Scheme == "http" && port == "80") || (normalized.Scheme == "https" && port == "443") {
+		normalized.Host = normalized.Hostname()
+	}
+	if normalized.Path == "" && normalized.Host != "" {
+		normalized.Path = "/"
+	}
+
+	return &normalized
+}
+
+// FormType is the purpose a form was classified as
+type FormType string
+
+const (
+	FormOther  FormType = "other"
+	FormLogin  FormType = "login"
+	FormSignup FormType = "signup"
+	FormSearch FormType = "search"
+)
+
+// signupKeywords mark a form's submit text or fields as belonging to a registration form
+var signupKeywords = []string{"sign up", "signup", "register", "create account", "join", "terms", "agree"}
+
+// classifyForm guesses a form's purpose from its fields and submit text:
+//   - signup: a password plus a confirm-password field, or a password with register/terms wording
+//   - login: a password field plus a username or email field, or a form posting to a login URL
+//   - search: a single text input plus a submit control (or a type=search input)
+func (cs *CrawlerService) classifyForm(formNode *html.Node) FormType {
+	passwordFields := 0
+	textFields := 0
 	hasUsernameField := false
+	hasSearchField := false
+	hasSubmit := false
+	var wording []string
 
-	// Walk through form children to find input fields
+	// Walk through form children to find input fields and submit controls
 	var walkFormNode func(*html.Node)
 	walkFormNode = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "input" {
-			inputType := ""
-			inputName := ""
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "input":
+				inputType := strings.ToLower(getAttr(n, "type"))
+				inputName := strings.ToLower(getAttr(n, "name") + " " + getAttr(n, "id"))
 
-			for _, attr := range n.Attr {
-				if attr.Key == "type" {
-					inputType = strings.ToLower(attr.Val)
-				}
-				if attr.Key == "name" {
-					inputName = strings.ToLower(attr.Val)
+				switch inputType {
+				case "password":
+					passwordFields++
+				case "submit", "image":
+					hasSubmit = true
+					wording = append(wording, strings.ToLower(getAttr(n, "value")))
+				case "search":
+					hasSearchField = true
+					textFields++
+				case "", "text":
+					textFields++
+				case "checkbox":
+					wording = append(wording, inputName)
 				}
-			}
 
-			if inputType == "password" {
-				hasPasswordField = true
-			}
-
-			if strings.Contains(inputName, "user") || strings.Contains(inputName, "email") ||
-				strings.Contains(inputName, "login") || inputType == "email" {
-				hasUsernameField = true
+				if inputType == "email" || containsAny([]string{inputName}, cs.loginKeywords) {
+					hasUsernameField = true
+				}
+			case "button":
+				if buttonType := strings.ToLower(getAttr(n, "type")); buttonType == "" || buttonType == "submit" {
+					hasSubmit = true
+				}
+				wording = append(wording, strings.ToLower(textContent(n)))
 			}
 		}
 
@@ -245,7 +1044,30 @@ func (cs *CrawlerService) isLoginForm(formNode *html.Node) bool {
 	}
 
 	walkFormNode(formNode)
-	return hasPasswordField && hasUsernameField
+
+	if passwordFields >= 2 || (passwordFields > 0 && containsAny(wording, signupKeywords)) {
+		return FormSignup
+	}
+	loginAction := containsAny([]string{strings.ToLower(getAttr(formNode, "action"))}, loginActionKeywords)
+	if passwordFields > 0 && (hasUsernameField || loginAction) {
+		return FormLogin
+	}
+	if passwordFields == 0 && (hasSearchField || (textFields == 1 && hasSubmit)) {
+		return FormSearch
+	}
+	return FormOther
+}
+
+// containsAny reports whether any of the texts contains any of the keywords
+func containsAny(texts, keywords []string) bool {
+	for _, text := range texts {
+		for _, keyword := range keywords {
+			if strings.Contains(text, keyword) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // detectHTMLVersion detects the HTML version from doctype
@@ -267,95 +1089,169 @@ func (cs *CrawlerService) detectHTMLVersion(htmlContent string) string {
 	return "Unknown"
 }
 
-// checkLinkAccessibility checks which links are broken (return 4xx/5xx)
-func (cs *CrawlerService) checkLinkAccessibility(internalLinks, externalLinks []string) []string {
+// checkLinkAccessibility checks up to maxChecked links (all when negative) for being broken
+// (4xx/5xx) and returns the broken ones along with when each checked link was evaluated
+func (cs *CrawlerService) checkLinkAccessibility(ctx context.Context, internalLinks, externalLinks []string, maxChecked int) ([]string, map[string]time.Time) {
 	var brokenLinks []string
 
-	// Combine all links for checking
-	allLinks := append(internalLinks, externalLinks...)
+	// Combine all checkable links
+	allLinks := make([]string, 0, len(internalLinks)+len(externalLinks))
+	for _, links := range [][]string{internalLinks, externalLinks} {
+		for _, link := range links {
+			if cs.shouldCheckLink(link) {
+				allLinks = append(allLinks, link)
+			}
+		}
+	}
 
-	// Limit to first 50 links to avoid overwhelming the target server
-	if len(allLinks) > 50 {
-		allLinks = allLinks[:50]
+	// Limit the number of links checked to avoid overwhelming the target server
+	if maxChecked >= 0 && len(allLinks) > maxChecked {
+		allLinks = allLinks[:maxChecked]
 	}
 
+	checkedAt := make(map[string]time.Time, len(allLinks))
 	for _, link := range allLinks {
-		if cs.isLinkBroken(link) {
+		if ctx.Err() != nil {
+			break
+		}
+		broken := cs.isLinkBroken(ctx, link)
+		if ctx.Err() != nil {
+			break // Interrupted, so the link wasn't really checked
+		}
+		if broken {
 			brokenLinks = append(brokenLinks, link)
 		}
+		checkedAt[link] = time.Now()
 		// Small delay to be respectful to the server
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
 
-	return brokenLinks
+	return brokenLinks, checkedAt
+}
+
+// checkLinks checks the accessibility of a page's links within a span
+func (cs *CrawlerService) checkLinks(ctx context.Context, crawlData *CrawlData, maxChecked int) ([]string, map[string]time.Time) {
+	ctx, span := tracing.Start(ctx, "check_links",
+		attribute.Int("links.total", len(crawlData.InternalLinks)+len(crawlData.ExternalLinks)))
+	defer span.End()
+
+	brokenLinks, checkedAt := cs.checkLinkAccessibility(ctx, crawlData.InternalLinks, crawlData.ExternalLinks, maxChecked)
+	span.SetAttributes(attribute.Int("links.checked", len(checkedAt)), attribute.Int("links.broken", len(brokenLinks)))
+	return brokenLinks, checkedAt
+}
+
+// lastCheckedAt returns when a link was checked, or nil if it was skipped or beyond the cap
+func lastCheckedAt(checkedAt map[string]time.Time, link string) *time.Time {
+	if t, ok := checkedAt[link]; ok {
+		return &t
+	}
+	return nil
 }
 
 // isLinkBroken checks if a link returns 4xx or 5xx status
-func (cs *CrawlerService) isLinkBroken(link string) bool {
+func (cs *CrawlerService) isLinkBroken(ctx context.Context, link string) bool {
 	client := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout:   10 * time.Second,
+		Transport: cs.client.Transport, // Same proxy and TLS settings as page fetches
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return nil // Follow redirects
+			// Follow redirects, except off CRAWL_DOMAIN_ALLOWLIST: the redirect itself is the answer
+			if !config.CrawlDomainAllowed(req.URL.Hostname()) {
+				return http.ErrUseLastResponse
+			}
+			return nil
 		},
 	}
 
-	resp, err := client.Head(link)
-	if err != nil {
-		// If HEAD fails, try GET
-		resp, err = client.Get(link)
+	probe := func(method string) (*http.Response, error) {
+		req, err := cs.newRequest(ctx, method, link)
 		if err != nil {
-			return true
+			return nil, err
+		}
+		return client.Do(req)
+	}
+
+	var resp *http.Response
+	var err error
+	switch cs.checkMethod {
+	case linkCheckGetOnly:
+		resp, err = probe(http.MethodGet)
+	case linkCheckHeadOnly:
+		resp, err = probe(http.MethodHead)
+	default:
+		resp, err = probe(http.MethodHead)
+		if err != nil {
+			// If HEAD fails, try GET
+			resp, err = probe(http.MethodGet)
 		}
 	}
+	if err != nil {
+		return true
+	}
 	defer resp.Body.Close()
 
 	return resp.StatusCode >= 400
 }
 
-// saveLinks saves individual links to the database
-func (cs *CrawlerService) saveLinks(crawlResultID uint, internalLinks, externalLinks, brokenLinks []string) {
+// storeLinks saves the links of a saved crawl result. When that fails no link is stored,
+// as saveLinks rolls back, so the result's link counts are cleared to match.
+func (cs *CrawlerService) storeLinks(result *models.CrawlResult, crawlData *CrawlData, brokenLinks []string, checkedAt map[string]time.Time) {
+	err := cs.saveLinks(result.ID, crawlData, brokenLinks, checkedAt)
+	if err == nil {
+		return
+	}
+
+	log.Printf("Failed to save links for crawl result %d, clearing its link counts: %v", result.ID, err)
+	result.InternalLinks, result.ExternalLinks, result.BrokenLinks = 0, 0, 0
+	if err := cs.db.Model(result).Updates(map[string]interface{}{
+		"internal_links": 0,
+		"external_links": 0,
+		"broken_links":   0,
+	}).Error; err != nil {
+		log.Printf("Failed to clear link counts of crawl result %d: %v", result.ID, err)
+	}
+}
+
+// saveLinks saves individual links to the database in batches.
+// All links are inserted in a single transaction so a failure leaves no partial set behind.
+func (cs *CrawlerService) saveLinks(crawlResultID uint, crawlData *CrawlData, brokenLinks []string, checkedAt map[string]time.Time) error {
 	brokenSet := make(map[string]bool)
 	for _, broken := range brokenLinks {
 		brokenSet[broken] = true
 	}
 
-	// Save internal links
-	for _, link := range internalLinks {
-		// Truncate URL if too long (safeguard)
-		url := link
-		if len(url) > 500 {
-			url = url[:497] + "..."
-		}
-
-		linkEntry := models.Link{
-			CrawlResultID: crawlResultID,
-			URL:           url,
-			Type:          models.LinkTypeInternal,
-			IsBroken:      brokenSet[link],
-		}
-		if err := cs.db.Create(&linkEntry).Error; err != nil {
-			// Log error but continue processing other links
-			fmt.Printf("Failed to save internal link: %v\n", err)
+	linkEntries := make([]models.Link, 0, len(crawlData.InternalLinks)+len(crawlData.ExternalLinks))
+	addLinks := func(links []string, linkType models.LinkType) {
+		for _, link := range links {
+			rel := crawlData.LinkRels[link]
+			linkEntries = append(linkEntries, models.Link{
+				CrawlResultID:   crawlResultID,
+				URL:             truncate(link, 500), // Truncate URL if too long (safeguard)
+				Type:            linkType,
+				IsBroken:        brokenSet[link],
+				LastCheckedAt:   lastCheckedAt(checkedAt, link),
+				OccurrenceCount: max(crawlData.LinkOccurrences[link], 1),
+				Nofollow:        rel.Nofollow,
+				Sponsored:       rel.Sponsored,
+				UGC:             rel.UGC,
+			})
 		}
 	}
+	addLinks(crawlData.InternalLinks, models.LinkTypeInternal)
+	addLinks(crawlData.ExternalLinks, models.LinkTypeExternal)
 
-	// Save external links
-	for _, link := range externalLinks {
-		// Truncate URL if too long (safeguard)
-		url := link
-		if len(url) > 500 {
-			url = url[:497] + "..."
-		}
+	if len(linkEntries) == 0 {
+		return nil
+	}
 
-		linkEntry := models.Link{
-			CrawlResultID: crawlResultID,
-			URL:           url,
-			Type:          models.LinkTypeExternal,
-			IsBroken:      brokenSet[link],
-		}
-		if err := cs.db.Create(&linkEntry).Error; err != nil {
-			// Log error but continue processing other links
-			fmt.Printf("Failed to save external link: %v\n", err)
-		}
+	batchSize := config.GetEnvInt("LINK_INSERT_BATCH_SIZE", 100)
+	if batchSize < 1 {
+		batchSize = 100
 	}
+
+	return cs.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&linkEntries, batchSize).Error
+	})
 }
@@ -0,0 +1,946 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// createRunningURL saves a URL claimed for crawling, with link checks turned off
+func createRunningURL(t testing.TB, db *gorm.DB, address string) models.URL {
+	t.Helper()
+	user := models.User{Username: "crawler", Email: "crawler@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	noLinkChecks := 0
+	urlEntry := models.URL{URL: address, UserID: user.ID, Status: models.StatusRunning, MaxLinksToCheck: &noLinkChecks}
+	if err := db.Create(&urlEntry).Error; err != nil {
+		t.Fatal(err)
+	}
+	return urlEntry
+}
+
+func TestCrawlURLRecordsResponseStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus models.CrawlStatus
+		wantErr    bool
+	}{
+		{"ok", http.StatusOK, models.StatusCompleted, false},
+		{"not found", http.StatusNotFound, models.StatusError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					http.NotFound(w, r)
+					return
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `<html><head><title>Page</title></head><body><h1>Page</h1></body></html>`)
+			}))
+			defer server.Close()
+
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			err := NewCrawlerService(db).CrawlURL(urlEntry.ID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CrawlURL error = %v, want error: %v", err, tt.wantErr)
+			}
+
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatalf("loading the crawl result: %v", err)
+			}
+			if result.ResponseStatus != tt.status {
+				t.Errorf("response status = %d, want %d", result.ResponseStatus, tt.status)
+			}
+
+			if err := db.First(&urlEntry, urlEntry.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if urlEntry.Status != tt.wantStatus {
+				t.Errorf("URL status = %q, want %q", urlEntry.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCrawlerSendsConfiguredHeaders(t *testing.T) {
+	t.Setenv("CRAWLER_USER_AGENT", "SkyellTest/1.0 (+https://example.com/bot)")
+	t.Setenv("CRAWLER_EXTRA_HEADERS", "Accept-Language: de; X-Crawl-Token:abc")
+
+	var mu sync.Mutex
+	received := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.Method+" "+r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+
+		// Echo the User-Agent back as the page title
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body></body></html>", html.EscapeString(r.UserAgent()))
+	}))
+	defer server.Close()
+
+	cs := NewCrawlerService(nil)
+	data, err := cs.fetchAndAnalyze(context.Background(), server.URL+"/", RenderOptions{FollowRedirects: true})
+	if err != nil {
+		t.Fatalf("fetchAndAnalyze: %v", err)
+	}
+	if data.Title != "SkyellTest/1.0 (+https://example.com/bot)" {
+		t.Errorf("page saw User-Agent %q, want the configured one", data.Title)
+	}
+
+	if cs.isLinkBroken(context.Background(), server.URL+"/link") {
+		t.Fatal("link reported broken")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, request := range []string{"GET /", "HEAD /link"} {
+		header, ok := received[request]
+		if !ok {
+			t.Errorf("no %s request received", request)
+			continue
+		}
+		if got := header.Get("User-Agent"); got != "SkyellTest/1.0 (+https://example.com/bot)" {
+			t.Errorf("%s: User-Agent = %q, want the configured one", request, got)
+		}
+		if header.Get("Accept-Language") != "de" || header.Get("X-Crawl-Token") != "abc" {
+			t.Errorf("%s: headers = %v, want the extra headers", request, header)
+		}
+	}
+}
+
+func TestCrawlURLSavesRepeatedLinksOnce(t *testing.T) {
+	db := testutil.NewDB(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Nav</title></head><body>
+			<nav><a href="/about">About</a> <a href="/contact">Contact</a></nav>
+			<main><a href="/about#team">Team</a> <a href="https://Other.example/">Partner</a></main>
+			<footer><a href="/about">About</a> <a href="https://other.example/">Partner</a></footer>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	var result models.CrawlResult
+	if err := db.Preload("Links").Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+		t.Fatalf("loading the crawl result: %v", err)
+	}
+	if result.InternalLinks != 2 || result.ExternalLinks != 1 {
+		t.Errorf("internal, external links = %d, %d, want 2 unique internal and 1 external",
+			result.InternalLinks, result.ExternalLinks)
+	}
+
+	counts := make(map[string]int)
+	for _, link := range result.Links {
+		if _, seen := counts[link.URL]; seen {
+			t.Errorf("link %s saved more than once", link.URL)
+		}
+		counts[link.URL] = link.OccurrenceCount
+	}
+	want := map[string]int{
+		server.URL + "/about":    3,
+		server.URL + "/contact":  1,
+		"https://other.example/": 2,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("saved links = %v, want %v", counts, want)
+	}
+	for link, count := range want {
+		if counts[link] != count {
+			t.Errorf("%s: occurrence count = %d, want %d", link, counts[link], count)
+		}
+	}
+}
+
+func TestFetchAndAnalyzeRedirectedOffHost(t *testing.T) {
+	final := serveHTML(t, `<html><head><title>Parked</title></head><body></body></html>`)
+	finalURL, err := url.Parse(final.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The final server is reached as localhost while the page is submitted as 127.0.0.1
+	offHost := "http://localhost:" + finalURL.Port() + "/"
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, offHost, http.StatusMovedPermanently)
+		case "/same-host":
+			http.Redirect(w, r, "/home", http.StatusFound)
+		default:
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body></body></html>`)
+		}
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/moved", true},
+		{"/same-host", false},
+		{"/home", false},
+	}
+	for _, tt := range tests {
+		data, err := NewCrawlerService(nil).fetchAndAnalyze(context.Background(), origin.URL+tt.path, RenderOptions{FollowRedirects: true})
+		if err != nil {
+			t.Fatalf("%s: fetchAndAnalyze: %v", tt.path, err)
+		}
+		if data.RedirectedOffHost != tt.want {
+			t.Errorf("%s: redirected off host = %v, want %v", tt.path, data.RedirectedOffHost, tt.want)
+		}
+	}
+}
+
+func TestMaxRedirects(t *testing.T) {
+	t.Setenv("MAX_REDIRECTS", "1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/one":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/two":
+			http.Redirect(w, r, "/one", http.StatusFound)
+		default:
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	cs := NewCrawlerService(nil)
+	if _, err := cs.fetchAndAnalyze(context.Background(), server.URL+"/one", RenderOptions{FollowRedirects: true}); err != nil {
+		t.Errorf("one redirect: %v, want it followed", err)
+	}
+	if _, err := cs.fetchAndAnalyze(context.Background(), server.URL+"/two", RenderOptions{FollowRedirects: true}); err == nil {
+		t.Error("two redirects were followed with MAX_REDIRECTS=1")
+	}
+}
+
+func TestRedirectsStayOnTheDomainAllowlist(t *testing.T) {
+	// The allow-listed server is reached as 127.0.0.1 and the other one as localhost
+	disallowed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer disallowed.Close()
+	offList := strings.Replace(disallowed.URL, "127.0.0.1", "localhost", 1) + "/"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, offList, http.StatusFound)
+		case "/here":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body></body></html>`)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "127.0.0.1")
+
+	cs := NewCrawlerService(nil)
+	if _, err := cs.fetchAndAnalyze(context.Background(), server.URL+"/here", RenderOptions{FollowRedirects: true}); err != nil {
+		t.Errorf("redirect on the allow-list: %v, want it followed", err)
+	}
+	if _, err := cs.fetchAndAnalyze(context.Background(), server.URL+"/away", RenderOptions{FollowRedirects: true}); err == nil {
+		t.Error("a redirect off the allow-list was followed")
+	}
+
+	// Link checks stop at the redirect instead of following it to the 404
+	if cs.isLinkBroken(context.Background(), server.URL+"/away") {
+		t.Error("a link redirecting off the allow-list was checked at its target")
+	}
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "")
+	if !cs.isLinkBroken(context.Background(), server.URL+"/away") {
+		t.Error("a link redirecting to a 404 wasn't broken with no allow-list set")
+	}
+}
+
+func TestCrawlURLRecordsLinkRel(t *testing.T) {
+	db := testutil.NewDB(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Rels</title></head><body>
+			<a href="/plain">Plain</a>
+			<a href="/noopener" rel="noopener">Noopener</a>
+			<a href="https://ads.example/" rel="sponsored NOFOLLOW">Ad</a>
+			<a href="https://forum.example/" rel="ugc">Comment</a>
+			<a href="https://old.example/" rel="external nofollow">Old</a>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	var links []models.Link
+	if err := db.Joins("JOIN crawl_results ON crawl_results.id = links.crawl_result_id").
+		Where("crawl_results.url_id = ?", urlEntry.ID).Find(&links).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]LinkRel{
+		server.URL + "/plain":    {},
+		server.URL + "/noopener": {},
+		"https://ads.example/":   {Nofollow: true, Sponsored: true},
+		"https://forum.example/": {UGC: true},
+		"https://old.example/":   {Nofollow: true},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("saved %d links, want %d", len(links), len(want))
+	}
+	for _, link := range links {
+		got := LinkRel{Nofollow: link.Nofollow, Sponsored: link.Sponsored, UGC: link.UGC}
+		if got != want[link.URL] {
+			t.Errorf("%s: rel = %+v, want %+v", link.URL, got, want[link.URL])
+		}
+	}
+}
+
+func TestCrawlURLRecordsLinksChecked(t *testing.T) {
+	tests := []struct {
+		maxChecked  string
+		wantChecked int
+	}{
+		{"2", 2},
+		{"10", 5},
+	}
+	for _, tt := range tests {
+		t.Run("MAX_LINKS_CHECKED="+tt.maxChecked, func(t *testing.T) {
+			t.Setenv("MAX_LINKS_CHECKED", tt.maxChecked)
+			db := testutil.NewDB(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, `<html><head><title>Many links</title></head><body>
+					<a href="/1">1</a> <a href="/2">2</a> <a href="/3">3</a> <a href="/4">4</a> <a href="/5">5</a>
+				</body></html>`)
+			}))
+			defer server.Close()
+
+			// Without a per-URL cap, MAX_LINKS_CHECKED applies
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := db.Model(&urlEntry).Update("max_links_to_check", nil).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			var result models.CrawlResult
+			if err := db.Preload("Links").Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatalf("loading the crawl result: %v", err)
+			}
+			if result.LinksChecked != tt.wantChecked || result.LinksTotal != 5 {
+				t.Errorf("checked %d of %d links, want %d of 5", result.LinksChecked, result.LinksTotal, tt.wantChecked)
+			}
+			if result.BrokenLinks != tt.wantChecked {
+				t.Errorf("broken links = %d, want the %d checked", result.BrokenLinks, tt.wantChecked)
+			}
+
+			checked := 0
+			for _, link := range result.Links {
+				if link.LastCheckedAt != nil {
+					checked++
+				}
+			}
+			if checked != tt.wantChecked {
+				t.Errorf("%d links have a check time, want %d", checked, tt.wantChecked)
+			}
+		})
+	}
+}
+
+func TestCrawlURLSkipsLinkExtensions(t *testing.T) {
+	tests := []struct {
+		skip        string
+		wantChecked []string
+	}{
+		{"", []string{"/page"}},
+		{"zip, PDF", []string{"/page", "/photo.JPG"}},
+	}
+	for _, tt := range tests {
+		t.Run("SKIP_LINK_EXTENSIONS="+tt.skip, func(t *testing.T) {
+			t.Setenv("SKIP_LINK_EXTENSIONS", tt.skip)
+			db := testutil.NewDB(t)
+
+			var mu sync.Mutex
+			var checked []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/favicon.ico":
+					http.NotFound(w, r)
+					return
+				case "/":
+					fmt.Fprint(w, `<html><head><title>Downloads</title></head><body>
+						<a href="/page">Page</a> <a href="/report.pdf">Report</a> <a href="/photo.JPG">Photo</a>
+						<a href="data:text/plain,hello">Inline</a>
+					</body></html>`)
+					return
+				}
+				mu.Lock()
+				checked = append(checked, r.URL.Path)
+				mu.Unlock()
+			}))
+			defer server.Close()
+
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := db.Model(&urlEntry).Update("max_links_to_check", nil).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(checked)
+			sort.Strings(tt.wantChecked)
+			if !slices.Equal(checked, tt.wantChecked) {
+				t.Errorf("checked %v, want %v", checked, tt.wantChecked)
+			}
+
+			// Skipped links still count towards the totals
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatal(err)
+			}
+			if result.LinksTotal != 4 || result.LinksChecked != len(tt.wantChecked) {
+				t.Errorf("checked %d of %d links, want %d of 4", result.LinksChecked, result.LinksTotal, len(tt.wantChecked))
+			}
+		})
+	}
+}
+
+func TestShouldCheckLink(t *testing.T) {
+	cs := NewCrawlerService(nil)
+	tests := map[string]bool{
+		"https://example.com/page":         true,
+		"https://example.com/file.PDF":     false,
+		"https://example.com/file.pdf?x=1": false,
+		"http://example.com/archive.zip":   false,
+		"javascript:alert(1)":              false,
+		"data:text/plain,hello":            false,
+		"mailto:someone@example.com":       false,
+	}
+	for link, want := range tests {
+		if got := cs.shouldCheckLink(link); got != want {
+			t.Errorf("shouldCheckLink(%q) = %v, want %v", link, got, want)
+		}
+	}
+}
+
+func TestCrawlURLDetectsContentChanges(t *testing.T) {
+	db := testutil.NewDB(t)
+	var mu sync.Mutex
+	title := "Unchanged"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body></body></html>", title)
+	}))
+	defer server.Close()
+
+	cs := NewCrawlerService(db)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	crawl := func() models.CrawlResult {
+		t.Helper()
+		// Claiming fails harmlessly before the first crawl, as the URL is already running
+		if _, err := cs.ClaimURL(urlEntry.ID); err != nil {
+			t.Fatalf("ClaimURL: %v", err)
+		}
+		if err := cs.CrawlURL(urlEntry.ID); err != nil {
+			t.Fatalf("CrawlURL: %v", err)
+		}
+		var result models.CrawlResult
+		if err := db.Where("url_id = ?", urlEntry.ID).Order("id desc").First(&result).Error; err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	first := crawl()
+	if !first.Changed || first.ContentHash == "" {
+		t.Errorf("first crawl: changed = %v, hash = %q, want a changed result with a hash", first.Changed, first.ContentHash)
+	}
+
+	second := crawl()
+	if second.Changed || second.ContentHash != first.ContentHash {
+		t.Errorf("same content: changed = %v, hash = %q, want unchanged with hash %q", second.Changed, second.ContentHash, first.ContentHash)
+	}
+
+	mu.Lock()
+	title = "Changed"
+	mu.Unlock()
+	third := crawl()
+	if !third.Changed || third.ContentHash == first.ContentHash {
+		t.Errorf("new content: changed = %v, hash = %q, want changed with a new hash", third.Changed, third.ContentHash)
+	}
+}
+
+func TestCrawlURLSkipsUnmodifiedPages(t *testing.T) {
+	db := testutil.NewDB(t)
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+	var mu sync.Mutex
+	etag := `"v1"`
+	var conditional []string // If-None-Match and If-Modified-Since of each page request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		conditional = append(conditional, r.Header.Get("If-None-Match")+"|"+r.Header.Get("If-Modified-Since"))
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		fmt.Fprintf(w, `<html><head><title>Version %s</title></head><body><a href="/a">A</a><a href="/b">B</a></body></html>`, etag)
+	}))
+	defer server.Close()
+
+	cs := NewCrawlerService(db)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	crawl := func() models.CrawlResult {
+		t.Helper()
+		if _, err := cs.ClaimURL(urlEntry.ID); err != nil {
+			t.Fatalf("ClaimURL: %v", err)
+		}
+		if err := cs.CrawlURL(urlEntry.ID); err != nil {
+			t.Fatalf("CrawlURL: %v", err)
+		}
+		var result models.CrawlResult
+		if err := db.Preload("Links").Where("url_id = ?", urlEntry.ID).Order("id desc").First(&result).Error; err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	first := crawl()
+	if first.ETag != `"v1"` || first.LastModified != lastModified {
+		t.Errorf("validators = %q, %q; want the response's ETag and Last-Modified", first.ETag, first.LastModified)
+	}
+
+	second := crawl()
+	if second.ID == first.ID || second.Changed || second.Title != first.Title || len(second.Links) != len(first.Links) || len(first.Links) != 2 {
+		t.Errorf("unmodified page: result %d changed %v, title %q, %d links; want a new unchanged copy of result %d with %q and 2 links",
+			second.ID, second.Changed, second.Title, len(second.Links), first.ID, first.Title)
+	}
+	var saved models.URL
+	if err := db.First(&saved, urlEntry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.StatusCompleted {
+		t.Errorf("status after a 304 = %s, want %s", saved.Status, models.StatusCompleted)
+	}
+
+	mu.Lock()
+	etag = `"v2"`
+	mu.Unlock()
+	third := crawl()
+	if !third.Changed || third.Title != `Version "v2"` || third.ETag != `"v2"` {
+		t.Errorf("modified page: changed %v, title %q, ETag %q; want a fresh analysis", third.Changed, third.Title, third.ETag)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"|", `"v1"|` + lastModified, `"v1"|` + lastModified}
+	if !slices.Equal(conditional, want) {
+		t.Errorf("conditional headers = %q, want %q", conditional, want)
+	}
+}
+
+func TestCrawlURLSetsLinkLastCheckedAt(t *testing.T) {
+	db := testutil.NewDB(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Links</title></head><body>
+				<a href="/checked">Checked</a> <a href="/beyond-the-cap">Not checked</a>
+			</body></html>`)
+		case "/checked":
+			fmt.Fprint(w, "ok")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := db.Model(&urlEntry).Update("max_links_to_check", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+	after := time.Now()
+
+	var links []models.Link
+	if err := db.Joins("JOIN crawl_results ON crawl_results.id = links.crawl_result_id").
+		Where("crawl_results.url_id = ?", urlEntry.ID).Order("links.id").Find(&links).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("saved %d links, want 2", len(links))
+	}
+
+	checked, skipped := links[0], links[1]
+	if checked.LastCheckedAt == nil || checked.LastCheckedAt.Before(before) || checked.LastCheckedAt.After(after) {
+		t.Errorf("%s: last checked at = %v, want during the crawl", checked.URL, checked.LastCheckedAt)
+	}
+	if skipped.LastCheckedAt != nil {
+		t.Errorf("%s: last checked at = %v, want nil beyond the cap", skipped.URL, skipped.LastCheckedAt)
+	}
+}
+
+func TestCrawlURLSendsAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		global, perURL string
+		want           string
+	}{
+		{"unset", "", "", ""},
+		{"global", "de-DE,de;q=0.9", "", "de-DE,de;q=0.9"},
+		{"per URL", "de-DE", "fr-CA", "fr-CA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CRAWLER_ACCEPT_LANGUAGE", tt.global)
+			db := testutil.NewDB(t)
+
+			// Echo the Accept-Language back as the Content-Language
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if language := r.Header.Get("Accept-Language"); language != "" {
+					w.Header().Set("Content-Language", language)
+				}
+				fmt.Fprint(w, `<html><head><title>Localized</title></head><body></body></html>`)
+			}))
+			defer server.Close()
+
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := db.Model(&urlEntry).Update("accept_language", tt.perURL).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatal(err)
+			}
+			if result.ContentLanguage != tt.want {
+				t.Errorf("content language = %q, want %q", result.ContentLanguage, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawlURLCountsStructuralTags(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head><title>Layout</title></head><body>
+<header><nav>Top</nav></header>
+<main>
+<h1>Layout</h1>
+<ARTICLE><h2>First</h2><section>A</section><section>B</section></ARTICLE>
+<article><h2>Second</h2><figure>Chart</figure></article>
+<aside><h3>Related</h3></aside>
+</main>
+<footer><nav>Bottom</nav></footer>
+</body></html>`
+
+	tests := []struct {
+		name        string
+		countedTags string
+		want        map[string]int
+	}{
+		{"default tags", "", map[string]int{
+			"h1": 1, "h2": 2, "h3": 1,
+			"header": 1, "nav": 2, "main": 1, "article": 2, "section": 2, "aside": 1, "footer": 1,
+		}},
+		{"configured tags", " Figure ,article", map[string]int{"h1": 1, "h2": 2, "h3": 1, "article": 2, "figure": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.countedTags != "" {
+				t.Setenv("CRAWLER_COUNTED_TAGS", tt.countedTags)
+			}
+			db := testutil.NewDB(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, page)
+			}))
+			defer server.Close()
+
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatalf("loading the crawl result: %v", err)
+			}
+			if !maps.Equal(result.TagCounts, tt.want) {
+				t.Errorf("tag counts = %v, want %v", result.TagCounts, tt.want)
+			}
+			// The heading columns are still filled in for older clients
+			if result.H1Count != 1 || result.H2Count != 2 || result.H3Count != 1 || result.H4Count != 0 {
+				t.Errorf("heading counts = h1 %d, h2 %d, h3 %d, h4 %d, want 1, 2, 1, 0",
+					result.H1Count, result.H2Count, result.H3Count, result.H4Count)
+			}
+		})
+	}
+}
+
+func TestCrawlURLHonoursPerURLOverrides(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		case "/moved":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	t.Run("timeout", func(t *testing.T) {
+		db := testutil.NewDB(t)
+		urlEntry := createRunningURL(t, db, server.URL+"/slow")
+		timeout := 1
+		if err := db.Model(&urlEntry).Update("crawl_timeout_seconds", timeout).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err == nil {
+			t.Fatal("crawling a page slower than the URL's timeout succeeded")
+		}
+		// The default timeout is 30s
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("crawl gave up after %v, want about the URL's %ds timeout", elapsed, timeout)
+		}
+	})
+
+	t.Run("follow redirects", func(t *testing.T) {
+		db := testutil.NewDB(t)
+		urlEntry := createRunningURL(t, db, server.URL+"/moved")
+		if err := db.Model(&urlEntry).Update("follow_redirects", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		NewCrawlerService(db).CrawlURL(urlEntry.ID)
+
+		var result models.CrawlResult
+		if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+			t.Fatalf("loading the crawl result: %v", err)
+		}
+		if result.ResponseStatus != http.StatusFound || result.Title != "" {
+			t.Errorf("status %d, title %q; want the redirect itself, not the page it points to", result.ResponseStatus, result.Title)
+		}
+	})
+}
+
+func TestCrawlURLFlagsH1Count(t *testing.T) {
+	tests := []struct {
+		name                      string
+		body                      string
+		wantMultiple, wantMissing bool
+	}{
+		{"two H1s", `<h1>One</h1><h1>Two</h1>`, true, false},
+		{"one H1", `<h1>One</h1><h2>Sub</h2>`, false, false},
+		{"no H1", `<h2>Sub</h2>`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			server := serveHTML(t, "<html><head></head><body>"+tt.body+"</body></html>")
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatalf("loading the crawl result: %v", err)
+			}
+			if result.MultipleH1 != tt.wantMultiple || result.MissingH1 != tt.wantMissing {
+				t.Errorf("multiple H1 %v, missing H1 %v; want %v, %v", result.MultipleH1, result.MissingH1, tt.wantMultiple, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestCrawlURLFlagsThinContent(t *testing.T) {
+	t.Setenv("THIN_CONTENT_WORDS", "20")
+	long := strings.Repeat("<p>Five words in this sentence.</p>", 10)
+	tests := []struct {
+		name      string
+		body      string
+		wantWords int
+		wantThin  bool
+	}{
+		{"short page", `<h1>Hello there</h1><p>Not much to read.</p><script>var ignored = "many words in a script";</script>`, 6, true},
+		{"long page", `<h1>Hello there</h1>` + long, 52, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			server := serveHTML(t, "<html><head><title>Words in the head</title></head><body>"+tt.body+"</body></html>")
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatalf("loading the crawl result: %v", err)
+			}
+			if result.WordCount != tt.wantWords || result.IsThinContent != tt.wantThin {
+				t.Errorf("word count %d, thin content %v; want %d, %v", result.WordCount, result.IsThinContent, tt.wantWords, tt.wantThin)
+			}
+		})
+	}
+}
+
+func TestCrawlURLRefusesDomainsOffTheAllowlist(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	// The URL was saved before the allow-list was set
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "example.com")
+
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err == nil {
+		t.Fatal("CrawlURL succeeded for a host off the allow-list")
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("the site got %d requests, want none", n)
+	}
+
+	var saved models.URL
+	if err := db.First(&saved, urlEntry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != models.StatusError {
+		t.Errorf("status = %s, want %s", saved.Status, models.StatusError)
+	}
+}
+
+func TestCrawlURLFlagsCanonicalMismatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		canonical string
+		want      bool
+	}{
+		{"points elsewhere", `<link rel="canonical" href="https://original.example/article">`, true},
+		{"points at the page", `<link rel="canonical" href="/#top">`, false},
+		{"no canonical", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			server := serveHTML(t, "<html><head>"+tt.canonical+"</head><body><h1>Article</h1></body></html>")
+			urlEntry := createRunningURL(t, db, server.URL+"/")
+			if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+				t.Fatalf("CrawlURL: %v", err)
+			}
+
+			var result models.CrawlResult
+			if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+				t.Fatalf("loading the crawl result: %v", err)
+			}
+			if result.CanonicalMismatch != tt.want {
+				t.Errorf("canonical mismatch = %v (canonical %q), want %v", result.CanonicalMismatch, result.CanonicalURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"a bit too long", 10, "a bit t..."},
+		{"日本語のテキストです", 10, "日本語のテキストです"}, // 10 characters, 30 bytes
+		{"日本語のテキストです！", 10, "日本語のテキス..."},
+		{"🙂🙂🙂🙂🙂", 4, "🙂..."},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlURLTruncatesMultiByteOpenGraph(t *testing.T) {
+	description := strings.Repeat("長い説明文🙂", 300) // 1,800 characters, far more bytes
+	db := testutil.NewDB(t)
+	server := serveHTML(t, `<html><head><title>CJK</title><meta property="og:description" content="`+description+`"></head><body></body></html>`)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	var result models.CrawlResult
+	if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+		t.Fatalf("loading the crawl result: %v", err)
+	}
+	if !utf8.ValidString(result.OGDescription) {
+		t.Error("the stored og:description isn't valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(result.OGDescription); n != 1024 || !strings.HasSuffix(result.OGDescription, "...") {
+		t.Errorf("stored og:description has %d characters, want 1024 ending in ...", n)
+	}
+}
@@ -0,0 +1,156 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+)
+
+// maxPageDelay caps the delay between linked pages, however long robots.txt or
+// rate limiting asks for
+const maxPageDelay = 30 * time.Second
+
+// linkedPage is a page queued for crawling by following an internal link
+type linkedPage struct {
+	url   string
+	depth int
+}
+
+// crawlLinkedPages follows the internal links of a URL's page breadth-first, up to the URL's
+// crawl depth, and saves each page reached as a child of the page's result. Every page is visited once per
+// crawl, at most CRAWL_MAX_PAGES pages are crawled and CRAWL_PAGE_DELAY is waited between
+// requests. Only pages on the URL's host are followed, and unless CRAWL_RESPECT_ROBOTS=false
+// its robots.txt is obeyed, including a longer Crawl-delay. The delay doubles whenever the
+// site answers 429 or 503. Pages that fail are logged and skipped; they don't fail the crawl.
+func (cs *CrawlerService) crawlLinkedPages(ctx context.Context, urlEntry *models.URL, root *models.CrawlResult, rootData *CrawlData, opts RenderOptions, maxChecked int) {
+	maxDepth := *urlEntry.CrawlDepth
+	maxPages := config.GetEnvInt("CRAWL_MAX_PAGES", 20)
+	delay := config.GetEnvDuration("CRAWL_PAGE_DELAY", 500*time.Millisecond)
+
+	rootURL, err := url.Parse(urlEntry.URL)
+	if err != nil {
+		return
+	}
+	visited := map[string]bool{normalizeURL(rootURL).String(): true}
+
+	robots := &robotsRules{}
+	if config.GetEnvBool("CRAWL_RESPECT_ROBOTS", true) {
+		robots = cs.fetchRobots(ctx, rootURL)
+	}
+	if robots.crawlDelay > delay {
+		delay = min(robots.crawlDelay, maxPageDelay)
+	}
+
+	var queue []linkedPage
+	enqueue := func(links []string, depth int) {
+		for _, link := range links {
+			if visited[link] || !cs.shouldCheckLink(link) {
+				continue
+			}
+			visited[link] = true
+
+			linkURL, err := url.Parse(link)
+			if err != nil || !strings.EqualFold(linkURL.Hostname(), rootURL.Hostname()) || !robots.allowed(linkURL) {
+				continue
+			}
+			queue = append(queue, linkedPage{url: link, depth: depth})
+		}
+	}
+	enqueue(rootData.InternalLinks, 1)
+
+	for crawled := 0; len(queue) > 0 && crawled < maxPages; crawled++ {
+		page := queue[0]
+		queue = queue[1:]
+
+		// Space out requests to the same site
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		crawlData, err := cs.crawlLinkedPage(ctx, root, page, linkedPageOptions(opts, rootURL, page.url), maxChecked)
+		if ctx.Err() != nil {
+			return
+		}
+		if crawlData != nil && (crawlData.ResponseStatus == http.StatusTooManyRequests || crawlData.ResponseStatus == http.StatusServiceUnavailable) {
+			delay = min(delay*2, maxPageDelay)
+		}
+		if err != nil {
+			log.Printf("Failed to crawl linked page %s of URL %d: %v", page.url, urlEntry.ID, err)
+			continue
+		}
+
+		if page.depth < maxDepth {
+			enqueue(crawlData.InternalLinks, page.depth+1)
+		}
+	}
+}
+
+// linkedPageOptions drops the URL's Authorization header for linked pages on another
+// origin (e.g. plain http), so credentials only go where the URL's owner sent them
+func linkedPageOptions(opts RenderOptions, rootURL *url.URL, pageURL string) RenderOptions {
+	page, err := url.Parse(pageURL)
+	if err == nil && sameOrigin(page, rootURL) {
+		return opts
+	}
+
+	headers := make(map[string]string, len(opts.Headers))
+	for key, value := range opts.Headers {
+		if !strings.EqualFold(key, "Authorization") {
+			headers[key] = value
+		}
+	}
+	opts.Headers = headers
+	return opts
+}
+
+// crawlLinkedPage fetches and analyzes one linked page and saves it as a child of root.
+// The page's data is returned along with the error when the server answered with an error.
+func (cs *CrawlerService) crawlLinkedPage(ctx context.Context, root *models.CrawlResult, page linkedPage, opts RenderOptions, maxChecked int) (*CrawlData, error) {
+	pageURL := truncate(page.url, 2048)
+
+	crawlData, err := cs.fetchAndAnalyze(ctx, page.url, opts)
+	if err != nil {
+		// Keep a record of linked pages that answered with an HTTP error
+		if crawlData != nil && crawlData.ResponseStatus != 0 {
+			errorResult := models.CrawlResult{
+				URLID:          root.URLID,
+				ParentID:       &root.ID,
+				PageURL:        pageURL,
+				Depth:          page.depth,
+				ResponseStatus: crawlData.ResponseStatus,
+			}
+			if err := cs.db.Create(&errorResult).Error; err != nil {
+				log.Printf("Failed to save error crawl result: %v", err)
+			}
+		}
+		return crawlData, err
+	}
+
+	brokenLinks, checkedAt := cs.checkLinks(ctx, crawlData, maxChecked)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result := newCrawlResult(root.URLID, crawlData, brokenLinks, checkedAt)
+	result.ParentID = &root.ID
+	result.PageURL = pageURL
+	result.Depth = page.depth
+	result.Changed = cs.contentChanged(root.URLID, &pageURL, crawlData.ContentHash)
+
+	if err := cs.db.Create(&result).Error; err != nil {
+		return nil, fmt.Errorf("failed to save crawl result: %w", err)
+	}
+	cs.storeLinks(&result, crawlData, brokenLinks, checkedAt)
+	cs.saveSnapshot(result.ID, crawlData)
+
+	return crawlData, nil
+}
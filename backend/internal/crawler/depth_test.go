@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+func TestCrawlLinkedPagesAtDepthOne(t *testing.T) {
+	t.Setenv("CRAWL_PAGE_DELAY", "0s")
+	db := testutil.NewDB(t)
+
+	var mux http.ServeMux
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body>
+				<a href="/about">About</a> <a href="/about#team">Team</a> <a href="/private">Private</a>
+			</body></html>`)
+		case "/about":
+			fmt.Fprint(w, `<html><head><title>About</title></head><body>
+				<a href="/">Home</a> <a href="/deep">Deep</a>
+			</body></html>`)
+		case "/deep", "/private":
+			t.Errorf("crawled %s, which is beyond depth 1 or disallowed by robots.txt", r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	server := httptest.NewServer(&mux)
+	defer server.Close()
+
+	user := models.User{Username: "depth", Email: "depth@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	depth, noLinkChecks := 1, 0
+	urlEntry := models.URL{
+		URL:             server.URL + "/",
+		UserID:          user.ID,
+		Status:          models.StatusRunning,
+		CrawlDepth:      &depth,
+		MaxLinksToCheck: &noLinkChecks,
+	}
+	if err := db.Create(&urlEntry).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	var results []models.CrawlResult
+	if err := db.Where("url_id = ?", urlEntry.ID).Order("id").Find(&results).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want the page and its one linked page", len(results))
+	}
+
+	root, child := results[0], results[1]
+	if root.ParentID != nil || root.Title != "Home" {
+		t.Errorf("root result = %+v, want the submitted page without a parent", root)
+	}
+	if child.ParentID == nil || *child.ParentID != root.ID || child.Depth != 1 || child.Title != "About" {
+		t.Errorf("child result = %+v, want the about page at depth 1 under %d", child, root.ID)
+	}
+	if child.PageURL != server.URL+"/about" {
+		t.Errorf("child page URL = %q, want %q", child.PageURL, server.URL+"/about")
+	}
+}
+
+func TestLinkedPageOptionsDropsCredentialsOffOrigin(t *testing.T) {
+	rootURL, _ := url.Parse("https://example.com/")
+	opts := RenderOptions{Headers: map[string]string{"Authorization": "Bearer secret", "Accept-Language": "de"}}
+
+	same := linkedPageOptions(opts, rootURL, "https://example.com/about")
+	if same.Headers["Authorization"] != "Bearer secret" {
+		t.Error("Authorization was dropped for a page on the same origin")
+	}
+
+	for _, page := range []string{"http://example.com/about", "https://example.com:8443/about", "https://other.example/"} {
+		other := linkedPageOptions(opts, rootURL, page)
+		if _, ok := other.Headers["Authorization"]; ok {
+			t.Errorf("Authorization was sent to %s", page)
+		}
+		if other.Headers["Accept-Language"] != "de" {
+			t.Errorf("Accept-Language was dropped for %s", page)
+		}
+	}
+	if opts.Headers["Authorization"] != "Bearer secret" {
+		t.Error("the URL's own options were modified")
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := parseRobots(strings.NewReader(`
+# Everyone else
+User-agent: *
+Disallow: /
+
+User-agent: Googlebot
+User-agent: skyell-crawler
+Disallow: /admin
+Allow: /admin/public
+Disallow: /*.pdf$
+Crawl-delay: 2
+`), DefaultUserAgent)
+
+	if robots.crawlDelay.Seconds() != 2 {
+		t.Errorf("crawl delay = %s, want 2s", robots.crawlDelay)
+	}
+
+	var allowed []string
+	for _, path := range []string{"/", "/admin", "/admin/users", "/admin/public/page", "/docs/a.pdf", "/docs/a.pdf?x=1"} {
+		u, _ := url.Parse(path)
+		if robots.allowed(u) {
+			allowed = append(allowed, path)
+		}
+	}
+	sort.Strings(allowed)
+	if want := "/ /admin/public/page /docs/a.pdf?x=1"; strings.Join(allowed, " ") != want {
+		t.Errorf("allowed paths = %v, want %s", allowed, want)
+	}
+
+	wildcard := parseRobots(strings.NewReader("User-agent: *\nDisallow: /private\n"), DefaultUserAgent)
+	if wildcard.allowed(&url.URL{Path: "/private/x"}) || !wildcard.allowed(&url.URL{Path: "/public"}) {
+		t.Error("the * group should apply when no group names the crawler")
+	}
+}
@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"log"
+	"time"
+
+	"skyell-backend/internal/models"
+)
+
+// RecordEvent appends a crawl lifecycle event to the audit trail. Failures are logged
+// rather than returned so auditing never blocks a crawl.
+// Every crawl status change records an event, so this also invalidates the user's
+// cached status snapshot and notifies their status streams.
+func (cs *CrawlerService) RecordEvent(urlID, userID uint, eventType models.CrawlEventType, detail string) {
+	cs.notifyEvent(urlID, userID, eventType, detail)
+
+	event := models.CrawlEvent{
+		URLID:  urlID,
+		UserID: userID,
+		Type:   eventType,
+		Detail: truncate(detail, 1024),
+	}
+	if err := cs.db.Create(&event).Error; err != nil {
+		log.Printf("Failed to record %s event for URL %d: %v", eventType, urlID, err)
+	}
+}
+
+// notifyEvent invalidates the user's cached status snapshot and tells their status
+// streams about an event, without recording it
+func (cs *CrawlerService) notifyEvent(urlID, userID uint, eventType models.CrawlEventType, detail string) {
+	cs.InvalidateStatus(userID)
+	cs.broker.publish(userID, StatusUpdate{
+		URLID:  urlID,
+		Event:  eventType,
+		Status: eventStatus(eventType),
+		Detail: detail,
+		At:     time.Now(),
+	})
+}
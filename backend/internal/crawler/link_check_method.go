@@ -0,0 +1,31 @@
+package crawler
+
+import (
+	"log"
+	"strings"
+)
+
+// linkCheckMethod selects the HTTP requests used to check whether a link is broken
+type linkCheckMethod string
+
+const (
+	// linkCheckHeadThenGet sends HEAD and falls back to GET when the HEAD request fails
+	linkCheckHeadThenGet linkCheckMethod = "head_then_get"
+	// linkCheckGetOnly always sends GET, for servers that answer HEAD with misleading statuses.
+	// Each check then starts downloading the linked page, which costs more bandwidth.
+	linkCheckGetOnly linkCheckMethod = "get_only"
+	// linkCheckHeadOnly only sends HEAD and treats a failed request as broken
+	linkCheckHeadOnly linkCheckMethod = "head_only"
+)
+
+// parseLinkCheckMethod reads a LINK_CHECK_METHOD value, falling back to head_then_get
+// when it isn't recognized
+func parseLinkCheckMethod(value string) linkCheckMethod {
+	switch method := linkCheckMethod(strings.ToLower(strings.TrimSpace(value))); method {
+	case linkCheckHeadThenGet, linkCheckGetOnly, linkCheckHeadOnly:
+		return method
+	default:
+		log.Printf("Unknown LINK_CHECK_METHOD %q; using %s", value, linkCheckHeadThenGet)
+		return linkCheckHeadThenGet
+	}
+}
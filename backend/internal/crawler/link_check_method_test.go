@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestParseLinkCheckMethod(t *testing.T) {
+	tests := map[string]linkCheckMethod{
+		"head_then_get": linkCheckHeadThenGet,
+		" GET_ONLY ":    linkCheckGetOnly,
+		"head_only":     linkCheckHeadOnly,
+		"":              linkCheckHeadThenGet,
+		"options":       linkCheckHeadThenGet,
+	}
+	for value, want := range tests {
+		if got := parseLinkCheckMethod(value); got != want {
+			t.Errorf("parseLinkCheckMethod(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+// methodServer answers GET with 200 and HEAD with head, recording the methods it receives
+func methodServer(t *testing.T, head http.HandlerFunc) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		if r.Method == http.MethodHead {
+			head(w, r)
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(methods)
+	}
+}
+
+func TestIsLinkBrokenCheckMethods(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "127.0.0.0/8,::1/128")
+
+	// Answers HEAD with a misleading 404 although GET works
+	misleading := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}
+	// Drops HEAD requests without a response
+	dropping := func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		head        http.HandlerFunc
+		wantBroken  bool
+		wantMethods []string
+	}{
+		{"head_then_get, misleading HEAD", "head_then_get", misleading, true, []string{"HEAD"}},
+		{"head_then_get, failing HEAD", "head_then_get", dropping, false, []string{"HEAD", "GET"}},
+		{"get_only, misleading HEAD", "get_only", misleading, false, []string{"GET"}},
+		{"get_only, failing HEAD", "get_only", dropping, false, []string{"GET"}},
+		{"head_only, misleading HEAD", "head_only", misleading, true, []string{"HEAD"}},
+		{"head_only, failing HEAD", "head_only", dropping, true, []string{"HEAD"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LINK_CHECK_METHOD", tt.method)
+			server, methods := methodServer(t, tt.head)
+
+			if broken := NewCrawlerService(nil).isLinkBroken(context.Background(), server.URL+"/link"); broken != tt.wantBroken {
+				t.Errorf("broken = %v, want %v", broken, tt.wantBroken)
+			}
+			// A dropped request may be retried by the transport, so only the sequence matters
+			if got := slices.Compact(methods()); !slices.Equal(got, tt.wantMethods) {
+				t.Errorf("requests = %v, want %v", got, tt.wantMethods)
+			}
+		})
+	}
+}
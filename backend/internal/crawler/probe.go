@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"context"
+	"mime"
+	"net/http"
+)
+
+// ProbeResult reports whether a URL is reachable and looks crawlable
+type ProbeResult struct {
+	Reachable   bool   `json:"reachable"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	FinalURL    string `json:"final_url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	IsHTML      bool   `json:"is_html"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Probe checks a URL with a HEAD request, falling back to GET for servers that
+// don't support HEAD, using the crawler's client, timeouts and headers. The body
+// is never read and nothing is persisted. Loopback, private and link-local addresses
+// are refused, including on redirects.
+func (cs *CrawlerService) Probe(ctx context.Context, targetURL string) *ProbeResult {
+	resp, err := cs.probeRequest(ctx, http.MethodHead, targetURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = cs.probeRequest(ctx, http.MethodGet, targetURL)
+	}
+	if err != nil {
+		return &ProbeResult{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	return &ProbeResult{
+		Reachable:   resp.StatusCode < 400,
+		StatusCode:  resp.StatusCode,
+		FinalURL:    resp.Request.URL.String(),
+		ContentType: contentType,
+		IsHTML:      mediaType == "text/html" || mediaType == "application/xhtml+xml",
+	}
+}
+
+func (cs *CrawlerService) probeRequest(ctx context.Context, method, targetURL string) (*http.Response, error) {
+	req, err := cs.newRequest(ctx, method, targetURL)
+	if err != nil {
+		return nil, err
+	}
+	return cs.probeClient.Do(req)
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeReachable(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "127.0.0.0/8,::1/128")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	result := NewCrawlerService(nil).Probe(context.Background(), server.URL)
+	if !result.Reachable || result.StatusCode != http.StatusOK || !result.IsHTML {
+		t.Errorf("probe = %+v, want a reachable HTML page", result)
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "127.0.0.0/8,::1/128")
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	result := NewCrawlerService(nil).Probe(context.Background(), server.URL)
+	if result.Reachable || result.Error == "" {
+		t.Errorf("probe of a closed port = %+v, want an unreachable result with an error", result)
+	}
+}
+
+func TestProbeRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("probe reached a loopback server")
+	}))
+	defer server.Close()
+
+	result := NewCrawlerService(nil).Probe(context.Background(), server.URL)
+	if result.Reachable || !strings.Contains(result.Error, "internal address") {
+		t.Errorf("probe = %+v, want the loopback address refused", result)
+	}
+}
+
+func TestProbeRefusesRedirectToInternalAddress(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "127.0.0.1/32")
+
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("probe followed a redirect to a blocked address")
+	}))
+	// Allowed loopback serves the redirect; the IPv6 loopback it points to is not allowed
+	internal.Listener.Close()
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	defer redirect.Close()
+
+	result := NewCrawlerService(nil).Probe(context.Background(), redirect.URL)
+	if result.Reachable || !strings.Contains(result.Error, "internal address") {
+		t.Errorf("probe = %+v, want the redirect target refused", result)
+	}
+}
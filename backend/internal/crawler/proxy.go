@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"skyell-backend/internal/config"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyFunc selects the proxy for the crawler's requests. CRAWLER_HTTP_PROXY and
+// CRAWLER_HTTPS_PROXY take precedence over the standard HTTP_PROXY and HTTPS_PROXY,
+// and hosts matching NO_PROXY are always fetched directly.
+func proxyFunc() func(*http.Request) (*url.URL, error) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if proxy := config.GetEnv("CRAWLER_HTTP_PROXY", ""); proxy != "" {
+		proxyConfig.HTTPProxy = proxy
+	}
+	if proxy := config.GetEnv("CRAWLER_HTTPS_PROXY", ""); proxy != "" {
+		proxyConfig.HTTPSProxy = proxy
+	}
+	if proxyConfig.HTTPProxy != "" || proxyConfig.HTTPSProxy != "" {
+		log.Printf("Crawler requests use proxy (http: %q, https: %q, no_proxy: %q)",
+			redactProxy(proxyConfig.HTTPProxy), redactProxy(proxyConfig.HTTPSProxy), proxyConfig.NoProxy)
+	}
+
+	proxy := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// redactProxy hides the password of a proxy URL for logging
+func redactProxy(raw string) string {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return proxyURL.Redacted()
+}
@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestProxyFuncSelection(t *testing.T) {
+	t.Setenv("HTTP_PROXY", "http://standard.proxy:3128")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("NO_PROXY", "direct.example")
+	t.Setenv("CRAWLER_HTTP_PROXY", "http://crawler.proxy:8080")
+	t.Setenv("CRAWLER_HTTPS_PROXY", "")
+
+	proxy := proxyFunc()
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"http://site.example/", "http://crawler.proxy:8080"},
+		{"http://direct.example/", ""},
+		{"https://site.example/", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.target, nil)
+		got, err := proxy(req)
+		if err != nil {
+			t.Fatalf("%s: proxy: %v", tt.target, err)
+		}
+		if (got == nil && tt.want != "") || (got != nil && got.String() != tt.want) {
+			t.Errorf("%s: proxy = %v, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCrawlerRequestsGoThroughProxy(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+
+		// A proxied request carries the absolute target URL
+		if !r.URL.IsAbs() || r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><head><title>Via proxy</title></head><body></body></html>`))
+	}))
+	defer proxy.Close()
+
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("CRAWLER_HTTP_PROXY", proxy.URL)
+
+	cs := NewCrawlerService(nil)
+	data, err := cs.fetchAndAnalyze(context.Background(), "http://site.example/", RenderOptions{FollowRedirects: true})
+	if err != nil {
+		t.Fatalf("fetchAndAnalyze: %v", err)
+	}
+	if data.Title != "Via proxy" {
+		t.Errorf("title = %q, want the page served by the proxy", data.Title)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hosts) == 0 {
+		t.Fatal("no request went through the proxy")
+	}
+	for _, host := range hosts {
+		if host != "site.example" {
+			t.Errorf("proxy saw host %q, want site.example", host)
+		}
+	}
+}
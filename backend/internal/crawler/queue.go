@@ -0,0 +1,246 @@
+package crawler
+
+import (
+	"container/heap"
+	"log"
+	"sync"
+)
+
+// Crawl priorities; higher values are crawled first
+const (
+	MinPriority         = 0
+	MaxPriority         = 10
+	DefaultPriority     = 5 // Single crawls started with StartCrawl
+	DefaultBulkPriority = 1 // Crawls started in bulk, so single crawls jump ahead of them
+)
+
+// crawlJob is a claimed URL waiting for a worker
+type crawlJob struct {
+	urlID    uint
+	userID   uint
+	priority int
+	seq      uint64 // Enqueue order, so jobs of equal priority run first-in first-out
+}
+
+// jobHeap orders jobs by priority, then by enqueue order. It implements heap.Interface.
+type jobHeap []*crawlJob
+
+func (h jobHeap) Len() int { return len(h) }
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*crawlJob)) }
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return job
+}
+
+// crawlQueue feeds claimed URLs to a fixed pool of workers, highest priority first
+type crawlQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   jobHeap
+	queued map[uint]bool // URL IDs currently in jobs, so a URL is never queued twice
+	seq    uint64
+	run    func(job *crawlJob)
+
+	// perUser caps each user's running jobs (0 means no cap); jobs of a user at the cap
+	// wait while other users' jobs go ahead
+	perUser int
+	running map[uint]int // Running jobs per user ID
+
+	// paused stops workers from taking new jobs; current holds each worker's job (nil
+	// when idle), indexed by worker
+	paused  bool
+	current []*crawlJob
+}
+
+// newCrawlQueue starts workers goroutines that call run for each job, running at most
+// perUser jobs of the same user at once
+func newCrawlQueue(workers, perUser int, run func(job *crawlJob)) *crawlQueue {
+	if workers < 1 {
+		workers = 1
+	}
+
+	q := &crawlQueue{
+		queued:  make(map[uint]bool),
+		run:     run,
+		perUser: max(perUser, 0),
+		running: make(map[uint]int),
+		current: make([]*crawlJob, workers),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	for i := 0; i < workers; i++ {
+		go q.work(i)
+	}
+	return q
+}
+
+// push adds a job unless its URL is already waiting in the queue
+func (q *crawlQueue) push(job *crawlJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.queued[job.urlID] {
+		return
+	}
+	q.seq++
+	job.seq = q.seq
+	heap.Push(&q.jobs, job)
+	q.queued[job.urlID] = true
+	q.cond.Signal()
+}
+
+// next blocks until the queue isn't paused and a job of a user below the per-user cap is
+// available, removes it from the queue and counts it as running on the given worker
+func (q *crawlQueue) next(worker int) *crawlJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if !q.paused {
+			if job := q.popRunnable(); job != nil {
+				delete(q.queued, job.urlID)
+				q.running[job.userID]++
+				q.current[worker] = job
+				return job
+			}
+		}
+		q.cond.Wait()
+	}
+}
+
+// popRunnable removes and returns the highest priority job whose user is below the
+// per-user cap, or nil if there is none. Must be called with mu held.
+func (q *crawlQueue) popRunnable() *crawlJob {
+	var skipped []*crawlJob
+	defer func() {
+		for _, job := range skipped {
+			heap.Push(&q.jobs, job)
+		}
+	}()
+
+	for len(q.jobs) > 0 {
+		job := heap.Pop(&q.jobs).(*crawlJob)
+		if q.perUser == 0 || q.running[job.userID] < q.perUser {
+			return job
+		}
+		skipped = append(skipped, job)
+	}
+	return nil
+}
+
+// done records that the worker finished its job, letting waiting jobs of its user run
+func (q *crawlQueue) done(worker int, job *crawlJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running[job.userID]--; q.running[job.userID] <= 0 {
+		delete(q.running, job.userID)
+	}
+	q.current[worker] = nil
+	q.cond.Broadcast()
+}
+
+// work runs jobs on the given worker until the process exits
+func (q *crawlQueue) work(worker int) {
+	for {
+		job := q.next(worker)
+		q.run(job)
+		q.done(worker, job)
+	}
+}
+
+// setPaused pauses or resumes handing out jobs. Running jobs aren't affected and queued
+// jobs keep their place.
+func (q *crawlQueue) setPaused(paused bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.paused = paused
+	q.cond.Broadcast()
+}
+
+// QueueStatus is a snapshot of the crawl queue
+type QueueStatus struct {
+	Paused  bool           `json:"paused"`
+	Depth   int            `json:"depth"` // Jobs waiting for a worker
+	Workers []WorkerStatus `json:"workers"`
+}
+
+// WorkerStatus is what a queue worker is doing; URLID is set while it runs a crawl
+type WorkerStatus struct {
+	ID    int    `json:"id"`
+	State string `json:"state"` // "idle" or "running"
+	URLID *uint  `json:"url_id,omitempty"`
+}
+
+// status takes a snapshot of the queue
+func (q *crawlQueue) status() QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	status := QueueStatus{
+		Paused:  q.paused,
+		Depth:   len(q.jobs),
+		Workers: make([]WorkerStatus, len(q.current)),
+	}
+	for i, job := range q.current {
+		status.Workers[i] = WorkerStatus{ID: i + 1, State: "idle"}
+		if job != nil {
+			urlID := job.urlID
+			status.Workers[i].State = "running"
+			status.Workers[i].URLID = &urlID
+		}
+	}
+	return status
+}
+
+// PauseQueue stops workers from starting queued crawls until ResumeQueue is called.
+// Crawls already running finish normally.
+func (cs *CrawlerService) PauseQueue() {
+	cs.queue.setPaused(true)
+}
+
+// ResumeQueue lets workers start queued crawls again after PauseQueue
+func (cs *CrawlerService) ResumeQueue() {
+	cs.queue.setPaused(false)
+}
+
+// QueueStatus reports whether the queue is paused, how many jobs wait and what each
+// worker is doing
+func (cs *CrawlerService) QueueStatus() QueueStatus {
+	return cs.queue.status()
+}
+
+// Enqueue schedules a claimed URL for crawling at the given priority (clamped to
+// MinPriority..MaxPriority). Up to CRAWLER_MAX_CONCURRENT crawls run at once, and at most
+// MAX_CONCURRENT_CRAWLS_PER_USER of them for the same user.
+func (cs *CrawlerService) Enqueue(urlID, userID uint, priority int) {
+	if priority < MinPriority {
+		priority = MinPriority
+	}
+	if priority > MaxPriority {
+		priority = MaxPriority
+	}
+	cs.queue.push(&crawlJob{urlID: urlID, userID: userID, priority: priority})
+}
+
+// runJob crawls a dequeued URL; URLs stopped while waiting are skipped by CrawlURL
+func (cs *CrawlerService) runJob(job *crawlJob) {
+	if err := cs.CrawlURL(job.urlID); err != nil {
+		log.Printf("Crawl error for URL %d: %v", job.urlID, err)
+	}
+}
@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receive collects n URL IDs from ran, failing the test if they don't arrive in time
+func receive(t *testing.T, ran <-chan uint, n int) []uint {
+	t.Helper()
+	var ids []uint
+	for len(ids) < n {
+		select {
+		case id := <-ran:
+			ids = append(ids, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("ran %v, want %d jobs", ids, n)
+		}
+	}
+	return ids
+}
+
+func TestQueueRunsHigherPriorityFirst(t *testing.T) {
+	ran := make(chan uint, 10)
+	q := newCrawlQueue(1, 0, func(job *crawlJob) { ran <- job.urlID })
+	q.setPaused(true)
+
+	// A bulk start, then an urgent single crawl
+	for _, id := range []uint{1, 2, 3} {
+		q.push(&crawlJob{urlID: id, userID: 1, priority: DefaultBulkPriority})
+	}
+	q.push(&crawlJob{urlID: 4, userID: 1, priority: DefaultPriority})
+	q.push(&crawlJob{urlID: 2, userID: 1, priority: MaxPriority}) // Already queued, so ignored
+	if depth := q.status().Depth; depth != 4 {
+		t.Errorf("depth = %d, want 4", depth)
+	}
+
+	q.setPaused(false)
+	if got, want := receive(t, ran, 4), []uint{4, 1, 2, 3}; !slices.Equal(got, want) {
+		t.Errorf("ran %v, want the urgent job first, then the rest in order: %v", got, want)
+	}
+}
+
+func TestQueueCapsRunningJobsPerUser(t *testing.T) {
+	ran := make(chan uint, 10)
+	release := make(chan struct{})
+	q := newCrawlQueue(2, 1, func(job *crawlJob) {
+		ran <- job.urlID
+		<-release
+	})
+	q.setPaused(true)
+
+	// User 1's second job waits for their first, even though a worker is free
+	q.push(&crawlJob{urlID: 1, userID: 1, priority: MaxPriority})
+	q.push(&crawlJob{urlID: 2, userID: 1, priority: MaxPriority})
+	q.push(&crawlJob{urlID: 3, userID: 2, priority: MinPriority})
+	q.setPaused(false)
+
+	got := receive(t, ran, 2)
+	slices.Sort(got)
+	if !slices.Equal(got, []uint{1, 3}) {
+		t.Errorf("first jobs = %v, want one per user: [1 3]", got)
+	}
+	select {
+	case id := <-ran:
+		t.Errorf("job %d started while its user was at the cap", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if got := receive(t, ran, 1); got[0] != 2 {
+		t.Errorf("next job = %d, want 2", got[0])
+	}
+}
+
+func TestEnqueueClampsPriority(t *testing.T) {
+	cs := NewCrawlerService(nil)
+	cs.PauseQueue()
+	cs.Enqueue(1, 1, MaxPriority+5)
+	cs.Enqueue(2, 1, MinPriority-5)
+
+	cs.queue.mu.Lock()
+	defer cs.queue.mu.Unlock()
+	for _, job := range cs.queue.jobs {
+		want := MaxPriority
+		if job.urlID == 2 {
+			want = MinPriority
+		}
+		if job.priority != want {
+			t.Errorf("URL %d queued at priority %d, want %d", job.urlID, job.priority, want)
+		}
+	}
+}
+
+func TestQueueUserNeverExceedsCap(t *testing.T) {
+	const limit, jobs = 2, 20
+	var mu sync.Mutex
+	running, peak := 0, 0
+	ran := make(chan uint, jobs)
+	q := newCrawlQueue(8, limit, func(job *crawlJob) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		ran <- job.urlID
+	})
+	q.setPaused(true)
+	for id := uint(1); id <= jobs; id++ {
+		q.push(&crawlJob{urlID: id, userID: 1, priority: DefaultBulkPriority})
+	}
+	q.setPaused(false)
+
+	receive(t, ran, jobs)
+	mu.Lock()
+	defer mu.Unlock()
+	if peak > limit {
+		t.Errorf("peak running jobs = %d, want at most %d", peak, limit)
+	}
+}
+
+func TestQueuePauseAndResume(t *testing.T) {
+	ran := make(chan uint, 10)
+	release := make(chan struct{})
+	q := newCrawlQueue(1, 0, func(job *crawlJob) {
+		ran <- job.urlID
+		<-release
+	})
+
+	q.push(&crawlJob{urlID: 1, userID: 1, priority: DefaultPriority})
+	receive(t, ran, 1)
+
+	// Pausing lets the running job finish but starts nothing new
+	q.setPaused(true)
+	q.push(&crawlJob{urlID: 2, userID: 1, priority: DefaultPriority})
+	q.push(&crawlJob{urlID: 3, userID: 2, priority: DefaultPriority})
+	status := q.status()
+	if !status.Paused || status.Depth != 2 || status.Workers[0].State != "running" || *status.Workers[0].URLID != 1 {
+		t.Errorf("status = %+v, want paused with 2 queued and job 1 running", status)
+	}
+
+	close(release)
+	select {
+	case id := <-ran:
+		t.Errorf("job %d started while paused", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if status := q.status(); status.Depth != 2 || status.Workers[0].State != "idle" {
+		t.Errorf("status = %+v, want 2 queued and the worker idle", status)
+	}
+
+	// Resuming runs the waiting jobs in order
+	q.setPaused(false)
+	if got := receive(t, ran, 2); !slices.Equal(got, []uint{2, 3}) {
+		t.Errorf("ran %v after resuming, want [2 3]", got)
+	}
+	if status := q.status(); status.Paused || status.Depth != 0 {
+		t.Errorf("status = %+v, want resumed and empty", status)
+	}
+}
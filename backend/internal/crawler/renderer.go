@@ -0,0 +1,338 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"skyell-backend/internal/config"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// Page is a fetched page ready for analysis
+type Page struct {
+	StatusCode int
+	FinalURL   *url.URL
+	Header     http.Header
+	Body       []byte // Not read for error responses (status >= 400)
+
+	// UTF8 is set when Body was already decoded to UTF-8 by the renderer
+	UTF8 bool
+
+	// TLS describes the page's certificate when it was served over https
+	TLS *TLSInfo
+}
+
+// RenderOptions controls how a single page is fetched
+type RenderOptions struct {
+	Headers         map[string]string // Sent only for the page itself, never for its resources or other origins
+	FollowRedirects bool
+	Timeout         time.Duration // Overrides the renderer's default when non-zero
+}
+
+// Renderer fetches the HTML of a page. fetchAndAnalyze analyzes the result the same
+// way regardless of which renderer produced it.
+type Renderer interface {
+	Render(ctx context.Context, targetURL string, opts RenderOptions) (*Page, error)
+}
+
+// newRenderer picks the renderer from RENDER_MODE: "headless" renders pages in the
+// Chrome instance at CHROME_ENDPOINT, anything else fetches raw HTML over HTTP
+func newRenderer(cs *CrawlerService) Renderer {
+	if config.GetEnv("RENDER_MODE", "http") != "headless" {
+		return &httpRenderer{cs: cs}
+	}
+
+	endpoint := config.GetEnv("CHROME_ENDPOINT", "")
+	if endpoint == "" {
+		log.Println("RENDER_MODE=headless requires CHROME_ENDPOINT; falling back to HTTP fetching")
+		return &httpRenderer{cs: cs}
+	}
+
+	return &headlessRenderer{
+		cs:       cs,
+		endpoint: endpoint,
+		timeout:  config.GetEnvDuration("RENDER_TIMEOUT", 30*time.Second),
+		wait:     config.GetEnvDuration("RENDER_WAIT", time.Second),
+	}
+}
+
+// httpRenderer fetches the raw HTML with the crawler's HTTP client
+type httpRenderer struct {
+	cs *CrawlerService
+}
+
+func (r *httpRenderer) Render(ctx context.Context, targetURL string, opts RenderOptions) (*Page, error) {
+	req, err := r.cs.newRequest(ctx, http.MethodGet, targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for key, value := range opts.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// Apply per-request overrides to a copy of the shared client
+	client := *r.cs.client
+	if opts.Timeout > 0 {
+		client.Timeout = opts.Timeout
+	}
+	if !opts.FollowRedirects {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	page := &Page{
+		StatusCode: resp.StatusCode,
+		FinalURL:   resp.Request.URL,
+		Header:     resp.Header,
+		TLS:        tlsInfo(resp.TLS, resp.Request.URL.Hostname()),
+	}
+	if resp.StatusCode >= 400 {
+		return page, nil
+	}
+
+	page.Body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return page, nil
+}
+
+// headlessRenderer loads the page in a remote Chrome over the DevTools Protocol and
+// returns the DOM after scripts have run. Chrome always follows redirects, so pages that
+// must not follow them are checked over plain HTTP first.
+type headlessRenderer struct {
+	cs       *CrawlerService
+	endpoint string        // ws:// or http:// DevTools endpoint
+	timeout  time.Duration // Upper bound for loading and rendering the page
+	wait     time.Duration // Extra time after load for client-side rendering to settle
+}
+
+func (r *headlessRenderer) Render(ctx context.Context, targetURL string, opts RenderOptions) (*Page, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", targetURL, err)
+	}
+
+	if !opts.FollowRedirects {
+		page, err := (&httpRenderer{cs: r.cs}).Render(ctx, targetURL, opts)
+		if err != nil || isRedirect(page.StatusCode) {
+			return page, err
+		}
+	}
+
+	allocCtx, cancelAlloc := chromedp.NewRemoteAllocator(ctx, r.endpoint)
+	defer cancelAlloc()
+
+	tabCtx, cancelTab := chromedp.NewContext(allocCtx)
+	defer cancelTab()
+
+	timeout := r.timeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, timeout)
+	defer cancelTimeout()
+
+	// Capture the status, headers and certificate of the main document response
+	var (
+		mu         sync.Mutex
+		statusCode int
+		respHeader = http.Header{}
+		certInfo   *TLSInfo
+	)
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		e, ok := ev.(*network.EventResponseReceived)
+		if !ok || e.Type != network.ResourceTypeDocument {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if statusCode != 0 {
+			return // Only the first document (the page itself, not iframes)
+		}
+		statusCode = int(e.Response.Status)
+		for key, value := range e.Response.Headers {
+			respHeader.Set(key, fmt.Sprint(value))
+		}
+		if details := e.Response.SecurityDetails; details != nil && details.ValidTo != nil {
+			// Chrome refuses to load pages with invalid certificates
+			certInfo = &TLSInfo{Valid: true, ExpiresAt: details.ValidTo.Time()}
+		}
+	})
+
+	// The page's own headers (such as its credentials) are added to the top-level document
+	// request only while it stays on the target's origin, never to the scripts, images and
+	// third-party hosts the page loads or to redirects leaving the origin
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		e, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+
+		headers := requestHeaders(e.Request.Headers)
+		if isPageRequest(e, chromedp.FromContext(tabCtx).Target.TargetID, target) {
+			headers = withHeaders(headers, opts.Headers)
+		}
+		go func() {
+			executor := cdp.WithExecutor(tabCtx, chromedp.FromContext(tabCtx).Target)
+			// Redirects of the page must stay on CRAWL_DOMAIN_ALLOWLIST too
+			if !navigationAllowed(e, chromedp.FromContext(tabCtx).Target.TargetID) {
+				if err := fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient).Do(executor); err != nil && tabCtx.Err() == nil {
+					log.Printf("Failed to block request for %s: %v", e.Request.URL, err)
+				}
+				return
+			}
+			if err := fetch.ContinueRequest(e.RequestID).WithHeaders(headers).Do(executor); err != nil && tabCtx.Err() == nil {
+				log.Printf("Failed to continue request for %s: %v", e.Request.URL, err)
+			}
+		}()
+	})
+
+	extraHeaders := network.Headers{}
+	for key, value := range r.cs.extraHeaders {
+		extraHeaders[key] = value
+	}
+
+	var html, location string
+	if err := chromedp.Run(tabCtx,
+		network.Enable(),
+		fetch.Enable().WithPatterns([]*fetch.RequestPattern{
+			{URLPattern: "*", ResourceType: network.ResourceTypeDocument, RequestStage: fetch.RequestStageRequest},
+		}),
+		emulation.SetUserAgentOverride(r.cs.userAgent),
+		network.SetExtraHTTPHeaders(extraHeaders),
+		chromedp.Navigate(targetURL),
+		chromedp.Sleep(r.wait),
+		chromedp.Location(&location),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	); err != nil {
+		return nil, fmt.Errorf("failed to render URL: %w", err)
+	}
+
+	finalURL, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid final URL %q: %w", location, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	page := &Page{
+		StatusCode: statusCode,
+		FinalURL:   finalURL,
+		Header:     respHeader,
+		UTF8:       true, // Chrome decodes the page before serializing the DOM
+		TLS:        certInfo,
+	}
+	if statusCode < 400 {
+		page.Body = []byte(html)
+	}
+	return page, nil
+}
+
+// isRedirect reports whether status is a redirect that carries a Location
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// isPageRequest reports whether a paused request loads the page itself: a document
+// requested by the tab's main frame (whose frame ID is the target ID) on the target's
+// scheme, host and port. Iframes and redirects to other origins don't qualify.
+func isPageRequest(e *fetch.EventRequestPaused, mainFrame target.ID, targetURL *url.URL) bool {
+	if e.ResourceType != network.ResourceTypeDocument || string(e.FrameID) != string(mainFrame) {
+		return false
+	}
+	requestURL, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return false
+	}
+	return sameOrigin(requestURL, targetURL)
+}
+
+// navigationAllowed reports whether a paused request may continue: anything but a
+// document of the tab's main frame on a host outside CRAWL_DOMAIN_ALLOWLIST
+func navigationAllowed(e *fetch.EventRequestPaused, mainFrame target.ID) bool {
+	if e.ResourceType != network.ResourceTypeDocument || string(e.FrameID) != string(mainFrame) {
+		return true
+	}
+	requestURL, err := url.Parse(e.Request.URL)
+	return err == nil && config.CrawlDomainAllowed(requestURL.Hostname())
+}
+
+// sameOrigin reports whether a and b have the same scheme, host and port
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Hostname(), b.Hostname()) && originPort(a) == originPort(b)
+}
+
+// originPort is the URL's port, or the default port of its scheme
+func originPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
+
+// requestHeaders converts the headers of a paused request for fetch.ContinueRequest
+func requestHeaders(headers network.Headers) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(headers))
+	for name, value := range headers {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	return entries
+}
+
+// withHeaders sets the extra headers on entries, replacing headers of the same name
+func withHeaders(entries []*fetch.HeaderEntry, extra map[string]string) []*fetch.HeaderEntry {
+	result := make([]*fetch.HeaderEntry, 0, len(entries)+len(extra))
+	for _, entry := range entries {
+		if !hasHeader(extra, entry.Name) {
+			result = append(result, entry)
+		}
+	}
+	for name, value := range extra {
+		result = append(result, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return result
+}
+
+// hasHeader reports whether headers contains name, ignoring case
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
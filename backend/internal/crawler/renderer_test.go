@@ -0,0 +1,188 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+// fakeRenderer returns canned HTML and records what it was asked to render
+type fakeRenderer struct {
+	html string
+
+	renderedURL string
+	opts        RenderOptions
+}
+
+func (r *fakeRenderer) Render(ctx context.Context, targetURL string, opts RenderOptions) (*Page, error) {
+	r.renderedURL = targetURL
+	r.opts = opts
+
+	finalURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{
+		StatusCode: http.StatusOK,
+		FinalURL:   finalURL,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       []byte(r.html),
+		UTF8:       true,
+	}, nil
+}
+
+func TestFetchAndAnalyzeUsesRenderer(t *testing.T) {
+	renderer := &fakeRenderer{html: `<!DOCTYPE html>
+<html><head>
+<title>Rendered app</title>
+<link rel="icon" href="/icon.png">
+</head><body>
+<h1>Client-side content</h1>
+<a href="/about">About</a>
+<a href="https://other.example/">Elsewhere</a>
+</body></html>`}
+
+	cs := NewCrawlerService(nil)
+	cs.renderer = renderer
+
+	opts := RenderOptions{Headers: map[string]string{"Authorization": "Bearer secret"}, FollowRedirects: true}
+	data, err := cs.fetchAndAnalyze(context.Background(), "https://example.com/app", opts)
+	if err != nil {
+		t.Fatalf("fetchAndAnalyze: %v", err)
+	}
+
+	if renderer.renderedURL != "https://example.com/app" {
+		t.Errorf("rendered %q, want the target URL", renderer.renderedURL)
+	}
+	if renderer.opts.Headers["Authorization"] != "Bearer secret" || !renderer.opts.FollowRedirects {
+		t.Errorf("renderer got options %+v, want the crawl's options", renderer.opts)
+	}
+
+	if data.Title != "Rendered app" {
+		t.Errorf("title = %q, want %q", data.Title, "Rendered app")
+	}
+	if data.HTMLVersion != "HTML5" {
+		t.Errorf("HTML version = %q, want HTML5", data.HTMLVersion)
+	}
+	if data.TagCounts["h1"] != 1 {
+		t.Errorf("H1 count = %d, want 1", data.TagCounts["h1"])
+	}
+	if len(data.InternalLinks) != 1 || data.InternalLinks[0] != "https://example.com/about" {
+		t.Errorf("internal links = %v, want [https://example.com/about]", data.InternalLinks)
+	}
+	if len(data.ExternalLinks) != 1 || data.ExternalLinks[0] != "https://other.example/" {
+		t.Errorf("external links = %v, want [https://other.example/]", data.ExternalLinks)
+	}
+	if !data.HasFavicon {
+		t.Error("favicon declared in the page was not detected")
+	}
+}
+
+func TestIsPageRequest(t *testing.T) {
+	target, _ := url.Parse("https://example.com/app")
+	const mainFrame = "MAIN"
+
+	paused := func(rawURL string, frame string, resourceType network.ResourceType) *fetch.EventRequestPaused {
+		return &fetch.EventRequestPaused{
+			Request:      &network.Request{URL: rawURL},
+			FrameID:      cdp.FrameID(frame),
+			ResourceType: resourceType,
+		}
+	}
+
+	tests := []struct {
+		name string
+		ev   *fetch.EventRequestPaused
+		want bool
+	}{
+		{"page itself", paused("https://example.com/app", mainFrame, network.ResourceTypeDocument), true},
+		{"redirect on the same origin", paused("https://EXAMPLE.com:443/login", mainFrame, network.ResourceTypeDocument), true},
+		{"redirect to another host", paused("https://accounts.example.net/", mainFrame, network.ResourceTypeDocument), false},
+		{"redirect to plain http", paused("http://example.com/app", mainFrame, network.ResourceTypeDocument), false},
+		{"iframe", paused("https://example.com/frame", "CHILD", network.ResourceTypeDocument), false},
+		{"script", paused("https://example.com/app.js", mainFrame, network.ResourceTypeScript), false},
+	}
+	for _, tt := range tests {
+		if got := isPageRequest(tt.ev, mainFrame, target); got != tt.want {
+			t.Errorf("%s: isPageRequest = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNavigationAllowed(t *testing.T) {
+	t.Setenv("CRAWL_DOMAIN_ALLOWLIST", "example.com")
+	const mainFrame = "MAIN"
+
+	paused := func(rawURL string, frame string, resourceType network.ResourceType) *fetch.EventRequestPaused {
+		return &fetch.EventRequestPaused{
+			Request:      &network.Request{URL: rawURL},
+			FrameID:      cdp.FrameID(frame),
+			ResourceType: resourceType,
+		}
+	}
+
+	tests := []struct {
+		name string
+		ev   *fetch.EventRequestPaused
+		want bool
+	}{
+		{"page on the allow-list", paused("https://example.com/app", mainFrame, network.ResourceTypeDocument), true},
+		{"redirect off the allow-list", paused("https://evil.example/", mainFrame, network.ResourceTypeDocument), false},
+		{"iframe off the allow-list", paused("https://evil.example/frame", "CHILD", network.ResourceTypeDocument), true},
+		{"script off the allow-list", paused("https://cdn.example/app.js", mainFrame, network.ResourceTypeScript), true},
+	}
+	for _, tt := range tests {
+		if got := navigationAllowed(tt.ev, mainFrame); got != tt.want {
+			t.Errorf("%s: navigationAllowed = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWithHeadersReplacesCaseInsensitively(t *testing.T) {
+	entries := []*fetch.HeaderEntry{
+		{Name: "accept-language", Value: "en"},
+		{Name: "Accept", Value: "text/html"},
+	}
+	got := withHeaders(entries, map[string]string{"Accept-Language": "de", "Authorization": "Basic x"})
+
+	values := make(map[string][]string)
+	for _, entry := range got {
+		values[http.CanonicalHeaderKey(entry.Name)] = append(values[http.CanonicalHeaderKey(entry.Name)], entry.Value)
+	}
+	if len(values["Accept-Language"]) != 1 || values["Accept-Language"][0] != "de" {
+		t.Errorf("Accept-Language = %v, want [de]", values["Accept-Language"])
+	}
+	if len(values["Accept"]) != 1 || values["Accept"][0] != "text/html" {
+		t.Errorf("Accept = %v, want [text/html]", values["Accept"])
+	}
+	if len(values["Authorization"]) != 1 {
+		t.Errorf("Authorization = %v, want one value", values["Authorization"])
+	}
+}
+
+func TestHeadlessRendererHonoursFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("https://elsewhere.example/", http.StatusFound))
+	defer server.Close()
+
+	// The DevTools endpoint is never reached: the redirect is found over plain HTTP first
+	cs := NewCrawlerService(nil)
+	renderer := &headlessRenderer{cs: cs, endpoint: "ws://127.0.0.1:1/devtools", timeout: time.Second}
+
+	page, err := renderer.Render(context.Background(), server.URL, RenderOptions{FollowRedirects: false})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if page.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want %d", page.StatusCode, http.StatusFound)
+	}
+	if location := page.Header.Get("Location"); location != "https://elsewhere.example/" {
+		t.Errorf("Location = %q, want the redirect target", location)
+	}
+}
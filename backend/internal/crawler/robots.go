@@ -0,0 +1,165 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsSize is how much of a robots.txt is read; the rest is ignored
+const maxRobotsSize = 512 << 10
+
+// robotsRule is an Allow or Disallow line of robots.txt
+type robotsRule struct {
+	allow   bool
+	length  int // Length of the pattern; the longest matching rule wins
+	pattern *regexp.Regexp
+}
+
+// robotsRules are the robots.txt rules that apply to the crawler on one site
+type robotsRules struct {
+	rules       []robotsRule
+	crawlDelay  time.Duration
+	disallowAll bool
+}
+
+// allowed reports whether robots.txt lets the crawler fetch a URL
+func (r *robotsRules) allowed(u *url.URL) bool {
+	if r.disallowAll {
+		return false
+	}
+
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if rule.length < best || !rule.pattern.MatchString(target) {
+			continue
+		}
+		// Allow wins over a Disallow of the same length
+		if rule.length > best || rule.allow {
+			allow = rule.allow
+		}
+		best = rule.length
+	}
+	return allow
+}
+
+// fetchRobots loads the robots.txt of a site. A missing file allows everything; a server
+// error or an unreachable site disallows everything, as RFC 9309 asks.
+func (cs *CrawlerService) fetchRobots(ctx context.Context, site *url.URL) *robotsRules {
+	robotsURL := url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/robots.txt"}
+	req, err := cs.newRequest(ctx, http.MethodGet, robotsURL.String())
+	if err != nil {
+		return &robotsRules{}
+	}
+	resp, err := cs.client.Do(req)
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{disallowAll: true}
+	case resp.StatusCode >= 400:
+		return &robotsRules{}
+	}
+	return parseRobots(io.LimitReader(resp.Body, maxRobotsSize), cs.userAgent)
+}
+
+// parseRobots reads the rules of the groups naming the crawler's product token (the part
+// of the User-Agent before the first "/"), or of the "*" group when none do
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	token := strings.ToLower(strings.TrimSpace(strings.SplitN(userAgent, "/", 2)[0]))
+
+	var specific, wildcard robotsRules
+	var matchSpecific, matchWildcard, inAgents, named bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			// A User-agent line after rules starts a new group
+			if !inAgents {
+				matchSpecific, matchWildcard = false, false
+			}
+			inAgents = true
+
+			agent := strings.ToLower(value)
+			if agent == "*" {
+				matchWildcard = true
+			} else if agent != "" && strings.HasPrefix(token, agent) {
+				matchSpecific, named = true, true
+			}
+			continue
+		}
+		inAgents = false
+
+		for _, group := range []struct {
+			rules *robotsRules
+			match bool
+		}{{&specific, matchSpecific}, {&wildcard, matchWildcard}} {
+			if group.match {
+				group.rules.add(key, value)
+			}
+		}
+	}
+
+	if named {
+		return &specific
+	}
+	return &wildcard
+}
+
+// add applies one Allow, Disallow or Crawl-delay line to the rules
+func (r *robotsRules) add(key, value string) {
+	switch key {
+	case "allow", "disallow":
+		if value == "" {
+			return // An empty Disallow allows everything
+		}
+		r.rules = append(r.rules, robotsRule{
+			allow:   key == "allow",
+			length:  len(value),
+			pattern: robotsPattern(value),
+		})
+	case "crawl-delay":
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+			r.crawlDelay = time.Duration(seconds * float64(time.Second))
+		}
+	}
+}
+
+// robotsPattern compiles a robots.txt path pattern, where * matches anything and a
+// trailing $ anchors the end of the URL
+func robotsPattern(value string) *regexp.Regexp {
+	anchored := strings.HasSuffix(value, "$")
+	value = strings.TrimSuffix(value, "$")
+
+	pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(value), `\*`, ".*")
+	if anchored {
+		pattern += "$"
+	}
+	return regexp.MustCompile(pattern)
+}
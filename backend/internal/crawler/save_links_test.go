@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// linkedCrawlData is crawl data with n links, half of them internal
+func linkedCrawlData(n int) *CrawlData {
+	data := &CrawlData{}
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			data.InternalLinks = append(data.InternalLinks, fmt.Sprintf("https://example.com/page/%d", i))
+		} else {
+			data.ExternalLinks = append(data.ExternalLinks, fmt.Sprintf("https://other.example/page/%d", i))
+		}
+	}
+	return data
+}
+
+// createCrawlResult saves a crawl result of urlID counting the links in data
+func createCrawlResult(tb testing.TB, db *gorm.DB, urlID uint, data *CrawlData) models.CrawlResult {
+	tb.Helper()
+	result := models.CrawlResult{URLID: urlID, InternalLinks: len(data.InternalLinks), ExternalLinks: len(data.ExternalLinks)}
+	if err := db.Create(&result).Error; err != nil {
+		tb.Fatal(err)
+	}
+	return result
+}
+
+// failLinkBatches makes every link insert after the first batch fail
+func failLinkBatches(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	batches := 0
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_links", func(tx *gorm.DB) {
+		if tx.Statement.Table == "links" {
+			if batches++; batches > 1 {
+				tx.AddError(errors.New("row too long"))
+			}
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveLinksRollsBackOnFailure(t *testing.T) {
+	t.Setenv("LINK_INSERT_BATCH_SIZE", "2")
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, "https://example.com")
+	data := linkedCrawlData(5)
+	result := createCrawlResult(t, db, urlEntry.ID, data)
+	failLinkBatches(t, db)
+
+	cs := NewCrawlerService(db)
+	if err := cs.saveLinks(result.ID, data, nil, nil); err == nil {
+		t.Fatal("saveLinks succeeded although the second batch failed")
+	}
+	var stored int64
+	db.Model(&models.Link{}).Where("crawl_result_id = ?", result.ID).Count(&stored)
+	if stored != 0 {
+		t.Errorf("%d links stored, want the first batch rolled back too", stored)
+	}
+
+	// The result's counts follow what was stored
+	cs.storeLinks(&result, data, nil, nil)
+	var saved models.CrawlResult
+	if err := db.First(&saved, result.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.InternalLinks != 0 || saved.ExternalLinks != 0 || result.InternalLinks != 0 {
+		t.Errorf("link counts = %d internal, %d external, want 0 with no links stored", saved.InternalLinks, saved.ExternalLinks)
+	}
+}
+
+func BenchmarkSaveLinks(b *testing.B) {
+	const links = 500
+	data := linkedCrawlData(links)
+
+	b.Run("per_row", func(b *testing.B) {
+		db := testutil.NewDB(b)
+		urlEntry := createRunningURL(b, db, "https://example.com")
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			result := createCrawlResult(b, db, urlEntry.ID, data)
+			b.StartTimer()
+
+			// What saveLinks did before it batched: one INSERT per link
+			insert := func(links []string, linkType models.LinkType) {
+				for _, link := range links {
+					entry := models.Link{CrawlResultID: result.ID, URL: truncate(link, 500), Type: linkType, OccurrenceCount: 1}
+					if err := db.Create(&entry).Error; err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			insert(data.InternalLinks, models.LinkTypeInternal)
+			insert(data.ExternalLinks, models.LinkTypeExternal)
+		}
+	})
+
+	b.Run("batched", func(b *testing.B) {
+		db := testutil.NewDB(b)
+		urlEntry := createRunningURL(b, db, "https://example.com")
+		cs := NewCrawlerService(db)
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			result := createCrawlResult(b, db, urlEntry.ID, data)
+			b.StartTimer()
+
+			if err := cs.saveLinks(result.ID, data, nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// snapshotsEnabled reports whether the analyzed HTML is stored with each crawl result
+func snapshotsEnabled() bool {
+	return config.GetEnvBool("STORE_SNAPSHOTS", false)
+}
+
+// saveSnapshot stores the analyzed HTML of a crawl result, gzip-compressed and capped at
+// SNAPSHOT_MAX_BYTES. It does nothing when the crawl data carries no HTML.
+func (cs *CrawlerService) saveSnapshot(crawlResultID uint, crawlData *CrawlData) {
+	if crawlData.HTML == nil {
+		return
+	}
+
+	html := crawlData.HTML
+	truncated := false
+	if maxBytes := config.GetEnvInt("SNAPSHOT_MAX_BYTES", 2<<20); maxBytes > 0 && len(html) > maxBytes {
+		html = html[:maxBytes]
+		truncated = true
+	}
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(html); err != nil {
+		log.Printf("Failed to compress snapshot of crawl result %d: %v", crawlResultID, err)
+		return
+	}
+	if err := gz.Close(); err != nil {
+		log.Printf("Failed to compress snapshot of crawl result %d: %v", crawlResultID, err)
+		return
+	}
+
+	snapshot := models.CrawlSnapshot{
+		CrawlResultID: crawlResultID,
+		HTML:          compressed.Bytes(),
+		Size:          len(html),
+		Truncated:     truncated,
+	}
+	if err := cs.db.Create(&snapshot).Error; err != nil {
+		log.Printf("Failed to save snapshot of crawl result %d: %v", crawlResultID, err)
+	}
+}
+
+// copySnapshot gives a reused crawl result a copy of the previous result's snapshot, if any
+func copySnapshot(tx *gorm.DB, fromResultID, toResultID uint) error {
+	var snapshot models.CrawlSnapshot
+	err := tx.Where("crawl_result_id = ?", fromResultID).Limit(1).Find(&snapshot).Error
+	if err != nil || snapshot.ID == 0 {
+		return err
+	}
+
+	snapshot.ID = 0
+	snapshot.CrawlResultID = toResultID
+	return tx.Create(&snapshot).Error
+}
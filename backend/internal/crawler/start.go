@@ -0,0 +1,79 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDailyCrawlLimit is returned by StartCrawls when the crawls would take the user past
+// their daily crawl limit
+var ErrDailyCrawlLimit = errors.New("daily crawl limit reached")
+
+// StartCrawls claims the user's URLs like ClaimURL and records their started events in
+// the same transaction, returning the IDs it claimed. URLs that are already running are
+// skipped. The started events are what dailyLimit counts (0 means no limit): if the claims
+// would take the user's crawls started today past it, nothing is claimed and
+// ErrDailyCrawlLimit is returned. The user's row is locked for the transaction, so
+// concurrent starts of the same user are counted one after the other.
+func (cs *CrawlerService) StartCrawls(userID uint, urlIDs []uint, dailyLimit int) ([]uint, error) {
+	var claimed []uint
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		claimed = nil
+
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		for _, urlID := range urlIDs {
+			result := tx.Model(&models.URL{}).
+				Where("id = ? AND user_id = ? AND status != ?", urlID, userID, models.StatusRunning).
+				Updates(map[string]interface{}{
+					"status":        models.StatusRunning,
+					"error_message": "",
+				})
+			if result.Error != nil {
+				return fmt.Errorf("failed to claim URL: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			event := models.CrawlEvent{URLID: urlID, UserID: userID, Type: models.EventStarted}
+			if err := tx.Create(&event).Error; err != nil {
+				return fmt.Errorf("failed to record started event: %w", err)
+			}
+			claimed = append(claimed, urlID)
+		}
+
+		if dailyLimit <= 0 || len(claimed) == 0 {
+			return nil
+		}
+		now := time.Now()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		var started int64
+		if err := tx.Model(&models.CrawlEvent{}).
+			Where("user_id = ? AND type = ? AND created_at >= ?", userID, models.EventStarted, startOfDay).
+			Count(&started).Error; err != nil {
+			return fmt.Errorf("failed to count crawls started today: %w", err)
+		}
+		if started > int64(dailyLimit) {
+			return ErrDailyCrawlLimit
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, urlID := range claimed {
+		cs.notifyEvent(urlID, userID, models.EventStarted, "")
+	}
+	return claimed, nil
+}
@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+
+	"skyell-backend/internal/models"
+)
+
+// StatusSnapshot is a user's URL statuses along with a count per status.
+// Snapshots are shared between callers and must not be modified.
+type StatusSnapshot struct {
+	URLs    []models.URL
+	Summary map[string]int
+}
+
+type statusCacheEntry struct {
+	snapshot  *StatusSnapshot
+	expiresAt time.Time
+}
+
+// statusCache holds recent status snapshots per user. It's bounded to maxEntries users;
+// when full, expired entries are dropped first and then the one expiring soonest.
+type statusCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[uint]statusCacheEntry
+
+	// generations counts each user's invalidations, so a snapshot read before an
+	// invalidation isn't cached after it
+	generations map[uint]uint64
+}
+
+func newStatusCache(ttl time.Duration, maxEntries int) *statusCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &statusCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[uint]statusCacheEntry),
+
+		generations: make(map[uint]uint64),
+	}
+}
+
+// get returns the user's snapshot if it hasn't expired. Otherwise it returns the user's
+// current generation, to pass to set with the snapshot read in its place.
+func (sc *statusCache) get(userID uint) (*StatusSnapshot, uint64, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	entry, ok := sc.entries[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, sc.generations[userID], false
+	}
+	return entry.snapshot, 0, true
+}
+
+// set stores the user's snapshot, evicting entries if the cache is full. The snapshot is
+// dropped if the user's statuses were invalidated since generation was returned by get.
+func (sc *statusCache) set(userID uint, generation uint64, snapshot *StatusSnapshot) {
+	if sc.ttl <= 0 {
+		return // Caching disabled
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.generations[userID] != generation {
+		return // Stale: read before the latest invalidation
+	}
+
+	now := time.Now()
+	if _, ok := sc.entries[userID]; !ok && len(sc.entries) >= sc.maxEntries {
+		for id, entry := range sc.entries {
+			if now.After(entry.expiresAt) {
+				delete(sc.entries, id)
+			}
+		}
+		if len(sc.entries) >= sc.maxEntries {
+			var oldestID uint
+			var oldest time.Time
+			for id, entry := range sc.entries {
+				if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+					oldestID, oldest = id, entry.expiresAt
+				}
+			}
+			delete(sc.entries, oldestID)
+		}
+	}
+
+	sc.entries[userID] = statusCacheEntry{snapshot: snapshot, expiresAt: now.Add(sc.ttl)}
+}
+
+// invalidate drops the user's snapshot so the next poll sees fresh statuses
+func (sc *statusCache) invalidate(userID uint) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.entries, userID)
+	sc.generations[userID]++
+}
+
+// StatusSnapshot returns the statuses of all the user's URLs, served from a short-lived
+// cache (STATUS_CACHE_TTL) that's invalidated whenever a crawl changes one of them
+func (cs *CrawlerService) StatusSnapshot(userID uint) (*StatusSnapshot, error) {
+	snapshot, generation, ok := cs.statuses.get(userID)
+	if ok {
+		return snapshot, nil
+	}
+
+	var urls []models.URL
+	if err := cs.db.Where("user_id = ?", userID).Select("id, url, status, error_message, updated_at").Find(&urls).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot = &StatusSnapshot{URLs: urls, Summary: SummarizeStatuses(urls)}
+	cs.statuses.set(userID, generation, snapshot)
+	return snapshot, nil
+}
+
+// InvalidateStatus drops the user's cached status snapshot. Call it after any change to
+// the user's URLs, not just their crawl status.
+func (cs *CrawlerService) InvalidateStatus(userID uint) {
+	cs.statuses.invalidate(userID)
+}
+
+// SummarizeStatuses counts the URLs in each status
+func SummarizeStatuses(urls []models.URL) map[string]int {
+	summary := map[string]int{
+		string(models.StatusQueued):    0,
+		string(models.StatusRunning):   0,
+		string(models.StatusCompleted): 0,
+		string(models.StatusError):     0,
+	}
+	for _, url := range urls {
+		summary[string(url.Status)]++
+	}
+	return summary
+}
@@ -0,0 +1,175 @@
+package crawler
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestStatusSnapshotIsCachedUntilInvalidated(t *testing.T) {
+	t.Setenv("STATUS_CACHE_TTL", "1m")
+	db := testutil.NewDB(t)
+	server := serveHTML(t, `<html><head><title>Status</title></head><body></body></html>`)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	cs := NewCrawlerService(db)
+
+	summary := func() map[string]int {
+		t.Helper()
+		snapshot, err := cs.StatusSnapshot(urlEntry.UserID)
+		if err != nil {
+			t.Fatalf("StatusSnapshot: %v", err)
+		}
+		return snapshot.Summary
+	}
+	if got := summary()[string(models.StatusRunning)]; got != 1 {
+		t.Fatalf("running = %d, want 1", got)
+	}
+
+	// A change the crawler doesn't know about is hidden by the cache
+	if err := db.Model(&urlEntry).Update("status", models.StatusQueued).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := summary()[string(models.StatusRunning)]; got != 1 {
+		t.Errorf("running = %d within the TTL, want the cached 1", got)
+	}
+	cs.InvalidateStatus(urlEntry.UserID)
+	if got := summary()[string(models.StatusQueued)]; got != 1 {
+		t.Errorf("queued = %d after invalidating, want 1", got)
+	}
+
+	// A crawl invalidates the snapshot when it changes the status
+	if err := db.Model(&urlEntry).Update("status", models.StatusRunning).Error; err != nil {
+		t.Fatal(err)
+	}
+	cs.InvalidateStatus(urlEntry.UserID)
+	summary()
+	if err := cs.CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+	if got := summary(); got[string(models.StatusCompleted)] != 1 || got[string(models.StatusRunning)] != 0 {
+		t.Errorf("summary after the crawl = %v, want the URL completed", got)
+	}
+}
+
+func TestStatusSnapshotIgnoresInvalidationDuringRead(t *testing.T) {
+	t.Setenv("STATUS_CACHE_TTL", "1m")
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, "https://example.com")
+	cs := NewCrawlerService(db)
+
+	// The crawl finishes while the snapshot is being read
+	invalidated := false
+	if err := db.Callback().Query().After("gorm:query").Register("test:finish_crawl", func(tx *gorm.DB) {
+		if tx.Statement.Table == "urls" && !invalidated {
+			invalidated = true
+			db.Session(&gorm.Session{SkipHooks: true}).Model(&models.URL{}).Where("id = ?", urlEntry.ID).Update("status", models.StatusCompleted)
+			cs.InvalidateStatus(urlEntry.UserID)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := cs.StatusSnapshot(urlEntry.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stale.Summary[string(models.StatusRunning)] != 1 {
+		t.Fatalf("summary = %v, want the URL read as running", stale.Summary)
+	}
+	fresh, err := cs.StatusSnapshot(urlEntry.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Summary[string(models.StatusCompleted)] != 1 {
+		t.Errorf("summary after the crawl = %v, want the snapshot read before it not cached", fresh.Summary)
+	}
+}
+
+func TestStatusCacheDropsSnapshotsReadBeforeInvalidation(t *testing.T) {
+	sc := newStatusCache(time.Minute, 10)
+	_, generation, _ := sc.get(1)
+	sc.invalidate(1)
+	sc.set(1, generation, &StatusSnapshot{})
+	if _, _, ok := sc.get(1); ok {
+		t.Error("snapshot read before an invalidation was cached")
+	}
+
+	_, generation, _ = sc.get(1)
+	sc.set(1, generation, &StatusSnapshot{})
+	if _, _, ok := sc.get(1); !ok {
+		t.Error("snapshot read after the invalidation wasn't cached")
+	}
+}
+
+func TestStatusCacheExpires(t *testing.T) {
+	sc := newStatusCache(20*time.Millisecond, 10)
+	sc.set(1, 0, &StatusSnapshot{})
+	if _, _, ok := sc.get(1); !ok {
+		t.Fatal("snapshot missing right after it was set")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, _, ok := sc.get(1); ok {
+		t.Error("snapshot served after its TTL")
+	}
+
+	disabled := newStatusCache(0, 10)
+	disabled.set(1, 0, &StatusSnapshot{})
+	if _, _, ok := disabled.get(1); ok {
+		t.Error("snapshot cached with a zero TTL")
+	}
+}
+
+func TestStatusCacheIsBounded(t *testing.T) {
+	sc := newStatusCache(time.Minute, 2)
+	sc.set(1, 0, &StatusSnapshot{})
+	time.Sleep(time.Millisecond)
+	sc.set(2, 0, &StatusSnapshot{})
+	time.Sleep(time.Millisecond)
+	sc.set(3, 0, &StatusSnapshot{})
+
+	if len(sc.entries) != 2 {
+		t.Fatalf("%d entries, want at most 2", len(sc.entries))
+	}
+	if _, _, ok := sc.get(1); ok {
+		t.Error("the entry expiring soonest was kept over newer ones")
+	}
+	for _, userID := range []uint{2, 3} {
+		if _, _, ok := sc.get(userID); !ok {
+			t.Errorf("user %d was evicted", userID)
+		}
+	}
+}
+
+func TestStatusCacheConcurrentUse(t *testing.T) {
+	sc := newStatusCache(time.Minute, 8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				_, generation, _ := sc.get(userID)
+				snapshot := &StatusSnapshot{Summary: map[string]int{fmt.Sprint(j): j}}
+				sc.set(userID, generation, snapshot)
+				if got, _, ok := sc.get(userID); ok && got == nil {
+					t.Errorf("user %d: cached a nil snapshot", userID)
+				}
+				if j%10 == 0 {
+					sc.invalidate(userID)
+				}
+			}
+		}(uint(i % 12))
+	}
+	wg.Wait()
+
+	if len(sc.entries) > 8 {
+		t.Errorf("%d entries, want at most 8", len(sc.entries))
+	}
+}
@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+
+	"skyell-backend/internal/models"
+)
+
+// StatusUpdate is a crawl status change pushed to a user's status stream
+type StatusUpdate struct {
+	URLID  uint                  `json:"url_id"`
+	Event  models.CrawlEventType `json:"event"`
+	Status models.CrawlStatus    `json:"status"`
+	Detail string                `json:"detail,omitempty"`
+	At     time.Time             `json:"at"`
+}
+
+// statusUpdateBuffer is how many updates a subscriber may fall behind before new ones are dropped
+const statusUpdateBuffer = 32
+
+// statusBroker fans status updates out to each user's subscribers
+type statusBroker struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan StatusUpdate]struct{}
+}
+
+func newStatusBroker() *statusBroker {
+	return &statusBroker{subscribers: make(map[uint]map[chan StatusUpdate]struct{})}
+}
+
+// subscribe registers a subscriber for the user's updates; the returned function removes it
+func (b *statusBroker) subscribe(userID uint) (<-chan StatusUpdate, func()) {
+	ch := make(chan StatusUpdate, statusUpdateBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan StatusUpdate]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[userID], ch)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+		})
+	}
+}
+
+// publish sends an update to the user's subscribers without blocking; a subscriber whose
+// buffer is full misses the update
+func (b *statusBroker) publish(userID uint, update StatusUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[userID] {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// eventStatus is the URL status a crawl event leaves behind
+func eventStatus(eventType models.CrawlEventType) models.CrawlStatus {
+	switch eventType {
+	case models.EventStarted:
+		return models.StatusRunning
+	case models.EventCompleted:
+		return models.StatusCompleted
+	case models.EventFailed:
+		return models.StatusError
+	default:
+		return models.StatusQueued
+	}
+}
+
+// SubscribeStatus streams the user's crawl status changes until the returned function is called
+func (cs *CrawlerService) SubscribeStatus(userID uint) (<-chan StatusUpdate, func()) {
+	return cs.broker.subscribe(userID)
+}
@@ -0,0 +1,51 @@
+package crawler
+
+import (
+	"testing"
+
+	"skyell-backend/internal/models"
+)
+
+func TestStatusBroker(t *testing.T) {
+	b := newStatusBroker()
+	first, unsubscribeFirst := b.subscribe(1)
+	second, unsubscribeSecond := b.subscribe(1)
+	other, unsubscribeOther := b.subscribe(2)
+	defer unsubscribeOther()
+
+	b.publish(1, StatusUpdate{URLID: 7, Event: models.EventCompleted})
+	for i, ch := range []<-chan StatusUpdate{first, second} {
+		select {
+		case update := <-ch:
+			if update.URLID != 7 {
+				t.Errorf("subscriber %d got %+v, want URL 7", i, update)
+			}
+		default:
+			t.Errorf("subscriber %d got no update", i)
+		}
+	}
+	select {
+	case update := <-other:
+		t.Errorf("another user's subscriber got %+v", update)
+	default:
+	}
+
+	// A subscriber that stopped reading doesn't block publishing
+	for i := 0; i < statusUpdateBuffer+5; i++ {
+		b.publish(1, StatusUpdate{URLID: uint(i)})
+	}
+	if len(first) != statusUpdateBuffer {
+		t.Errorf("buffered %d updates, want %d", len(first), statusUpdateBuffer)
+	}
+
+	// Unsubscribing removes the subscriber, and the user once nobody is left
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if n := len(b.subscribers[1]); n != 1 {
+		t.Errorf("%d subscribers left, want 1", n)
+	}
+	unsubscribeSecond()
+	if _, ok := b.subscribers[1]; ok {
+		t.Error("the user is still registered after every subscriber left")
+	}
+}
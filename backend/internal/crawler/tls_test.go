@@ -0,0 +1,88 @@
+package crawler
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+)
+
+// serveSelfSigned starts an https test server, whose certificate no system root trusts
+func serveSelfSigned(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Internal</title></head><body></body></html>`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSelfSignedCertificateFailsTheCrawl(t *testing.T) {
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, serveSelfSigned(t).URL+"/")
+
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err == nil {
+		t.Fatal("crawling a page with a self-signed certificate succeeded with verification on")
+	}
+	if err := db.First(&urlEntry, urlEntry.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if urlEntry.Status != models.StatusError {
+		t.Errorf("URL status = %q, want %q", urlEntry.Status, models.StatusError)
+	}
+}
+
+func TestInsecureSkipTLSVerify(t *testing.T) {
+	t.Setenv("INSECURE_SKIP_TLS_VERIFY", "true")
+	db := testutil.NewDB(t)
+	server := serveSelfSigned(t)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	var result models.CrawlResult
+	if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+		t.Fatalf("loading the crawl result: %v", err)
+	}
+	if result.Title != "Internal" {
+		t.Errorf("title = %q, want the page to be analyzed", result.Title)
+	}
+	// Skipping verification doesn't make the certificate valid
+	if result.CertificateValid == nil || *result.CertificateValid {
+		t.Errorf("certificate valid = %v, want false", result.CertificateValid)
+	}
+	wantExpiry := server.Certificate().NotAfter
+	if result.CertificateExpiresAt == nil || !result.CertificateExpiresAt.Equal(wantExpiry) {
+		t.Errorf("certificate expires at %v, want %v", result.CertificateExpiresAt, wantExpiry)
+	}
+}
+
+func TestPlainHTTPHasNoCertificate(t *testing.T) {
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, serveHTML(t, `<html><head><title>Plain</title></head></html>`).URL+"/")
+
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+	var result models.CrawlResult
+	if err := db.Where("url_id = ?", urlEntry.ID).First(&result).Error; err != nil {
+		t.Fatalf("loading the crawl result: %v", err)
+	}
+	if result.CertificateValid != nil || result.CertificateExpiresAt != nil {
+		t.Errorf("certificate valid %v, expires at %v; want neither over plain http", result.CertificateValid, result.CertificateExpiresAt)
+	}
+
+	if info := tlsInfo(&tls.ConnectionState{}, "example.com"); info != nil {
+		t.Errorf("tlsInfo without peer certificates = %+v, want nil", info)
+	}
+}
@@ -0,0 +1,74 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"skyell-backend/internal/testutil"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCrawlURLTracesPhasesAndPropagatesContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+		provider.Shutdown(context.Background())
+	})
+
+	var mu sync.Mutex
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			mu.Lock()
+			traceparent = r.Header.Get("Traceparent")
+			mu.Unlock()
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Traced</title></head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	db := testutil.NewDB(t)
+	urlEntry := createRunningURL(t, db, server.URL+"/")
+	if err := NewCrawlerService(db).CrawlURL(urlEntry.ID); err != nil {
+		t.Fatalf("CrawlURL: %v", err)
+	}
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	crawl, ok := spans["crawl"]
+	if !ok {
+		t.Fatalf("spans = %v, want a crawl span", exporter.GetSpans())
+	}
+	for _, name := range []string{"fetch", "parse", "check_links"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("no %s span was recorded", name)
+			continue
+		}
+		if span.Parent.SpanID() != crawl.SpanContext.SpanID() {
+			t.Errorf("%s span isn't a child of the crawl span", name)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	traceID := crawl.SpanContext.TraceID().String()
+	if len(traceparent) < 35 || traceparent[3:35] != traceID {
+		t.Errorf("traceparent = %q, want the crawl's trace %s", traceparent, traceID)
+	}
+}
@@ -0,0 +1,131 @@
+package crawler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/netguard"
+)
+
+// WebhookSignatureHeader carries the hex HMAC-SHA256 of the payload, keyed by the user's
+// webhook secret, as "sha256=<hex>"
+const WebhookSignatureHeader = "X-Skyell-Signature"
+
+// webhookClient delivers webhooks; it doesn't share the crawler's client so slow
+// receivers can't hold up crawls. Internal addresses are refused, including on redirects.
+var webhookClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: netguard.Transport(http.DefaultTransport.(*http.Transport)),
+}
+
+// WebhookPayload is the JSON body posted when a crawl completes or fails
+type WebhookPayload struct {
+	Event     string             `json:"event"` // crawl.completed or crawl.failed
+	URLID     uint               `json:"url_id"`
+	URL       string             `json:"url"`
+	Status    models.CrawlStatus `json:"status"`
+	Error     string             `json:"error,omitempty"`
+	ResultID  uint               `json:"result_id,omitempty"`
+	Summary   *WebhookSummary    `json:"summary,omitempty"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// WebhookSummary holds the link counts of a completed crawl
+type WebhookSummary struct {
+	InternalLinks int `json:"internal_links"`
+	ExternalLinks int `json:"external_links"`
+	BrokenLinks   int `json:"broken_links"`
+}
+
+// SignWebhook returns the signature header value for a payload
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyWebhook posts the outcome of a crawl to the owner's webhook, if one is configured.
+// Delivery is retried up to WEBHOOK_MAX_ATTEMPTS times with exponential backoff; failures
+// are logged and never affect the crawl.
+func (cs *CrawlerService) notifyWebhook(urlEntry *models.URL, status models.CrawlStatus, errorMessage string, crawlResult *models.CrawlResult) {
+	var user models.User
+	if err := cs.db.Select("id, webhook_url, webhook_secret").First(&user, urlEntry.UserID).Error; err != nil {
+		log.Printf("Failed to load webhook settings for user %d: %v", urlEntry.UserID, err)
+		return
+	}
+	if user.WebhookURL == "" {
+		return
+	}
+
+	payload := WebhookPayload{
+		Event:     "crawl.completed",
+		URLID:     urlEntry.ID,
+		URL:       urlEntry.URL,
+		Status:    status,
+		Error:     errorMessage,
+		Timestamp: time.Now().UTC(),
+	}
+	if status == models.StatusError {
+		payload.Event = "crawl.failed"
+	}
+	if crawlResult != nil {
+		payload.ResultID = crawlResult.ID
+		payload.Summary = &WebhookSummary{
+			InternalLinks: crawlResult.InternalLinks,
+			ExternalLinks: crawlResult.ExternalLinks,
+			BrokenLinks:   crawlResult.BrokenLinks,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode webhook for URL %d: %v", urlEntry.ID, err)
+		return
+	}
+	signature := SignWebhook(user.WebhookSecret, body)
+
+	maxAttempts := config.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 3)
+	backoff := time.Second
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = deliverWebhook(user.WebhookURL, body, signature)
+		if err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Printf("Failed to deliver webhook for URL %d after %d attempts: %v", urlEntry.ID, maxAttempts, err)
+}
+
+// deliverWebhook makes a single delivery attempt; any non-2xx response is a failure
+func deliverWebhook(webhookURL string, body []byte, signature string) error {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	req.Header.Set(WebhookSignatureHeader, signature)
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/netguard"
+)
+
+func TestDeliverWebhookIsSigned(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "127.0.0.0/8,::1/128")
+	const secret = "webhook-secret"
+
+	received := make(chan WebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading body: %v", err)
+		}
+
+		// Verify the way a receiver would, without SignWebhook
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if got := r.Header.Get(WebhookSignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		received <- payload
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	body, _ := json.Marshal(WebhookPayload{
+		Event:     "crawl.completed",
+		URLID:     7,
+		URL:       "https://example.com",
+		Status:    models.StatusCompleted,
+		Timestamp: time.Now().UTC(),
+	})
+	if err := deliverWebhook(server.URL, body, SignWebhook(secret, body)); err != nil {
+		t.Fatalf("deliverWebhook: %v", err)
+	}
+
+	payload := <-received
+	if payload.Event != "crawl.completed" || payload.URLID != 7 {
+		t.Errorf("payload = %+v, want the delivered event", payload)
+	}
+}
+
+func TestDeliverWebhookFailsOnErrorStatus(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "127.0.0.0/8,::1/128")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := deliverWebhook(server.URL, []byte("{}"), SignWebhook("secret", []byte("{}"))); err == nil {
+		t.Error("deliverWebhook succeeded on a 500 response")
+	}
+}
+
+func TestDeliverWebhookRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook was delivered to a loopback address")
+	}))
+	defer server.Close()
+
+	err := deliverWebhook(server.URL, []byte("{}"), SignWebhook("secret", []byte("{}")))
+	if !errors.Is(err, netguard.ErrInternalAddress) {
+		t.Errorf("deliverWebhook = %v, want ErrInternalAddress", err)
+	}
+}
@@ -1,9 +1,13 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
+	"log"
 	"os"
+	"time"
 
+	"skyell-backend/internal/config"
 	"skyell-backend/internal/models"
 
 	"gorm.io/driver/mysql"
@@ -11,7 +15,20 @@ import (
 )
 
 func Connect() (*gorm.DB, error) {
-	// Get database configuration from environment variables
+	// Prefer a single DATABASE_URL, as provided by managed platforms
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		driver, dsn, err := ParseDatabaseURL(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		d, err := dialector(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		return open(d)
+	}
+
+	// Otherwise get database configuration from the individual DB_* variables
 	dbHost := os.Getenv("DB_HOST")
 	if dbHost == "" {
 		dbHost = "localhost"
@@ -38,24 +55,116 @@ func Connect() (*gorm.DB, error) {
 	}
 
 	// Create DSN (Data Source Name)
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC",
 		dbUser, dbPassword, dbHost, dbPort, dbName)
 
-	// Connect to database
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	return open(mysql.Open(dsn))
+}
+
+// open connects with the given dialector and applies the pool settings
+func open(d gorm.Dialector) (*gorm.DB, error) {
+	db, err := connectWithRetry(func() (*gorm.DB, error) {
+		// Unique index violations come back as gorm.ErrDuplicatedKey on every driver
+		return gorm.Open(d, &gorm.Config{TranslateError: true})
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := UseUTC(db); err != nil {
+		return nil, fmt.Errorf("failed to configure UTC timestamps: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	ConfigurePool(sqlDB)
+
 	return db, nil
 }
 
+// connectBackoff is the wait after the first failed connection attempt; it doubles after
+// each further failure, up to 10s
+var connectBackoff = 500 * time.Millisecond
+
+// connectWithRetry calls connect until it succeeds, so the server can start before the
+// database is ready. Failed attempts are retried with exponential backoff, at most
+// DB_CONNECT_RETRIES times and for no longer than DB_CONNECT_TIMEOUT.
+func connectWithRetry(connect func() (*gorm.DB, error)) (*gorm.DB, error) {
+	retries := max(config.GetEnvInt("DB_CONNECT_RETRIES", 10), 0)
+	deadline := time.Now().Add(config.GetEnvDuration("DB_CONNECT_TIMEOUT", time.Minute))
+	backoff := connectBackoff
+
+	for attempt := 1; ; attempt++ {
+		db, err := connect()
+		if err == nil {
+			return db, nil
+		}
+		if attempt > retries || time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("Database connection attempt %d failed, retrying in %s: %v", attempt, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, 10*time.Second)
+	}
+}
+
+// ConfigurePool applies DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS and DB_CONN_MAX_LIFETIME
+// to the connection pool
+func ConfigurePool(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(config.GetEnvInt("DB_MAX_OPEN_CONNS", 25))
+	sqlDB.SetMaxIdleConns(config.GetEnvInt("DB_MAX_IDLE_CONNS", 10))
+	sqlDB.SetConnMaxLifetime(config.GetEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute))
+}
+
 func Migrate(db *gorm.DB) error {
 	// Auto-migrate all models
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&models.URL{},
 		&models.CrawlResult{},
 		&models.Link{},
 		&models.User{},
-	)
+		&models.PasswordReset{},
+		&models.LoginAttempt{},
+		&models.CrawlEvent{},
+		&models.IdempotencyKey{},
+		&models.Tag{},
+		&models.APIKey{},
+		&models.CrawlSnapshot{},
+		&models.ResultView{},
+	); err != nil {
+		return err
+	}
+
+	return createFullTextIndex(db)
+}
+
+// fullTextIndexName is the name of the full-text index on crawl result titles
+const fullTextIndexName = "idx_crawl_results_title_fulltext"
+
+// createFullTextIndex adds a full-text index on crawl result titles where the database supports it
+func createFullTextIndex(db *gorm.DB) error {
+	switch db.Dialector.Name() {
+	case "mysql":
+		if db.Migrator().HasIndex(&models.CrawlResult{}, fullTextIndexName) {
+			return nil
+		}
+		return db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON crawl_results (title)", fullTextIndexName)).Error
+	case "postgres":
+		return db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON crawl_results USING GIN (to_tsvector('simple', coalesce(title, '')))", fullTextIndexName)).Error
+	default:
+		return nil
+	}
+}
+
+// HasFullTextSearch reports whether full-text search on crawl result titles is available
+func HasFullTextSearch(db *gorm.DB) bool {
+	switch db.Dialector.Name() {
+	case "mysql", "postgres":
+		return db.Migrator().HasIndex(&models.CrawlResult{}, fullTextIndexName)
+	default:
+		return false
+	}
 }
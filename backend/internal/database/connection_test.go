@@ -0,0 +1,73 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/database"
+	"skyell-backend/internal/testutil"
+)
+
+func TestConfigurePool(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "3")
+	t.Setenv("DB_MAX_IDLE_CONNS", "1")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "50ms")
+
+	db := testutil.NewDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.ConfigurePool(sqlDB)
+
+	if got := sqlDB.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("max open connections = %d, want 3", got)
+	}
+
+	// Hold three connections, then release them: only one may stay idle
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+	if stats := sqlDB.Stats(); stats.Idle != 1 || stats.MaxIdleClosed < 2 {
+		t.Errorf("idle = %d, closed for the idle limit = %d, want 1 idle and at least 2 closed", stats.Idle, stats.MaxIdleClosed)
+	}
+
+	// The idle connection outlives its lifetime and is closed instead of reused
+	time.Sleep(100 * time.Millisecond)
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if got := sqlDB.Stats().MaxLifetimeClosed; got < 1 {
+		t.Errorf("closed for the lifetime limit = %d, want at least 1", got)
+	}
+}
+
+func TestConfigurePoolDefaults(t *testing.T) {
+	for _, key := range []string{"DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME"} {
+		t.Setenv(key, "")
+	}
+
+	db := testutil.NewDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.ConfigurePool(sqlDB)
+
+	if got := sqlDB.Stats().MaxOpenConnections; got != 25 {
+		t.Errorf("max open connections = %d, want the default 25", got)
+	}
+}
@@ -0,0 +1,7 @@
+package database
+
+// Unexported identifiers used by the external tests
+var (
+	ConnectWithRetry = connectWithRetry
+	ConnectBackoff   = &connectBackoff
+)
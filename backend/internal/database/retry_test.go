@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"skyell-backend/internal/database"
+
+	"gorm.io/gorm"
+)
+
+// failingConnector fails its first failures calls, then connects
+type failingConnector struct {
+	failures int
+	calls    int
+}
+
+func (f *failingConnector) connect() (*gorm.DB, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errors.New("connection refused")
+	}
+	return &gorm.DB{}, nil
+}
+
+// setBackoff makes the first retry wait d for the rest of the test
+func setBackoff(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := *database.ConnectBackoff
+	*database.ConnectBackoff = d
+	t.Cleanup(func() { *database.ConnectBackoff = old })
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	setBackoff(t, time.Millisecond)
+	t.Setenv("DB_CONNECT_TIMEOUT", "1m")
+
+	tests := []struct {
+		name      string
+		retries   string
+		failures  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"connects first time", "3", 0, 1, false},
+		{"connects after failures", "3", 2, 3, false},
+		{"connects on the last retry", "3", 3, 4, false},
+		{"gives up after the retries", "3", 10, 4, true},
+		{"no retries", "0", 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DB_CONNECT_RETRIES", tt.retries)
+			connector := &failingConnector{failures: tt.failures}
+
+			db, err := database.ConnectWithRetry(connector.connect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && db == nil {
+				t.Error("no database returned")
+			}
+			if connector.calls != tt.wantCalls {
+				t.Errorf("attempts = %d, want %d", connector.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestConnectWithRetryTimeout(t *testing.T) {
+	setBackoff(t, 20*time.Millisecond)
+	t.Setenv("DB_CONNECT_RETRIES", "100")
+	t.Setenv("DB_CONNECT_TIMEOUT", "100ms")
+
+	// Waits of 20ms, 40ms and 80ms would end past the deadline, so it gives up after 3 attempts
+	connector := &failingConnector{failures: 100}
+	start := time.Now()
+	if _, err := database.ConnectWithRetry(connector.connect); err == nil {
+		t.Fatal("connected, want the timeout to give up")
+	}
+	if connector.calls != 3 {
+		t.Errorf("attempts = %d, want 3", connector.calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %s, want within the 100ms timeout", elapsed)
+	}
+}
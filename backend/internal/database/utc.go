@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// UseUTC makes every model timestamp UTC: the ones GORM sets on create and update, and
+// all time fields of the models queries load. Drivers otherwise scan times in their
+// connection's location, and pgx in the server's local zone.
+func UseUTC(db *gorm.DB) error {
+	db.Config.NowFunc = func() time.Time {
+		return time.Now().UTC()
+	}
+	return db.Callback().Query().After("gorm:query").Register("skyell:utc_times", utcTimes)
+}
+
+// utcTimes converts the time fields of the models a query loaded to UTC
+func utcTimes(tx *gorm.DB) {
+	if tx.Error != nil || tx.Statement.Schema == nil {
+		return
+	}
+
+	var fields []*schema.Field
+	for _, field := range tx.Statement.Schema.Fields {
+		if isTimeField(field.FieldType) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	// Pluck and Scan load into other types than the model, which are left alone
+	modelType := tx.Statement.Schema.ModelType
+	toUTC := func(model reflect.Value) {
+		if !model.IsValid() || model.Type() != modelType {
+			return
+		}
+		for _, field := range fields {
+			setUTC(field.ReflectValueOf(tx.Statement.Context, model))
+		}
+	}
+	switch value := reflect.Indirect(tx.Statement.ReflectValue); value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			toUTC(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		toUTC(value)
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	nullTimeType  = reflect.TypeOf(sql.NullTime{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// isTimeField reports whether a field holds a time: time.Time, sql.NullTime or
+// gorm.DeletedAt, or a pointer to one
+func isTimeField(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t == timeType || t == nullTimeType || t == deletedAtType
+}
+
+// setUTC converts the time held by a time field to UTC in place
+func setUTC(value reflect.Value) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if !value.CanSet() {
+		return
+	}
+
+	switch t := value.Addr().Interface().(type) {
+	case *time.Time:
+		*t = t.UTC()
+	case *sql.NullTime:
+		t.Time = t.Time.UTC()
+	case *gorm.DeletedAt:
+		t.Time = t.Time.UTC()
+	}
+}
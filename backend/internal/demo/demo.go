@@ -0,0 +1,180 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	username = "skyell-demo"
+	email    = "demo@skyell.invalid"
+
+	// unusablePassword isn't a bcrypt hash, so no password ever matches it and nobody can
+	// sign in as the demo user
+	unusablePassword = "!"
+)
+
+// userID is the ID of the seeded demo user; 0 while DEMO_MODE is off
+var userID atomic.Uint64
+
+// UserID returns the ID of the shared demo user, or 0 when DEMO_MODE is off
+func UserID() uint {
+	return uint(userID.Load())
+}
+
+// Start seeds the shared demo user and its data set when DEMO_MODE=true, and resets the
+// data set every DEMO_RESET_INTERVAL (0 disables resets) until ctx is done, which ends demo
+// mode. It does nothing otherwise.
+func Start(ctx context.Context, db *gorm.DB) error {
+	if !config.GetEnvBool("DEMO_MODE", false) {
+		return nil
+	}
+
+	user := models.User{Username: username, Email: email, Password: unusablePassword}
+	if err := db.Where(models.User{Email: email}).FirstOrCreate(&user).Error; err != nil {
+		return err
+	}
+	// Resets delete everything the demo user owns, so never adopt an account someone can use
+	if user.Password != unusablePassword {
+		return fmt.Errorf("user %d with the demo email %s has a password; refusing to use it as the demo user", user.ID, email)
+	}
+	if err := Reset(db, user.ID); err != nil {
+		return err
+	}
+	userID.Store(uint64(user.ID))
+	log.Printf("DEMO_MODE is enabled: results of the demo user %d are readable without signing in", user.ID)
+
+	interval := config.GetEnvDuration("DEMO_RESET_INTERVAL", 24*time.Hour)
+	go func() {
+		defer userID.Store(0)
+
+		var resets <-chan time.Time // Never fires when resets are disabled
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			resets = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-resets:
+			}
+
+			if err := Reset(db, user.ID); err != nil {
+				log.Printf("Failed to reset demo data: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Reset replaces everything the demo user owns with the sample data set
+func Reset(db *gorm.DB, demoUserID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		urlIDs := tx.Unscoped().Model(&models.URL{}).Select("id").Where("user_id = ?", demoUserID)
+		resultIDs := tx.Unscoped().Model(&models.CrawlResult{}).Select("id").Where("url_id IN (?)", urlIDs)
+
+		if err := tx.Unscoped().Where("crawl_result_id IN (?)", resultIDs).Delete(&models.Link{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("crawl_result_id IN (?)", resultIDs).Delete(&models.CrawlSnapshot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", demoUserID).Delete(&models.ResultView{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", demoUserID).Delete(&models.CrawlEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("url_id IN (?)", urlIDs).Delete(&models.CrawlResult{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM url_tags WHERE url_id IN (?)", urlIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", demoUserID).Delete(&models.URL{}).Error; err != nil {
+			return err
+		}
+
+		urls := sampleURLs(demoUserID, time.Now())
+		return tx.Create(&urls).Error
+	})
+}
+
+// sampleURLs is the demo data set: a few crawled pages with their links, crawled at now
+func sampleURLs(demoUserID uint, now time.Time) []models.URL {
+	page := func(address, title string, h1, h2 int, links []models.Link) models.URL {
+		result := models.CrawlResult{
+			Title:              title,
+			HTMLVersion:        "HTML5",
+			ResponseStatus:     200,
+			HasMetaDescription: true,
+			HasTitle:           true,
+			HasViewportMeta:    true,
+			H1Count:            h1,
+			H2Count:            h2,
+			MissingH1:          h1 == 0,
+			MultipleH1:         h1 > 1,
+			Changed:            true,
+			Links:              links,
+			LinksChecked:       len(links),
+			LinksTotal:         len(links),
+			CreatedAt:          now,
+		}
+		for _, link := range links {
+			switch link.Type {
+			case models.LinkTypeInternal:
+				result.InternalLinks++
+			case models.LinkTypeExternal:
+				result.ExternalLinks++
+			}
+			if link.IsBroken {
+				result.BrokenLinks++
+			}
+		}
+
+		return models.URL{
+			URL:          address,
+			UserID:       demoUserID,
+			Status:       models.StatusCompleted,
+			CrawlResults: []models.CrawlResult{result},
+		}
+	}
+	link := func(address, anchor string, linkType models.LinkType, status int) models.Link {
+		return models.Link{
+			URL:           address,
+			AnchorText:    anchor,
+			Type:          linkType,
+			StatusCode:    status,
+			IsBroken:      status >= 400,
+			LastCheckedAt: &now,
+		}
+	}
+
+	return []models.URL{
+		page("https://example.com", "Example Domain", 1, 0, []models.Link{
+			link("https://www.iana.org/domains/example", "More information...", models.LinkTypeExternal, 200),
+		}),
+		page("https://go.dev", "The Go Programming Language", 1, 6, []models.Link{
+			link("https://go.dev/doc/", "Docs", models.LinkTypeInternal, 200),
+			link("https://go.dev/learn/", "Learn", models.LinkTypeInternal, 200),
+			link("https://go.dev/old-page", "Old page", models.LinkTypeInternal, 404),
+			link("https://github.com/golang/go", "GitHub", models.LinkTypeExternal, 200),
+		}),
+		page("https://www.wikipedia.org", "Wikipedia", 2, 3, []models.Link{
+			link("https://en.wikipedia.org/", "English", models.LinkTypeExternal, 200),
+			link("https://de.wikipedia.org/", "Deutsch", models.LinkTypeExternal, 200),
+			link("https://www.wikipedia.org/missing", "Missing", models.LinkTypeInternal, 500),
+		}),
+	}
+}
@@ -0,0 +1,115 @@
+package janitor
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// settleTime is how old a crawl result must be before its link counts are checked; a crawl
+// saves its result before its links, so newer results may legitimately not match yet
+const settleTime = time.Hour
+
+// DeleteOrphanedLinks removes links whose crawl result no longer exists and returns how
+// many were removed. Results in the trash still exist, so their links are kept.
+func DeleteOrphanedLinks(db *gorm.DB) (int64, error) {
+	result := db.Unscoped().
+		Where("NOT EXISTS (SELECT 1 FROM crawl_results WHERE crawl_results.id = links.crawl_result_id)").
+		Delete(&models.Link{})
+	return result.RowsAffected, result.Error
+}
+
+// linkCounts are the link counts of a crawl result as stored and as counted from its links
+type linkCounts struct {
+	ID            uint
+	InternalLinks int
+	ExternalLinks int
+	BrokenLinks   int
+	Internal      int
+	External      int
+	Broken        int
+}
+
+// FixLinkCounts recomputes the link counts of crawl results created before settledBefore
+// whose stored counts differ from their actual links, and returns how many were fixed
+func FixLinkCounts(db *gorm.DB, settledBefore time.Time) (int64, error) {
+	counts := db.Model(&models.Link{}).
+		Select("crawl_result_id, "+
+			"SUM(CASE WHEN type = ? THEN 1 ELSE 0 END) AS internal, "+
+			"SUM(CASE WHEN type = ? THEN 1 ELSE 0 END) AS external, "+
+			"SUM(CASE WHEN is_broken THEN 1 ELSE 0 END) AS broken",
+			models.LinkTypeInternal, models.LinkTypeExternal).
+		Group("crawl_result_id")
+
+	var drifted []linkCounts
+	if err := db.Model(&models.CrawlResult{}).
+		Select("crawl_results.id, crawl_results.internal_links, crawl_results.external_links, crawl_results.broken_links, "+
+			"COALESCE(counts.internal, 0) AS internal, COALESCE(counts.external, 0) AS external, COALESCE(counts.broken, 0) AS broken").
+		Joins("LEFT JOIN (?) AS counts ON counts.crawl_result_id = crawl_results.id", counts).
+		Where("crawl_results.created_at < ?", settledBefore).
+		Where("crawl_results.internal_links <> COALESCE(counts.internal, 0) OR " +
+			"crawl_results.external_links <> COALESCE(counts.external, 0) OR " +
+			"crawl_results.broken_links <> COALESCE(counts.broken, 0)").
+		Scan(&drifted).Error; err != nil {
+		return 0, err
+	}
+
+	var fixed int64
+	for _, c := range drifted {
+		// Only overwrite counts that are still the ones read above, in case the result changed since
+		result := db.Model(&models.CrawlResult{}).
+			Where("id = ? AND internal_links = ? AND external_links = ? AND broken_links = ?",
+				c.ID, c.InternalLinks, c.ExternalLinks, c.BrokenLinks).
+			Updates(map[string]interface{}{
+				"internal_links": c.Internal,
+				"external_links": c.External,
+				"broken_links":   c.Broken,
+				"links_total":    c.Internal + c.External,
+			})
+		if result.Error != nil {
+			return fixed, result.Error
+		}
+		fixed += result.RowsAffected
+	}
+
+	return fixed, nil
+}
+
+// Start periodically removes orphaned links and fixes drifted link counts, every
+// LINK_JANITOR_INTERVAL. It does nothing when the interval is 0.
+func Start(ctx context.Context, db *gorm.DB) {
+	interval := config.GetEnvDuration("LINK_JANITOR_INTERVAL", time.Hour)
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if removed, err := DeleteOrphanedLinks(db); err != nil {
+				log.Printf("Failed to delete orphaned links: %v", err)
+			} else if removed > 0 {
+				log.Printf("Deleted %d orphaned links", removed)
+			}
+
+			if fixed, err := FixLinkCounts(db, time.Now().Add(-settleTime)); err != nil {
+				log.Printf("Failed to fix link counts: %v", err)
+			} else if fixed > 0 {
+				log.Printf("Fixed link counts of %d crawl results", fixed)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,140 @@
+package janitor
+
+import (
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// seedResult saves a crawl result of a new URL with the given stored counts and links
+func seedResult(t *testing.T, db *gorm.DB, userID uint, address string, stored models.CrawlResult, links ...models.Link) models.CrawlResult {
+	t.Helper()
+	urlEntry := models.URL{URL: address, UserID: userID, Status: models.StatusCompleted}
+	if err := db.Create(&urlEntry).Error; err != nil {
+		t.Fatal(err)
+	}
+	stored.URLID = urlEntry.ID
+	stored.Links = links
+	if err := db.Create(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// hardDeleteResult removes a crawl result row but not its links, as an interrupted save
+// or a manual delete would; foreign keys are switched off on the connection used
+func hardDeleteResult(t *testing.T, db *gorm.DB, id uint) {
+	t.Helper()
+	err := db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+		return conn.Exec("DELETE FROM crawl_results WHERE id = ?", id).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func countLinks(t *testing.T, db *gorm.DB, resultID uint) int64 {
+	t.Helper()
+	var count int64
+	if err := db.Unscoped().Model(&models.Link{}).Where("crawl_result_id = ?", resultID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestDeleteOrphanedLinks(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := models.User{Username: "janitor", Email: "janitor@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	link := func(address string) models.Link {
+		return models.Link{URL: address, Type: models.LinkTypeInternal}
+	}
+
+	kept := seedResult(t, db, user.ID, "https://kept.example", models.CrawlResult{InternalLinks: 1}, link("https://kept.example/a"))
+	trashed := seedResult(t, db, user.ID, "https://trashed.example", models.CrawlResult{InternalLinks: 1}, link("https://trashed.example/a"))
+	if err := db.Delete(&trashed).Error; err != nil {
+		t.Fatal(err)
+	}
+	orphaned := seedResult(t, db, user.ID, "https://gone.example", models.CrawlResult{InternalLinks: 2},
+		link("https://gone.example/a"), link("https://gone.example/b"))
+	hardDeleteResult(t, db, orphaned.ID)
+
+	removed, err := DeleteOrphanedLinks(db)
+	if err != nil {
+		t.Fatalf("DeleteOrphanedLinks: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d links, want the 2 orphaned ones", removed)
+	}
+	if n := countLinks(t, db, orphaned.ID); n != 0 {
+		t.Errorf("%d orphaned links are left", n)
+	}
+	// Links of existing results, including results in the trash, are kept
+	if countLinks(t, db, kept.ID) != 1 || countLinks(t, db, trashed.ID) != 1 {
+		t.Error("links of existing results were removed")
+	}
+
+	if removed, err := DeleteOrphanedLinks(db); err != nil || removed != 0 {
+		t.Errorf("second run removed %d links (%v), want none", removed, err)
+	}
+}
+
+func TestFixLinkCounts(t *testing.T) {
+	db := testutil.NewDB(t)
+	user := models.User{Username: "counter", Email: "counter@example.com", Password: "x"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	links := func() []models.Link {
+		return []models.Link{
+			{URL: "https://example.com/a", Type: models.LinkTypeInternal},
+			{URL: "https://example.com/b", Type: models.LinkTypeInternal, IsBroken: true},
+			{URL: "https://other.example/", Type: models.LinkTypeExternal},
+		}
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	drifted := seedResult(t, db, user.ID, "https://drifted.example",
+		models.CrawlResult{InternalLinks: 5, ExternalLinks: 0, BrokenLinks: 0, CreatedAt: old}, links()...)
+	accurate := seedResult(t, db, user.ID, "https://accurate.example",
+		models.CrawlResult{InternalLinks: 2, ExternalLinks: 1, BrokenLinks: 1, LinksTotal: 3, CreatedAt: old}, links()...)
+	// A crawl may still be saving the links of a recent result
+	recent := seedResult(t, db, user.ID, "https://recent.example", models.CrawlResult{InternalLinks: 40})
+
+	fixed, err := FixLinkCounts(db, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("FixLinkCounts: %v", err)
+	}
+	if fixed != 1 {
+		t.Errorf("fixed %d results, want 1", fixed)
+	}
+
+	reload := func(id uint) models.CrawlResult {
+		t.Helper()
+		var result models.CrawlResult
+		if err := db.First(&result, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if got := reload(drifted.ID); got.InternalLinks != 2 || got.ExternalLinks != 1 || got.BrokenLinks != 1 || got.LinksTotal != 3 {
+		t.Errorf("drifted counts = %d internal, %d external, %d broken, %d total; want 2, 1, 1, 3",
+			got.InternalLinks, got.ExternalLinks, got.BrokenLinks, got.LinksTotal)
+	}
+	if got := reload(accurate.ID); got.InternalLinks != 2 || got.ExternalLinks != 1 || got.BrokenLinks != 1 {
+		t.Errorf("accurate counts changed to %d, %d, %d", got.InternalLinks, got.ExternalLinks, got.BrokenLinks)
+	}
+	if got := reload(recent.ID); got.InternalLinks != 40 {
+		t.Errorf("recent result's internal links = %d, want it left at 40", got.InternalLinks)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds all application metrics, separate from the global default registry
+var Registry = prometheus.NewRegistry()
+
+var (
+	CrawlsStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "skyell_crawls_started_total",
+		Help: "Total number of crawls started.",
+	})
+
+	CrawlsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "skyell_crawls_completed_total",
+		Help: "Total number of crawls that completed successfully.",
+	})
+
+	CrawlsFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "skyell_crawls_failed_total",
+		Help: "Total number of crawls that failed.",
+	})
+
+	CrawlsRunning = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "skyell_crawls_running",
+		Help: "Number of crawls currently in progress.",
+	})
+
+	CrawlDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "skyell_crawl_duration_seconds",
+		Help:    "Duration of crawls in seconds.",
+		Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "skyell_http_request_duration_seconds",
+		Help:    "Latency of HTTP requests in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		CrawlsStarted,
+		CrawlsCompleted,
+		CrawlsFailed,
+		CrawlsRunning,
+		CrawlDuration,
+		HTTPRequestDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return gin.WrapH(h)
+}
+
+// Middleware records the latency of every HTTP request
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Use the route template rather than the raw path to keep label cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
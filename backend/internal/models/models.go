@@ -25,8 +25,96 @@ type User struct {
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// WebhookURL is notified when the user's crawls complete or fail; payloads are signed
+	// with WebhookSecret
+	WebhookURL    string `json:"webhook_url,omitempty" gorm:"size:500"`
+	WebhookSecret string `json:"-" gorm:"size:64"`
+}
+
+// APIKey is a long-lived credential for server-to-server access, sent in the X-API-Key header.
+// Only a hash of the key is stored; the full key is shown once when it's created.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	Label      string     `json:"label" gorm:"size:100"`
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the key
+	Prefix     string     `json:"prefix" gorm:"size:16"`                 // Start of the key, to tell keys apart
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Revoked    bool       `json:"revoked" gorm:"not null;default:false"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// PasswordReset represents a single-use password reset token issued to a user
+type PasswordReset struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"foreignKey:UserID"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;size:64"` // SHA-256 of the token sent by email
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// LoginAttempt tracks recent failed logins for an email address, whether or not an account exists for it
+type LoginAttempt struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Email       string     `json:"email" gorm:"uniqueIndex;not null;size:255"`
+	Failures    int        `json:"failures" gorm:"not null;default:0"`
+	WindowStart time.Time  `json:"window_start" gorm:"not null"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IdempotencyKey stores the response of a request made with an Idempotency-Key header so
+// retries of the same request replay it instead of repeating the side effect.
+// A StatusCode of 0 means the original request is still being processed.
+type IdempotencyKey struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key          string    `json:"key" gorm:"not null;size:255;uniqueIndex:idx_idempotency_user_key"`
+	Endpoint     string    `json:"endpoint" gorm:"not null;size:255"`
+	RequestHash  string    `json:"-" gorm:"not null;size:64"`
+	StatusCode   int       `json:"status_code"`
+	ResponseBody string    `json:"-" gorm:"type:text"`
+	ExpiresAt    time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CrawlEventType is a crawl lifecycle transition recorded in the audit trail
+type CrawlEventType string
+
+const (
+	EventStarted   CrawlEventType = "started"
+	EventCompleted CrawlEventType = "completed"
+	EventFailed    CrawlEventType = "failed"
+	EventStopped   CrawlEventType = "stopped"
+)
+
+// CrawlEvent is an audit record of a crawl lifecycle transition
+type CrawlEvent struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	URLID     uint           `json:"url_id" gorm:"not null;index"`
+	UserID    uint           `json:"user_id" gorm:"not null;index"`
+	Type      CrawlEventType `json:"type" gorm:"not null;size:20"`
+	Detail    string         `json:"detail,omitempty" gorm:"size:1024"`
+	CreatedAt time.Time      `json:"created_at" gorm:"index"`
 }
 
+// URLAuthType is how the crawler authenticates when fetching a URL
+type URLAuthType string
+
+const (
+	AuthTypeNone   URLAuthType = "none"
+	AuthTypeBasic  URLAuthType = "basic"
+	AuthTypeBearer URLAuthType = "bearer"
+)
+
 // URL represents a URL to be crawled
 type URL struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
@@ -39,8 +127,33 @@ type URL struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Optional credentials sent when crawling; AuthCredentials is encrypted at rest and never serialized
+	AuthType        URLAuthType `json:"auth_type,omitempty" gorm:"size:20"`
+	AuthCredentials string      `json:"-" gorm:"type:text"`
+
+	// AcceptLanguage overrides CRAWLER_ACCEPT_LANGUAGE for this URL
+	AcceptLanguage string `json:"accept_language,omitempty" gorm:"size:100"`
+
+	// Per-URL crawl overrides; nil uses the global configuration
+	MaxLinksToCheck     *int  `json:"max_links_to_check"`
+	FollowRedirects     *bool `json:"follow_redirects"`
+	CrawlTimeoutSeconds *int  `json:"crawl_timeout_seconds"`
+	CrawlDepth          *int  `json:"crawl_depth"` // Levels of internal links followed; 0 crawls the page only
+
 	// Relationship to crawl results
 	CrawlResults []CrawlResult `json:"crawl_results,omitempty" gorm:"foreignKey:URLID"`
+
+	// Tags attached to this URL
+	Tags []Tag `json:"tags,omitempty" gorm:"many2many:url_tags;"`
+}
+
+// Tag is a user-defined label for grouping URLs
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name      string    `json:"name" gorm:"not null;size:100;uniqueIndex:idx_tags_user_name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // CrawlResult represents the analysis results for a crawled URL
@@ -49,10 +162,55 @@ type CrawlResult struct {
 	URLID uint `json:"url_id" gorm:"not null;index"`
 	URL   URL  `json:"url" gorm:"foreignKey:URLID"`
 
+	// Pages reached by following internal links (crawl depth >= 1) are saved as children
+	// of the result of the URL's own page, for which ParentID and PageURL are empty.
+	// Depth is the number of links followed to reach the page.
+	ParentID *uint  `json:"parent_id,omitempty" gorm:"index"`
+	PageURL  string `json:"page_url,omitempty" gorm:"size:2048"`
+	Depth    int    `json:"depth"`
+
 	// Page Information
-	Title        string `json:"title" gorm:"size:512"`
-	HTMLVersion  string `json:"html_version" gorm:"size:50"`
-	HasLoginForm bool   `json:"has_login_form"`
+	Title          string `json:"title" gorm:"size:512"`
+	HTMLVersion    string `json:"html_version" gorm:"size:50"`
+	HasLoginForm   bool   `json:"has_login_form"`
+	ResponseStatus int    `json:"response_status"` // HTTP status code of the crawled page
+
+	// Derived SEO checks: pages should have exactly one H1 and a meta description
+	MultipleH1         bool `json:"multiple_h1"`
+	MissingH1          bool `json:"missing_h1"`
+	HasMetaDescription bool `json:"has_meta_description"`
+
+	// WordCount is the number of words of visible text; pages with fewer words than
+	// THIN_CONTENT_WORDS are flagged as thin content
+	WordCount     int  `json:"word_count"`
+	IsThinContent bool `json:"is_thin_content"`
+
+	// Resources referenced by the page: distinct external scripts and stylesheets, and
+	// inline scripts
+	ScriptCount       int `json:"script_count"`
+	StylesheetCount   int `json:"stylesheet_count"`
+	InlineScriptCount int `json:"inline_script_count"`
+
+	// Other form types found on the page, alongside HasLoginForm
+	HasSignupForm bool `json:"has_signup_form"`
+	HasSearchForm bool `json:"has_search_form"`
+
+	// Basic SEO presence checks
+	HasTitle        bool `json:"has_title"`
+	HasFavicon      bool `json:"has_favicon"` // <link rel="icon"> or a reachable /favicon.ico
+	HasViewportMeta bool `json:"has_viewport_meta"`
+
+	// RedirectedOffHost is set when redirects ended on a different host than the submitted URL
+	RedirectedOffHost bool `json:"redirected_off_host"`
+
+	// SEO and social metadata
+	CanonicalURL  string `json:"canonical_url" gorm:"size:500"`
+	OGTitle       string `json:"og_title" gorm:"size:512"`
+	OGDescription string `json:"og_description" gorm:"size:1024"`
+	OGImage       string `json:"og_image" gorm:"size:500"`
+
+	// CanonicalMismatch is set when the canonical URL points at a different page than the one crawled
+	CanonicalMismatch bool `json:"canonical_mismatch"`
 
 	// Heading Counts
 	H1Count int `json:"h1_count"`
@@ -62,11 +220,41 @@ type CrawlResult struct {
 	H5Count int `json:"h5_count"`
 	H6Count int `json:"h6_count"`
 
+	// TagCounts holds counts of h1-h6 and the configured structural tags (nav, footer, ...)
+	TagCounts map[string]int `json:"tag_counts,omitempty" gorm:"serializer:json;type:text"`
+
 	// Link Statistics
 	InternalLinks int `json:"internal_links"`
 	ExternalLinks int `json:"external_links"`
 	BrokenLinks   int `json:"broken_links"`
 
+	// ContentHash is the SHA-256 of the fetched HTML; Changed is set when it differs from the
+	// previous crawl of the same URL (always true for the first crawl)
+	ContentHash string `json:"content_hash" gorm:"size:64"`
+	Changed     bool   `json:"changed"`
+
+	// Validators of the page's response, sent back as If-None-Match and If-Modified-Since
+	// on the next crawl; a 304 answer reuses this result
+	ETag         string `json:"etag,omitempty" gorm:"size:255"`
+	LastModified string `json:"last_modified,omitempty" gorm:"size:100"`
+
+	// ContentLanguage is the page's Content-Language response header, if any
+	ContentLanguage string `json:"content_language,omitempty" gorm:"size:100"`
+
+	// Charset is the character encoding the page was declared in, e.g. utf-8 or shift_jis
+	Charset string `json:"charset,omitempty" gorm:"size:50"`
+
+	// Certificate of pages served over https; nil for plain http
+	CertificateValid     *bool      `json:"certificate_valid,omitempty"`
+	CertificateExpiresAt *time.Time `json:"certificate_expires_at,omitempty"`
+
+	// LinksChecked of LinksTotal links were checked for accessibility (capped by MAX_LINKS_CHECKED)
+	LinksChecked int `json:"links_checked"`
+	LinksTotal   int `json:"links_total"`
+
+	// IsPartial marks a result saved when its crawl was stopped during link checks
+	IsPartial bool `json:"is_partial"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -95,11 +283,42 @@ type Link struct {
 	StatusCode int      `json:"status_code,omitempty"` // HTTP status code if checked
 	IsBroken   bool     `json:"is_broken"`
 
+	// OccurrenceCount is how many times this link appeared on the page
+	OccurrenceCount int `json:"occurrence_count" gorm:"default:1"`
+
+	// LastCheckedAt is when the link's accessibility was last checked; nil if it never was
+	LastCheckedAt *time.Time `json:"last_checked_at"`
+
+	// Rel classification; a link with none of these set is followed
+	Nofollow  bool `json:"nofollow"`
+	Sponsored bool `json:"sponsored"`
+	UGC       bool `json:"ugc"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// CrawlSnapshot is the gzip-compressed HTML the crawler analyzed for a crawl result,
+// stored only when STORE_SNAPSHOTS is enabled
+type CrawlSnapshot struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	CrawlResultID uint   `json:"crawl_result_id" gorm:"not null;uniqueIndex"`
+	HTML          []byte `json:"-"`
+	Size          int    `json:"size"`      // Uncompressed size of the stored HTML
+	Truncated     bool   `json:"truncated"` // The page was cut at SNAPSHOT_MAX_BYTES
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResultView records when a user last opened a crawl result's details
+type ResultView struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_result_views_user_result"`
+	CrawlResultID uint      `json:"crawl_result_id" gorm:"not null;uniqueIndex:idx_result_views_user_result"`
+	ViewedAt      time.Time `json:"viewed_at" gorm:"not null;index"`
+}
+
 // GetHeadingCounts returns a map of heading levels to their counts
 func (cr *CrawlResult) GetHeadingCounts() map[string]int {
 	return map[string]int{
@@ -0,0 +1,132 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+
+	"skyell-backend/internal/config"
+)
+
+// ErrInternalAddress is returned when a request would reach a loopback, private,
+// link-local or unspecified address
+var ErrInternalAddress = errors.New("refusing to connect to an internal address")
+
+// Internal reports whether an IP is loopback, private, link-local, multicast or unspecified
+// and not on INTERNAL_ADDRESS_ALLOWLIST
+func Internal(ip net.IP) bool {
+	if !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() && !ip.IsUnspecified() {
+		return false
+	}
+
+	for _, entry := range config.GetEnvList("INTERNAL_ADDRESS_ALLOWLIST", nil) {
+		if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control is a net.Dialer Control hook that refuses connections to internal addresses.
+// It runs after name resolution, so it also catches hosts that resolve to internal IPs.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", ErrInternalAddress, address)
+	}
+	if Internal(ip) {
+		return fmt.Errorf("%w: %s", ErrInternalAddress, ip)
+	}
+	return nil
+}
+
+// CheckHost resolves a host and fails if any of its addresses is internal
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if Internal(ip) {
+			return fmt.Errorf("%w: %s", ErrInternalAddress, ip)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if Internal(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrInternalAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// Transport returns a copy of base whose connections to internal addresses are refused.
+// Every connection is checked as it's dialed, so each redirect hop is checked too.
+// Requests sent through a proxy are checked by resolving the target host before the
+// proxy is used, since the proxy itself makes the connection; the proxy's own address
+// is trusted.
+func Transport(base *http.Transport) *http.Transport {
+	transport := base.Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	guarded := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: Control}
+
+	var mu sync.Mutex
+	proxies := make(map[string]bool)
+
+	if proxy := base.Proxy; proxy != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			proxyURL, err := proxy(req)
+			if err != nil || proxyURL == nil {
+				return proxyURL, err
+			}
+			if err := CheckHost(req.Context(), req.URL.Hostname()); err != nil {
+				return nil, err
+			}
+
+			mu.Lock()
+			proxies[proxyAddr(proxyURL)] = true
+			mu.Unlock()
+			return proxyURL, nil
+		}
+	}
+
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		mu.Lock()
+		isProxy := proxies[address]
+		mu.Unlock()
+
+		if isProxy {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+	return transport
+}
+
+// proxyAddr is the host:port the transport dials for a proxy URL
+func proxyAddr(proxyURL *url.URL) string {
+	if port := proxyURL.Port(); port != "" {
+		return net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	port := "80"
+	switch proxyURL.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
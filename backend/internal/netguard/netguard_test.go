@@ -0,0 +1,56 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestInternal(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"::ffff:127.0.0.1", true},
+		{"93.184.216.34", false},
+		{"2606:2800:220:1::1", false},
+	}
+	for _, tt := range tests {
+		if got := Internal(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("Internal(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestInternalHonoursAllowlist(t *testing.T) {
+	t.Setenv("INTERNAL_ADDRESS_ALLOWLIST", "10.0.0.0/8, not-a-cidr")
+
+	if Internal(net.ParseIP("10.1.2.3")) {
+		t.Error("10.1.2.3 is on the allowlist but was treated as internal")
+	}
+	if !Internal(net.ParseIP("192.168.1.1")) {
+		t.Error("192.168.1.1 isn't on the allowlist but was allowed")
+	}
+}
+
+func TestControl(t *testing.T) {
+	if err := Control("tcp", "127.0.0.1:80", nil); !errors.Is(err, ErrInternalAddress) {
+		t.Errorf("Control(127.0.0.1:80) = %v, want ErrInternalAddress", err)
+	}
+	if err := Control("tcp", "[::1]:443", nil); !errors.Is(err, ErrInternalAddress) {
+		t.Errorf("Control([::1]:443) = %v, want ErrInternalAddress", err)
+	}
+	if err := Control("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("Control(93.184.216.34:443) = %v, want nil", err)
+	}
+}
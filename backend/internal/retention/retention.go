@@ -0,0 +1,148 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"skyell-backend/internal/config"
+	"skyell-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Policy decides which crawl results are pruned: results older than OlderThan are
+// removed, except the KeepLatest most recent results of each URL which are always kept
+type Policy struct {
+	OlderThan  time.Duration
+	KeepLatest int
+}
+
+// ParseDuration accepts Go durations ("720h") as well as whole days ("30d")
+func ParseDuration(raw string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(raw, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", raw)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", raw)
+	}
+	return d, nil
+}
+
+// PruneResults permanently deletes crawl results matching the policy, along with their
+// links, snapshots and views, and returns how many results were removed. URLs and results
+// in the trash are pruned too. When userID is nil every user's URLs are pruned.
+func PruneResults(db *gorm.DB, userID *uint, policy Policy) (int64, error) {
+	urlQuery := db.Unscoped().Model(&models.URL{})
+	if userID != nil {
+		urlQuery = urlQuery.Where("user_id = ?", *userID)
+	}
+
+	var urlIDs []uint
+	if err := urlQuery.Pluck("id", &urlIDs).Error; err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-policy.OlderThan)
+	var removed int64
+	for _, urlID := range urlIDs {
+		var results []models.CrawlResult
+		// Pages reached through crawl depth are kept or pruned with their parent
+		if err := db.Unscoped().Select("id", "created_at").
+			Where("url_id = ? AND parent_id IS NULL", urlID).
+			Order("created_at DESC, id DESC").
+			Find(&results).Error; err != nil {
+			return removed, err
+		}
+
+		var pruneIDs []uint
+		for i, result := range results {
+			if i >= policy.KeepLatest && result.CreatedAt.Before(cutoff) {
+				pruneIDs = append(pruneIDs, result.ID)
+			}
+		}
+		if len(pruneIDs) == 0 {
+			continue
+		}
+
+		var childIDs []uint
+		if err := db.Unscoped().Model(&models.CrawlResult{}).Where("parent_id IN ?", pruneIDs).Pluck("id", &childIDs).Error; err != nil {
+			return removed, err
+		}
+		pruneIDs = append(pruneIDs, childIDs...)
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Where("crawl_result_id IN ?", pruneIDs).Delete(&models.Link{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("crawl_result_id IN ?", pruneIDs).Delete(&models.CrawlSnapshot{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("crawl_result_id IN ?", pruneIDs).Delete(&models.ResultView{}).Error; err != nil {
+				return err
+			}
+			result := tx.Unscoped().Where("id IN ?", pruneIDs).Delete(&models.CrawlResult{})
+			if result.Error != nil {
+				return result.Error
+			}
+			removed += result.RowsAffected
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// StartPruner periodically prunes every user's results using RESULT_RETENTION_PERIOD and
+// RESULT_RETENTION_KEEP_LATEST, every RESULT_PRUNE_INTERVAL. It does nothing when
+// RESULT_RETENTION_PERIOD is unset.
+func StartPruner(ctx context.Context, db *gorm.DB) {
+	raw := config.GetEnv("RESULT_RETENTION_PERIOD", "")
+	if raw == "" {
+		return
+	}
+
+	olderThan, err := ParseDuration(raw)
+	if err != nil {
+		log.Printf("Result pruning disabled: RESULT_RETENTION_PERIOD: %v", err)
+		return
+	}
+
+	policy := Policy{
+		OlderThan:  olderThan,
+		KeepLatest: max(config.GetEnvInt("RESULT_RETENTION_KEEP_LATEST", 1), 0),
+	}
+	interval := config.GetEnvDuration("RESULT_PRUNE_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			removed, err := PruneResults(db, nil, policy)
+			if err != nil {
+				log.Printf("Failed to prune crawl results: %v", err)
+			} else if removed > 0 {
+				log.Printf("Pruned %d crawl results older than %s", removed, olderThan)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
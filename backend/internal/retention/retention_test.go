@@ -0,0 +1,111 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"skyell-backend/internal/models"
+	"skyell-backend/internal/testutil"
+
+	"gorm.io/gorm"
+)
+
+// seedResult saves a crawl result of urlID created at the given age, with a link,
+// a snapshot and a view by userID
+func seedResult(t *testing.T, db *gorm.DB, userID, urlID uint, parentID *uint, age time.Duration) models.CrawlResult {
+	t.Helper()
+	result := models.CrawlResult{
+		URLID:     urlID,
+		ParentID:  parentID,
+		CreatedAt: time.Now().Add(-age),
+		Links:     []models.Link{{URL: "https://example.com/a", Type: models.LinkTypeInternal}},
+	}
+	if err := db.Create(&result).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.CrawlSnapshot{CrawlResultID: result.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ResultView{UserID: userID, CrawlResultID: result.ID, ViewedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestPruneResults(t *testing.T) {
+	db := testutil.NewDB(t)
+	day := 24 * time.Hour
+
+	user := models.User{Username: "owner", Email: "owner@example.com", Password: "x"}
+	other := models.User{Username: "other", Email: "other@example.com", Password: "x"}
+	for _, u := range []*models.User{&user, &other} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := models.URL{URL: "https://example.com", UserID: user.ID}
+	trashed := models.URL{URL: "https://example.org", UserID: user.ID}
+	othersURL := models.URL{URL: "https://example.net", UserID: other.ID}
+	for _, u := range []*models.URL{&active, &trashed, &othersURL} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(&trashed).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	latest := seedResult(t, db, user.ID, active.ID, nil, 40*day)    // Kept as the latest, despite its age
+	old := seedResult(t, db, user.ID, active.ID, nil, 50*day)       // Pruned
+	child := seedResult(t, db, user.ID, active.ID, &old.ID, 50*day) // Pruned with its parent
+	trashedLatest := seedResult(t, db, user.ID, trashed.ID, nil, 40*day)
+	trashedOld := seedResult(t, db, user.ID, trashed.ID, nil, 50*day) // Pruned though its URL is in the trash
+	othersOld := seedResult(t, db, other.ID, othersURL.ID, nil, 50*day)
+	othersLatest := seedResult(t, db, other.ID, othersURL.ID, nil, 40*day)
+
+	// A result in the trash is pruned like any other
+	if err := db.Delete(&models.CrawlResult{}, trashedOld.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := PruneResults(db, &user.ID, Policy{OlderThan: 30 * day, KeepLatest: 1})
+	if err != nil {
+		t.Fatalf("PruneResults: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("removed %d results, want 3", removed)
+	}
+
+	var remaining []uint
+	if err := db.Unscoped().Model(&models.CrawlResult{}).Order("id").Pluck("id", &remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []uint{latest.ID, trashedLatest.ID, othersOld.ID, othersLatest.ID}
+	if len(remaining) != len(want) {
+		t.Fatalf("remaining results = %v, want %v", remaining, want)
+	}
+	for i := range want {
+		if remaining[i] != want[i] {
+			t.Fatalf("remaining results = %v, want %v", remaining, want)
+		}
+	}
+
+	// Nothing of the pruned results is left behind, not even soft-deleted links
+	pruned := []uint{old.ID, child.ID, trashedOld.ID}
+	for _, model := range []any{&models.Link{}, &models.CrawlSnapshot{}, &models.ResultView{}} {
+		var count int64
+		if err := db.Unscoped().Model(model).Where("crawl_result_id IN ?", pruned).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%d %T rows of pruned results remain", count, model)
+		}
+	}
+
+	var links int64
+	db.Unscoped().Model(&models.Link{}).Count(&links)
+	if links != int64(len(want)) {
+		t.Errorf("%d links remain, want one per remaining result", links)
+	}
+}
@@ -0,0 +1,84 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNoKey is returned when CREDENTIALS_ENCRYPTION_KEY is not configured
+var ErrNoKey = errors.New("CREDENTIALS_ENCRYPTION_KEY is not set")
+
+// key decodes CREDENTIALS_ENCRYPTION_KEY, a base64-encoded 32-byte AES-256 key
+func key() ([]byte, error) {
+	encoded := os.Getenv("CREDENTIALS_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, ErrNoKey
+	}
+
+	k, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CREDENTIALS_ENCRYPTION_KEY: %w", err)
+	}
+	if len(k) != 32 {
+		return nil, fmt.Errorf("invalid CREDENTIALS_ENCRYPTION_KEY: must decode to 32 bytes, got %d", len(k))
+	}
+	return k, nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	k, err := key()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext with AES-GCM and returns base64(nonce || ciphertext)
+func Encrypt(plaintext string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt
+func Decrypt(encoded string) (string, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid ciphertext: too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,45 @@
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"skyell-backend/internal/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// NewDB returns a migrated in-memory SQLite database private to the test. It's closed
+// when the test finishes.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	// Shared cache keeps one database across the pool's connections
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_busy_timeout=5000&_foreign_keys=1", name)
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true, // As database.Connect does
+	})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("getting test database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// Timestamps are UTC as with database.Connect
+	if err := database.UseUTC(db); err != nil {
+		t.Fatalf("configuring test database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	return db
+}
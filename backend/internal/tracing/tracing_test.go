@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording finished spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestMiddlewareCreatesServerSpan(t *testing.T) {
+	exporter := recordSpans(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	var handlerSpan trace.SpanContext
+	router.GET("/urls/:id", func(c *gin.Context) {
+		// Spans started by handlers are children of the server span
+		_, span := Start(c.Request.Context(), "lookup")
+		handlerSpan = span.SpanContext()
+		End(span, nil)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/urls/42", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", w.Code)
+	}
+
+	spans := exporter.GetSpans()
+	var server *tracetest.SpanStub
+	for i := range spans {
+		if spans[i].SpanKind == trace.SpanKindServer {
+			server = &spans[i]
+		}
+	}
+	if server == nil {
+		t.Fatalf("spans = %v, want a server span", spans)
+	}
+	if server.Name != "/urls/:id" {
+		t.Errorf("server span name = %q, want the route", server.Name)
+	}
+	if handlerSpan.TraceID() != server.SpanContext.TraceID() {
+		t.Error("the handler's span isn't part of the request's trace")
+	}
+}
+
+func TestSetupWithoutEndpointIsNoOp(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background())
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+	if otel.GetTracerProvider() != previous {
+		t.Error("Setup replaced the tracer provider without an endpoint")
+	}
+}
@@ -69,7 +69,8 @@ const URLTable: React.FC<URLTableProps> = ({ className = "" }) => {
 
   // Mutations for crawl operations
   const startCrawlMutation = useMutation({
-    mutationFn: (id: number) => apiService.startCrawl(id),
+    mutationFn: ({ id, force }: { id: number; force: boolean }) =>
+      apiService.startCrawl(id, force),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: ["urls"] });
     },
@@ -114,8 +115,16 @@ const URLTable: React.FC<URLTableProps> = ({ className = "" }) => {
   });
 
   // Event handlers
-  const handleStartCrawl = (id: number) => {
-    startCrawlMutation.mutate(id);
+  const handleStartCrawl = (id: number, status: string) => {
+    // Re-crawling a completed URL must be confirmed and forced
+    const force = status === "completed";
+    if (
+      force &&
+      !window.confirm("This URL has already been crawled. Crawl it again?")
+    ) {
+      return;
+    }
+    startCrawlMutation.mutate({ id, force });
   };
 
   const handleStopCrawl = (id: number) => {
@@ -438,7 +447,7 @@ const URLTable: React.FC<URLTableProps> = ({ className = "" }) => {
                       </button>
                     ) : (
                       <button
-                        onClick={() => handleStartCrawl(url.id)}
+                        onClick={() => handleStartCrawl(url.id, url.status)}
                         disabled={startCrawlMutation.isPending}
                         className="inline-flex items-center gap-1 px-2 py-1 bg-green-600 text-white text-xs rounded hover:bg-green-700 disabled:opacity-50 cursor-pointer"
                         title="Start crawling"
@@ -203,9 +203,9 @@ class APIService {
   }
 
   // Crawl control methods
-  async startCrawl(id: number): Promise<void> {
+  async startCrawl(id: number, force = false): Promise<void> {
     const response: AxiosResponse<APIResponse<void>> = await this.api.post(
-      `/crawl/start/${id}`
+      `/crawl/start/${id}${force ? "?force=true" : ""}`
     );
 
     if (!response.data.success) {